@@ -36,14 +36,15 @@ func (l LanguageCode) String() string {
 	return string(l)
 }
 
-type audio struct {
-	Rooms  *audioRooms
-	Speech *audioSpeech
-	Voices *audioVoices
+// AudioAPI is the audio api, see CozeAPI.Audio.
+type AudioAPI struct {
+	Rooms  AudioRoomsService
+	Speech AudioSpeechService
+	Voices AudioVoicesService
 }
 
-func newAudio(core *core) *audio {
-	return &audio{
+func newAudio(core *core) *AudioAPI {
+	return &AudioAPI{
 		Rooms:  newRooms(core),
 		Speech: newSpeech(core),
 		Voices: newVoice(core),
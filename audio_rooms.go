@@ -5,7 +5,8 @@ import (
 	"net/http"
 )
 
-func (r *audioRooms) Create(ctx context.Context, req *CreateAudioRoomsReq) (*CreateAudioRoomsResp, error) {
+func (r *audioRooms) Create(ctx context.Context, req *CreateAudioRoomsReq, opts ...CallOption) (*CreateAudioRoomsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/audio/rooms"
 	resp := &createAudioRoomsResp{}
@@ -16,6 +17,13 @@ func (r *audioRooms) Create(ctx context.Context, req *CreateAudioRoomsReq) (*Cre
 	return resp.Data, nil
 }
 
+// AudioRoomsService is the api of the audio rooms, see AudioAPI.Rooms.
+type AudioRoomsService interface {
+	Create(ctx context.Context, req *CreateAudioRoomsReq, opts ...CallOption) (*CreateAudioRoomsResp, error)
+}
+
+var _ AudioRoomsService = (*audioRooms)(nil)
+
 type audioRooms struct {
 	core *core
 }
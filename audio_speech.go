@@ -7,7 +7,8 @@ import (
 	"os"
 )
 
-func (r *audioSpeech) Create(ctx context.Context, req *CreateAudioSpeechReq) (*CreateAudioSpeechResp, error) {
+func (r *audioSpeech) Create(ctx context.Context, req *CreateAudioSpeechReq, opts ...CallOption) (*CreateAudioSpeechResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	uri := "/v1/audio/speech"
 	resp, err := r.core.RawRequest(ctx, http.MethodPost, uri, req)
 	if err != nil {
@@ -20,6 +21,13 @@ func (r *audioSpeech) Create(ctx context.Context, req *CreateAudioSpeechReq) (*C
 	return res, nil
 }
 
+// AudioSpeechService is the speech synthesis api, see AudioAPI.Speech.
+type AudioSpeechService interface {
+	Create(ctx context.Context, req *CreateAudioSpeechReq, opts ...CallOption) (*CreateAudioSpeechResp, error)
+}
+
+var _ AudioSpeechService = (*audioSpeech)(nil)
+
 type audioSpeech struct {
 	core *core
 }
@@ -8,7 +8,8 @@ import (
 	"strconv"
 )
 
-func (r *audioVoices) Clone(ctx context.Context, req *CloneAudioVoicesReq) (*CloneAudioVoicesResp, error) {
+func (r *audioVoices) Clone(ctx context.Context, req *CloneAudioVoicesReq, opts ...CallOption) (*CloneAudioVoicesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	path := "/v1/audio/voices/clone"
 	if req.File == nil {
 		return nil, fmt.Errorf("file is required")
@@ -46,7 +47,8 @@ func (r *audioVoices) Clone(ctx context.Context, req *CloneAudioVoicesReq) (*Clo
 	return resp.Data, nil
 }
 
-func (r *audioVoices) List(ctx context.Context, req *ListAudioVoicesReq) (NumberPaged[Voice], error) {
+func (r *audioVoices) List(ctx context.Context, req *ListAudioVoicesReq, opts ...CallOption) (NumberPaged[Voice], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.PageSize == 0 {
 		req.PageSize = 20
 	}
@@ -72,6 +74,14 @@ func (r *audioVoices) List(ctx context.Context, req *ListAudioVoicesReq) (Number
 		}, req.PageSize, req.PageNum)
 }
 
+// AudioVoicesService is the voice api, see AudioAPI.Voices.
+type AudioVoicesService interface {
+	Clone(ctx context.Context, req *CloneAudioVoicesReq, opts ...CallOption) (*CloneAudioVoicesResp, error)
+	List(ctx context.Context, req *ListAudioVoicesReq, opts ...CallOption) (NumberPaged[Voice], error)
+}
+
+var _ AudioVoicesService = (*audioVoices)(nil)
+
 type audioVoices struct {
 	core *core
 }
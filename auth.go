@@ -163,6 +163,9 @@ type oauthOption struct {
 	baseURL    string
 	wwwURL     string
 	httpClient HTTPClient
+	logger     Logger
+	logLevel   LogLevel
+	redaction  *RedactionConfig
 }
 
 type OAuthClientOption func(*oauthOption)
@@ -187,6 +190,28 @@ func WithAuthHttpClient(client HTTPClient) OAuthClientOption {
 	}
 }
 
+// WithAuthLogger sets the logger of the OAuth client
+func WithAuthLogger(logger Logger) OAuthClientOption {
+	return func(opt *oauthOption) {
+		opt.logger = logger
+	}
+}
+
+// WithAuthLogLevel sets the logging level of the OAuth client
+func WithAuthLogLevel(level LogLevel) OAuthClientOption {
+	return func(opt *oauthOption) {
+		opt.logLevel = level
+	}
+}
+
+// WithAuthLogRedaction configures the masking of secrets and personal data in the logs of the
+// OAuth client
+func WithAuthLogRedaction(config *RedactionConfig) OAuthClientOption {
+	return func(opt *oauthOption) {
+		opt.redaction = config
+	}
+}
+
 // newOAuthClient creates a new OAuth core
 func newOAuthClient(clientID, clientSecret string, opts ...OAuthClientOption) (*OAuthClient, error) {
 	initSettings := &oauthOption{
@@ -211,20 +236,23 @@ func newOAuthClient(clientID, clientSecret string, opts ...OAuthClientOption) (*
 	if initSettings.httpClient != nil {
 		httpClient = initSettings.httpClient
 	} else {
-		httpClient = http.DefaultClient
+		httpClient = defaultHTTPClient
 	}
 
 	if initSettings.wwwURL == "" {
 		initSettings.wwwURL = strings.Replace(initSettings.baseURL, "api.", "www.", 1)
 	}
 
+	core := newCore(httpClient, initSettings.baseURL)
+	core.logger = newClientLogger(initSettings.logger, initSettings.logLevel, initSettings.redaction)
+
 	return &OAuthClient{
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		baseURL:      initSettings.baseURL,
 		wwwURL:       initSettings.wwwURL,
 		hostName:     hostName,
-		core:         newCore(httpClient, initSettings.baseURL),
+		core:         core,
 	}, nil
 }
 
@@ -506,7 +534,7 @@ func (c *DeviceOAuthClient) GetAccessToken(ctx context.Context, dReq *GetDeviceO
 		return c.doGetAccessToken(ctx, req)
 	}
 
-	logger.Infof(ctx, "polling get access token\n")
+	c.core.logger.Infof(ctx, "polling get access token\n")
 	interval := 5
 	for {
 		var resp *OAuthToken
@@ -520,14 +548,14 @@ func (c *DeviceOAuthClient) GetAccessToken(ctx context.Context, dReq *GetDeviceO
 		}
 		switch authErr.Code {
 		case AuthorizationPending:
-			logger.Infof(ctx, "pending, sleep:%ds\n", interval)
+			c.core.logger.Infof(ctx, "pending, sleep:%ds\n", interval)
 		case SlowDown:
 			if interval < 30 {
 				interval += 5
 			}
-			logger.Infof(ctx, "slow down, sleep:%ds\n", interval)
+			c.core.logger.Infof(ctx, "slow down, sleep:%ds\n", interval)
 		default:
-			logger.Warnf(ctx, "get access token error:%s, return\n", err.Error())
+			c.core.logger.Warnf(ctx, "get access token error:%s, return\n", err.Error())
 			return nil, err
 		}
 		time.Sleep(time.Duration(interval) * time.Second)
@@ -6,7 +6,8 @@ import (
 	"strconv"
 )
 
-func (r *bots) Create(ctx context.Context, req *CreateBotsReq) (*CreateBotsResp, error) {
+func (r *bots) Create(ctx context.Context, req *CreateBotsReq, opts ...CallOption) (*CreateBotsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/bot/create"
 	resp := &createBotsResp{}
@@ -18,7 +19,8 @@ func (r *bots) Create(ctx context.Context, req *CreateBotsReq) (*CreateBotsResp,
 	return resp.Data, nil
 }
 
-func (r *bots) Update(ctx context.Context, req *UpdateBotsReq) (*UpdateBotsResp, error) {
+func (r *bots) Update(ctx context.Context, req *UpdateBotsReq, opts ...CallOption) (*UpdateBotsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/bot/update"
 	resp := &updateBotsResp{}
@@ -31,7 +33,8 @@ func (r *bots) Update(ctx context.Context, req *UpdateBotsReq) (*UpdateBotsResp,
 	return result, nil
 }
 
-func (r *bots) Publish(ctx context.Context, req *PublishBotsReq) (*PublishBotsResp, error) {
+func (r *bots) Publish(ctx context.Context, req *PublishBotsReq, opts ...CallOption) (*PublishBotsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/bot/publish"
 	resp := &publishBotsResp{}
@@ -43,7 +46,8 @@ func (r *bots) Publish(ctx context.Context, req *PublishBotsReq) (*PublishBotsRe
 	return resp.Data, nil
 }
 
-func (r *bots) Retrieve(ctx context.Context, req *RetrieveBotsReq) (*RetrieveBotsResp, error) {
+func (r *bots) Retrieve(ctx context.Context, req *RetrieveBotsReq, opts ...CallOption) (*RetrieveBotsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodGet
 	uri := "/v1/bot/get_online_info"
 	resp := &retrieveBotsResp{}
@@ -55,7 +59,8 @@ func (r *bots) Retrieve(ctx context.Context, req *RetrieveBotsReq) (*RetrieveBot
 	return resp.Bot, nil
 }
 
-func (r *bots) List(ctx context.Context, req *ListBotsReq) (NumberPaged[SimpleBot], error) {
+func (r *bots) List(ctx context.Context, req *ListBotsReq, opts ...CallOption) (NumberPaged[SimpleBot], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.PageSize == 0 {
 		req.PageSize = 20
 	}
@@ -82,6 +87,17 @@ func (r *bots) List(ctx context.Context, req *ListBotsReq) (NumberPaged[SimpleBo
 		}, req.PageSize, req.PageNum)
 }
 
+// BotsService is the bot api, see CozeAPI.Bots.
+type BotsService interface {
+	Create(ctx context.Context, req *CreateBotsReq, opts ...CallOption) (*CreateBotsResp, error)
+	Update(ctx context.Context, req *UpdateBotsReq, opts ...CallOption) (*UpdateBotsResp, error)
+	Publish(ctx context.Context, req *PublishBotsReq, opts ...CallOption) (*PublishBotsResp, error)
+	Retrieve(ctx context.Context, req *RetrieveBotsReq, opts ...CallOption) (*RetrieveBotsResp, error)
+	List(ctx context.Context, req *ListBotsReq, opts ...CallOption) (NumberPaged[SimpleBot], error)
+}
+
+var _ BotsService = (*bots)(nil)
+
 type bots struct {
 	core *core
 }
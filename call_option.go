@@ -0,0 +1,187 @@
+package coze
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// CallOption customizes a single API call, it is accepted by every service method.
+//
+// The options of a call sending several requests, such as Chat.CreateAndPoll, ChatSession.Send or
+// a workflow stream polling its run history, apply to each of its requests: the headers, query,
+// base URL and retry policy are sent with all of them, a response hook is called once per
+// response, and WithCallTimeout bounds each request rather than the whole call. The whole call is
+// bounded by the deadline of its context.
+type CallOption func(*callOption)
+
+type callOption struct {
+	requestOptions []RequestOption
+	baseURL        string
+	timeout        time.Duration
+	retryPolicy    *RetryPolicy
+	hasRetryPolicy bool
+	responseHooks  []func(resp *http.Response)
+
+	streamReconnect     *StreamReconnectPolicy
+	streamIdleTimeout   time.Duration
+	streamTimeout       time.Duration
+	cancelChatOnTimeout bool
+	cancelChatOnDone    bool
+}
+
+// WithCallHeader sets an http header on the request
+func WithCallHeader(key, value string) CallOption {
+	return func(opt *callOption) {
+		opt.requestOptions = append(opt.requestOptions, withHTTPHeader(key, value))
+	}
+}
+
+// WithCallQuery adds an http query parameter to the request
+func WithCallQuery(key, value string) CallOption {
+	return func(opt *callOption) {
+		opt.requestOptions = append(opt.requestOptions, withHTTPQuery(key, value))
+	}
+}
+
+// WithCallBaseURL sends the request to another base URL than the one of the client
+func WithCallBaseURL(baseURL string) CallOption {
+	return func(opt *callOption) {
+		opt.baseURL = baseURL
+	}
+}
+
+// WithCallTimeout bounds the duration of every request of the call, see CallOption. For streams and
+// binary responses, the timeout also covers reading the response body.
+func WithCallTimeout(timeout time.Duration) CallOption {
+	return func(opt *callOption) {
+		opt.timeout = timeout
+	}
+}
+
+// WithCallStreamIdleTimeout bounds the time Recv of a stream waits for the next event. Once
+// exceeded, the body is closed and Recv returns a *StreamTimeoutError.
+func WithCallStreamIdleTimeout(timeout time.Duration) CallOption {
+	return func(opt *callOption) {
+		opt.streamIdleTimeout = timeout
+	}
+}
+
+// WithCallStreamTimeout bounds the duration of a stream, from its response to its last event. Once
+// exceeded, the body is closed and Recv returns a *StreamTimeoutError. Unlike WithCallTimeout, it
+// does not cover the request.
+func WithCallStreamTimeout(timeout time.Duration) CallOption {
+	return func(opt *callOption) {
+		opt.streamTimeout = timeout
+	}
+}
+
+// WithCallCancelChatOnTimeout cancels the chat of a chat stream on the server once the stream
+// timed out, so that it stops generating, see WithCallStreamIdleTimeout and WithCallStreamTimeout.
+func WithCallCancelChatOnTimeout() CallOption {
+	return func(opt *callOption) {
+		opt.cancelChatOnTimeout = true
+	}
+}
+
+// WithCallCancelChatOnContextDone cancels the chat on the server once the context of the call is
+// done before the end of the chat, so that the bot stops generating. It applies to Chat.Stream and
+// Chat.StreamSubmitToolOutputs, Chat.CreateAndPoll always cancels its chat. The cancellation runs
+// with a short context detached from the done one, it is logged and reported to the metrics as a
+// chat.cancel call.
+func WithCallCancelChatOnContextDone() CallOption {
+	return func(opt *callOption) {
+		opt.cancelChatOnDone = true
+	}
+}
+
+// WithCallRetryPolicy overrides the retry policy of the client, nil disables retry for the call
+func WithCallRetryPolicy(policy *RetryPolicy) CallOption {
+	return func(opt *callOption) {
+		opt.retryPolicy = policy
+		opt.hasRetryPolicy = true
+	}
+}
+
+// WithCallResponseHook registers a hook called with the raw http response of every request of the
+// call, see CallOption
+func WithCallResponseHook(hook func(resp *http.Response)) CallOption {
+	return func(opt *callOption) {
+		opt.responseHooks = append(opt.responseHooks, hook)
+	}
+}
+
+const callOptionContextKey = contextKey("call_option")
+
+// withCallOptions stores the call options in the context, on top of the ones already stored by an
+// outer call, such as CreateAndPoll calling Create.
+func withCallOptions(ctx context.Context, opts []CallOption) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+	opt := &callOption{}
+	if parent := getCallOption(ctx); parent != nil {
+		*opt = *parent
+		opt.requestOptions = append([]RequestOption{}, parent.requestOptions...)
+		opt.responseHooks = append([]func(resp *http.Response){}, parent.responseHooks...)
+	}
+	for _, o := range opts {
+		o(opt)
+	}
+	return context.WithValue(ctx, callOptionContextKey, opt)
+}
+
+func getCallOption(ctx context.Context) *callOption {
+	opt, _ := ctx.Value(callOptionContextKey).(*callOption)
+	return opt
+}
+
+func (c *core) retryPolicyFor(ctx context.Context) *RetryPolicy {
+	if opt := getCallOption(ctx); opt != nil && opt.hasRetryPolicy {
+		return opt.retryPolicy
+	}
+	return c.retryPolicy
+}
+
+func (c *core) baseURLFor(ctx context.Context) string {
+	if opt := getCallOption(ctx); opt != nil && opt.baseURL != "" {
+		return opt.baseURL
+	}
+	return c.baseURL
+}
+
+// callTimeout applies the timeout of the call options to the context.
+func callTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if opt := getCallOption(ctx); opt != nil && opt.timeout > 0 {
+		return context.WithTimeout(ctx, opt.timeout)
+	}
+	return ctx, func() {}
+}
+
+func runResponseHooks(ctx context.Context, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	if opt := getCallOption(ctx); opt != nil {
+		for _, hook := range opt.responseHooks {
+			hook(resp)
+		}
+	}
+}
+
+// cancelOnClose cancels the context of the call once the caller closes the response body.
+func cancelOnClose(resp *http.Response, cancel context.CancelFunc) {
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,150 @@
+package coze
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallOptions(t *testing.T) {
+	t.Run("header, query and base url", func(t *testing.T) {
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "custom.api.coze.com", req.URL.Host)
+				assert.Equal(t, "/v3/chat/retrieve", req.URL.Path)
+				assert.Equal(t, "chat1", req.URL.Query().Get("chat_id"))
+				assert.Equal(t, "extra", req.URL.Query().Get("extra"))
+				assert.Equal(t, "tenant1", req.Header.Get("X-Tenant"))
+				return mockResponse(http.StatusOK, &retrieveChatsResp{Chat: &RetrieveChatsResp{Chat: Chat{ID: "chat1"}}})
+			},
+		}}, ComBaseURL)
+		chats := newChats(core)
+
+		resp, err := chats.Retrieve(context.Background(), &RetrieveChatsReq{ConversationID: "conv1", ChatID: "chat1"},
+			WithCallHeader("X-Tenant", "tenant1"),
+			WithCallQuery("extra", "extra"),
+			WithCallBaseURL("https://custom.api.coze.com"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "chat1", resp.ID)
+	})
+
+	t.Run("response hook", func(t *testing.T) {
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				return mockResponse(http.StatusOK, &meResp{User: &User{UserID: "user1"}})
+			},
+		}}, ComBaseURL)
+		users := newUsers(core)
+
+		var raw *http.Response
+		_, err := users.Me(context.Background(), WithCallResponseHook(func(resp *http.Response) {
+			raw = resp
+		}))
+		require.NoError(t, err)
+		require.NotNil(t, raw)
+		assert.Equal(t, "test_log_id", raw.Header.Get(logIDHeader))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			},
+		}}, ComBaseURL)
+		users := newUsers(core)
+
+		start := time.Now()
+		_, err := users.Me(context.Background(), WithCallTimeout(10*time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("stream timeout covers the body until close", func(t *testing.T) {
+		var reqCtx context.Context
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				reqCtx = req.Context()
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("event: done\ndata: \n")),
+				}, nil
+			},
+		}}, ComBaseURL)
+		chats := newChats(core)
+
+		stream, err := chats.Stream(context.Background(), &CreateChatsReq{}, WithCallTimeout(time.Minute))
+		require.NoError(t, err)
+		assert.NoError(t, reqCtx.Err())
+		require.NoError(t, stream.Close())
+		assert.ErrorIs(t, reqCtx.Err(), context.Canceled)
+	})
+
+	t.Run("retry override", func(t *testing.T) {
+		attempts := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return mockResponse(http.StatusServiceUnavailable, map[string]string{})
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+		chats := newChats(core)
+
+		_, err := chats.Retrieve(context.Background(), &RetrieveChatsReq{}, WithCallRetryPolicy(nil))
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+
+		attempts = 0
+		policy := newRetryTestPolicy()
+		policy.RetryNonIdempotent = true
+		_, err = chats.Cancel(context.Background(), &CancelChatsReq{}, WithCallRetryPolicy(policy))
+		require.Error(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("options of the outer call apply to inner calls", func(t *testing.T) {
+		ctx := withCallOptions(context.Background(), []CallOption{WithCallHeader("X-A", "a")})
+		ctx = withCallOptions(ctx, []CallOption{WithCallHeader("X-B", "b")})
+		req, err := newCore(nil, ComBaseURL).newRequest(ctx, http.MethodGet, "/test", nil, "application/json")
+		require.NoError(t, err)
+		assert.Equal(t, "a", req.Header.Get("X-A"))
+		assert.Equal(t, "b", req.Header.Get("X-B"))
+		assert.Same(t, ctx, withCallOptions(ctx, nil))
+	})
+
+	t.Run("options apply to every request of a call", func(t *testing.T) {
+		var paths, headers []string
+		hooks := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				paths = append(paths, req.URL.Path)
+				headers = append(headers, req.Header.Get("X-Test"))
+				switch req.URL.Path {
+				case "/v3/chat":
+					return mockResponse(http.StatusOK, &createChatsResp{Chat: &CreateChatsResp{Chat: Chat{ID: "chat1", ConversationID: "conv1"}}})
+				case "/v3/chat/retrieve":
+					return mockResponse(http.StatusOK, &retrieveChatsResp{Chat: &RetrieveChatsResp{Chat: Chat{ID: "chat1", ConversationID: "conv1", Status: ChatStatusCompleted}}})
+				}
+				return mockResponse(http.StatusOK, &listChatsMessagesResp{ListChatsMessagesResp: &ListChatsMessagesResp{}})
+			},
+		}}, ComBaseURL)
+
+		_, err := newChats(core).CreateAndPoll(context.Background(), &CreateChatsReq{BotID: "bot1"},
+			&ChatPollOptions{PollOptions: PollOptions{InitialInterval: time.Millisecond}},
+			WithCallHeader("X-Test", "1"),
+			WithCallResponseHook(func(resp *http.Response) { hooks++ }))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/v3/chat", "/v3/chat/retrieve", "/v3/chat/message/list"}, paths)
+		assert.Equal(t, []string{"1", "1", "1"}, headers)
+		assert.Equal(t, 3, hooks)
+	})
+}
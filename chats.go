@@ -1,16 +1,15 @@
 package coze
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
-	"strings"
+	"sync"
 	"time"
 )
 
-func (r *chat) Create(ctx context.Context, req *CreateChatsReq) (*CreateChatsResp, error) {
+func (r *chat) Create(ctx context.Context, req *CreateChatsReq, opts ...CallOption) (*CreateChatsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v3/chat"
 	resp := &createChatsResp{}
@@ -24,7 +23,14 @@ func (r *chat) Create(ctx context.Context, req *CreateChatsReq) (*CreateChatsRes
 	return resp.Chat, nil
 }
 
-func (r *chat) CreateAndPoll(ctx context.Context, req *CreateChatsReq, timeout *int) (*ChatPoll, error) {
+// CreateAndPoll creates the chat, and waits for its end, see ChatPollOptions. It returns the chat
+// and its messages once it is completed or canceled, or requires action without
+// ChatPollOptions.OnRequiresAction, and a *ChatFailedError once it failed.
+//
+// The polling is bounded by the deadline of the context, nil options poll with the defaults. Once
+// the context is done, the chat is canceled on the server.
+func (r *chat) CreateAndPoll(ctx context.Context, req *CreateChatsReq, pollOpts *ChatPollOptions, opts ...CallOption) (*ChatPoll, error) {
+	ctx = withCallOptions(ctx, opts)
 	req.Stream = ptr(false)
 	req.AutoSaveHistory = ptr(true)
 
@@ -32,51 +38,21 @@ func (r *chat) CreateAndPoll(ctx context.Context, req *CreateChatsReq, timeout *
 	if err != nil {
 		return nil, err
 	}
-	chat := chatResp.Chat
-	conversationID := chat.ConversationID
-	now := time.Now()
-	for {
-		time.Sleep(time.Second)
-		if timeout != nil && time.Since(now) > time.Duration(*timeout)*time.Second {
-			logger.Infof(ctx, "Create timeout: ", *timeout, " seconds, cancel Create")
-			cancelResp, err := r.Cancel(ctx, &CancelChatsReq{
-				ConversationID: conversationID,
-				ChatID:         chat.ID,
-			})
-			if err != nil {
-				logger.Warnf(ctx, "Cancel chat failed, err:%v", err)
-				return nil, err
-			}
-			chat = cancelResp.Chat
-			break
-		}
-		retrieveChat, err := r.Retrieve(ctx, &RetrieveChatsReq{
-			ConversationID: conversationID,
-			ChatID:         chat.ID,
-		})
-		if err != nil {
-			return nil, err
-		}
-		if retrieveChat.Chat.Status == ChatStatusCompleted {
-			chat = retrieveChat.Chat
-			logger.Infof(ctx, "Create completed, spend: %v", time.Since(now))
-			break
-		}
+	if pollOpts == nil {
+		pollOpts = &ChatPollOptions{}
 	}
-	messages, err := r.Messages.List(ctx, &ListChatsMessagesReq{
-		ConversationID: conversationID,
-		ChatID:         chat.ID,
-	})
-	if err != nil {
-		return nil, err
+	poll, err := pollChat(ctx, r, r.Messages, chatResp.Chat, pollOpts)
+	if err != nil && ctx.Err() != nil {
+		cancelChatDetached(ctx, r.client, chatResp.ConversationID, chatResp.ID, "context done")
 	}
-	return &ChatPoll{
-		Chat:     &chat,
-		Messages: messages.Messages,
-	}, nil
+	if err == nil {
+		r.client.logger.Infof(ctx, "Create %s, spend: %v", poll.Chat.Status, poll.Duration)
+	}
+	return poll, err
 }
 
-func (r *chat) Stream(ctx context.Context, req *CreateChatsReq) (Stream[ChatEvent], error) {
+func (r *chat) Stream(ctx context.Context, req *CreateChatsReq, opts ...CallOption) (Stream[ChatEvent], error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v3/chat"
 	req.Stream = ptr(true)
@@ -85,15 +61,33 @@ func (r *chat) Stream(ctx context.Context, req *CreateChatsReq) (Stream[ChatEven
 		return nil, err
 	}
 
-	return &streamReader[ChatEvent]{
-		ctx:          ctx,
-		response:     resp,
-		reader:       bufio.NewReader(resp.Body),
-		processor:    parseChatEvent,
-		httpResponse: newHTTPResponse(resp),
-	}, nil
+	return newChatStreamReader(ctx, r.client, resp, req.ConversationID, ""), nil
+}
+
+// ChatService is the chat api, see CozeAPI.Chat.
+type ChatService interface {
+	Create(ctx context.Context, req *CreateChatsReq, opts ...CallOption) (*CreateChatsResp, error)
+	CreateAndPoll(ctx context.Context, req *CreateChatsReq, pollOpts *ChatPollOptions, opts ...CallOption) (*ChatPoll, error)
+	Stream(ctx context.Context, req *CreateChatsReq, opts ...CallOption) (Stream[ChatEvent], error)
+	Cancel(ctx context.Context, req *CancelChatsReq, opts ...CallOption) (*CancelChatsResp, error)
+	Retrieve(ctx context.Context, req *RetrieveChatsReq, opts ...CallOption) (*RetrieveChatsResp, error)
+	SubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq, opts ...CallOption) (*SubmitToolOutputsChatResp, error)
+	StreamSubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq, opts ...CallOption) (Stream[ChatEvent], error)
+}
+
+// ChatAPI is the chat api and its sub-services.
+type ChatAPI struct {
+	ChatService
+	Messages ChatMessagesService
 }
 
+func newChatAPI(core *core) *ChatAPI {
+	chat := newChats(core)
+	return &ChatAPI{ChatService: chat, Messages: chat.Messages}
+}
+
+var _ ChatService = (*chat)(nil)
+
 type chat struct {
 	client   *core
 	Messages *chatMessages
@@ -106,32 +100,22 @@ func newChats(core *core) *chat {
 	}
 }
 
-func parseChatEvent(lineBytes []byte, reader *bufio.Reader) (*ChatEvent, bool, error) {
-	line := string(lineBytes)
-	if strings.HasPrefix(line, "event:") {
-		event := strings.TrimSpace(line[6:])
-		data, err := reader.ReadString('\n')
-		if err != nil {
-			return nil, false, err
-		}
-		data = strings.TrimSpace(data[5:])
-
-		eventLine := map[string]string{
-			"event": event,
-			"data":  data,
+// chatEventDecoder returns the decoder of chat streams, logging with the logger of the client.
+func chatEventDecoder(logger *levelLogger) eventDecoder[ChatEvent] {
+	return func(event *sseEvent) (*ChatEvent, bool, error) {
+		if event.Event == "" {
+			return nil, false, nil
 		}
-
-		eventData, err := doParseChatEvent(eventLine)
+		eventData, err := doParseChatEvent(logger, ChatEventType(event.Event), event.Data)
 		if err != nil {
 			return nil, false, err
 		}
-
 		return eventData, eventData.IsDone(), nil
 	}
-	return nil, false, nil
 }
 
-func (r *chat) Cancel(ctx context.Context, req *CancelChatsReq) (*CancelChatsResp, error) {
+func (r *chat) Cancel(ctx context.Context, req *CancelChatsReq, opts ...CallOption) (*CancelChatsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v3/chat/cancel"
 	resp := &cancelChatsResp{}
@@ -143,7 +127,8 @@ func (r *chat) Cancel(ctx context.Context, req *CancelChatsReq) (*CancelChatsRes
 	return resp.Chat, nil
 }
 
-func (r *chat) Retrieve(ctx context.Context, req *RetrieveChatsReq) (*RetrieveChatsResp, error) {
+func (r *chat) Retrieve(ctx context.Context, req *RetrieveChatsReq, opts ...CallOption) (*RetrieveChatsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodGet
 	uri := "/v3/chat/retrieve"
 	resp := &retrieveChatsResp{}
@@ -158,7 +143,8 @@ func (r *chat) Retrieve(ctx context.Context, req *RetrieveChatsReq) (*RetrieveCh
 	return resp.Chat, nil
 }
 
-func (r *chat) SubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq) (*SubmitToolOutputsChatResp, error) {
+func (r *chat) SubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq, opts ...CallOption) (*SubmitToolOutputsChatResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v3/chat/submit_tool_outputs"
 	resp := &submitToolOutputsChatResp{}
@@ -174,7 +160,8 @@ func (r *chat) SubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChat
 	return resp.Chat, nil
 }
 
-func (r *chat) StreamSubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq) (Stream[ChatEvent], error) {
+func (r *chat) StreamSubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq, opts ...CallOption) (Stream[ChatEvent], error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	req.Stream = ptr(true)
 	uri := "/v3/chat/submit_tool_outputs"
@@ -186,13 +173,7 @@ func (r *chat) StreamSubmitToolOutputs(ctx context.Context, req *SubmitToolOutpu
 		return nil, err
 	}
 
-	return &streamReader[ChatEvent]{
-		ctx:          ctx,
-		response:     resp,
-		reader:       bufio.NewReader(resp.Body),
-		processor:    parseChatEvent,
-		httpResponse: newHTTPResponse(resp),
-	}, nil
+	return newChatStreamReader(ctx, r.client, resp, req.ConversationID, req.ChatID), nil
 }
 
 // ChatStatus The running status of the session.
@@ -452,9 +433,87 @@ type ChatEvent struct {
 	WorkflowDebug *WorkflowDebug `json:"workflow_debug,omitempty"`
 }
 
-func doParseChatEvent(eventLine map[string]string) (*ChatEvent, error) {
-	eventType := ChatEventType(eventLine["event"])
-	data := eventLine["data"]
+// newChatStreamReader returns the reader of a chat stream. The ids of the chat, given or sent by the
+// chat events, are used to cancel the chat on the server once the stream timed out, if the call
+// options ask for it.
+func newChatStreamReader(ctx context.Context, core *core, resp *http.Response, conversationID, chatID string) *streamReader[ChatEvent] {
+	ids := &chatStreamIDs{conversationID: conversationID, chatID: chatID}
+	reader := newStreamReader(ctx, core, resp, ids.decoder(chatEventDecoder(core.logger)))
+	opt := getCallOption(ctx)
+	if opt == nil {
+		return reader
+	}
+	if opt.cancelChatOnTimeout {
+		reader.onTimeout = func(ctx context.Context) {
+			conversationID, chatID := ids.get()
+			cancelChatDetached(ctx, core, conversationID, chatID, "stream timeout")
+		}
+	}
+	if opt.cancelChatOnDone {
+		reader.stopWatch = afterDone(ctx, func() {
+			conversationID, chatID := ids.get()
+			cancelChatDetached(ctx, core, conversationID, chatID, "context done")
+		})
+	}
+	return reader
+}
+
+// chatStreamIDs records the ids of the chat of a stream.
+type chatStreamIDs struct {
+	mu             sync.Mutex
+	conversationID string
+	chatID         string
+}
+
+func (ids *chatStreamIDs) decoder(decoder eventDecoder[ChatEvent]) eventDecoder[ChatEvent] {
+	return func(event *sseEvent) (*ChatEvent, bool, error) {
+		chatEvent, isDone, err := decoder(event)
+		if chatEvent != nil && chatEvent.Chat != nil {
+			ids.mu.Lock()
+			if chatEvent.Chat.ConversationID != "" {
+				ids.conversationID = chatEvent.Chat.ConversationID
+			}
+			if chatEvent.Chat.ID != "" {
+				ids.chatID = chatEvent.Chat.ID
+			}
+			ids.mu.Unlock()
+		}
+		return chatEvent, isDone, err
+	}
+}
+
+func (ids *chatStreamIDs) get() (conversationID, chatID string) {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	return ids.conversationID, ids.chatID
+}
+
+// cancelChatTimeout bounds the cancellation of a chat by the SDK.
+const cancelChatTimeout = 10 * time.Second
+
+// cancelChatDetached cancels a chat on the server on behalf of a call which failed, even if the
+// context of the call is done. The outcome is logged, the call having already failed.
+func cancelChatDetached(ctx context.Context, core *core, conversationID, chatID, reason string) {
+	fields := []LogField{
+		{Key: LogKeyConversationID, Value: conversationID},
+		{Key: LogKeyChatID, Value: chatID},
+		{Key: "reason", Value: reason},
+	}
+	if conversationID == "" || chatID == "" {
+		core.logger.LogFields(ctx, LogLevelWarn, "cannot cancel the chat, its id was not received", fields...)
+		return
+	}
+	ctx, cancel := context.WithTimeout(detachContext(ctx), cancelChatTimeout)
+	defer cancel()
+	_, err := newChats(core).Cancel(ctx, &CancelChatsReq{ConversationID: conversationID, ChatID: chatID})
+	if err != nil {
+		core.logger.LogFields(ctx, LogLevelWarn, "cancel chat failed", append(fields, LogField{Key: LogKeyError, Value: err})...)
+		return
+	}
+	core.logger.LogFields(ctx, LogLevelInfo, "chat canceled", fields...)
+}
+
+func doParseChatEvent(logger *levelLogger, eventType ChatEventType, data string) (*ChatEvent, error) {
 	switch eventType {
 	case ChatEventDone:
 		workflowDebug := &WorkflowDebug{}
@@ -466,7 +525,7 @@ func doParseChatEvent(eventLine map[string]string) (*ChatEvent, error) {
 		}
 		return &ChatEvent{Event: eventType, WorkflowDebug: workflowDebug}, nil
 	case ChatEventError:
-		return nil, errors.New(data)
+		return nil, parseChatErrorEvent(data)
 	case ChatEventConversationMessageDelta, ChatEventConversationMessageCompleted, ChatEventConversationAudioDelta:
 		message := &Message{}
 		if err := json.Unmarshal([]byte(data), message); err != nil {
@@ -484,6 +543,16 @@ func doParseChatEvent(eventLine map[string]string) (*ChatEvent, error) {
 	}
 }
 
+// parseChatErrorEvent returns the error reported by the data of an error event, which is
+// {"code": ..., "msg": ...}, or a plain message.
+func parseChatErrorEvent(data string) error {
+	resp := &baseResponse{}
+	if json.Unmarshal([]byte(data), resp) != nil || (resp.Code == 0 && resp.Msg == "") {
+		return &StreamError{Message: data}
+	}
+	return &StreamError{Code: resp.Code, Message: resp.Msg}
+}
+
 func (c *ChatEvent) IsDone() bool {
 	return c.Event == ChatEventDone || c.Event == ChatEventError
 }
@@ -492,4 +561,18 @@ func (c *ChatEvent) IsDone() bool {
 type ChatPoll struct {
 	Chat     *Chat      `json:"chat"`
 	Messages []*Message `json:"messages"`
+
+	// Polls is the number of retrievals of the chat, Rounds the number of tool outputs submitted,
+	// and Duration the time from the creation of the chat to its end.
+	Polls    int           `json:"polls,omitempty"`
+	Rounds   int           `json:"rounds,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// Usage returns the token usage of the chat, nil if it is unknown.
+func (p *ChatPoll) Usage() *ChatUsage {
+	if p.Chat == nil {
+		return nil
+	}
+	return p.Chat.Usage
 }
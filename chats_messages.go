@@ -5,7 +5,8 @@ import (
 	"net/http"
 )
 
-func (r *chatMessages) List(ctx context.Context, req *ListChatsMessagesReq) (*ListChatsMessagesResp, error) {
+func (r *chatMessages) List(ctx context.Context, req *ListChatsMessagesReq, opts ...CallOption) (*ListChatsMessagesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodGet
 	uri := "/v3/chat/message/list"
 	resp := &listChatsMessagesResp{}
@@ -25,6 +26,13 @@ func (r *chatMessages) List(ctx context.Context, req *ListChatsMessagesReq) (*Li
 	return result, nil
 }
 
+// ChatMessagesService is the api of the messages of a chat, see ChatAPI.Messages.
+type ChatMessagesService interface {
+	List(ctx context.Context, req *ListChatsMessagesReq, opts ...CallOption) (*ListChatsMessagesResp, error)
+}
+
+var _ ChatMessagesService = (*chatMessages)(nil)
+
 type chatMessages struct {
 	core *core
 }
@@ -0,0 +1,118 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChatPollOptions configures how Chat.CreateAndPoll waits for the end of the chat. The polling is
+// bounded by the deadline of the context.
+type ChatPollOptions struct {
+	// PollOptions are the intervals between two retrievals of the chat, DefaultPollOptions by
+	// default.
+	PollOptions
+
+	// OnRequiresAction is called with the chat requiring the outputs of its tools. The outputs
+	// returned are submitted, and the polling goes on until the next end of the chat. Its error
+	// stops the polling. When nil, the polling ends, and the chat requiring action is returned.
+	OnRequiresAction func(ctx context.Context, chat *Chat) ([]*ToolOutput, error)
+}
+
+// ChatFailedError is returned by Chat.CreateAndPoll for a chat which failed. It matches the kind of
+// the code of its last error with errors.Is, when the code is known to LookupErrorCode.
+type ChatFailedError struct {
+	// Chat is the failed chat, with its usage.
+	Chat *Chat
+
+	// LastError is the error of the chat, nil if the server did not report it.
+	LastError *ChatError
+
+	// LogID is the log ID of the retrieval of the failed chat.
+	LogID string
+}
+
+// Error implements the error interface
+func (e *ChatFailedError) Error() string {
+	code, msg := 0, ""
+	if e.LastError != nil {
+		code, msg = e.LastError.Code, e.LastError.Msg
+	}
+	return fmt.Sprintf("chat %s failed: code=%d, message=%s, logid=%s", e.Chat.ID, code, msg, e.LogID)
+}
+
+// Is matches the kind of the code of the last error.
+func (e *ChatFailedError) Is(target error) bool {
+	if e.LastError == nil {
+		return false
+	}
+	info, ok := LookupErrorCode(e.LastError.Code)
+	return ok && info.Kind != nil && info.Kind == target
+}
+
+// AsChatFailedError checks if the error is of type ChatFailedError
+func AsChatFailedError(err error) (*ChatFailedError, bool) {
+	var chatErr *ChatFailedError
+	if errors.As(err, &chatErr) {
+		return chatErr, true
+	}
+	return nil, false
+}
+
+// pollChat waits for the end of the chat, answering its tool calls with OnRequiresAction, and lists
+// its messages. A completed or canceled chat, or one requiring action without OnRequiresAction,
+// is returned, a failed one is a *ChatFailedError.
+func pollChat(ctx context.Context, chats ChatService, messages ChatMessagesService, chat Chat, opts *ChatPollOptions, callOpts ...CallOption) (*ChatPoll, error) {
+	started := time.Now()
+	polls, rounds := 0, 0
+	logID := ""
+	for {
+		retrieved, n, err := PollUntil(ctx, &opts.PollOptions, func(ctx context.Context) (*RetrieveChatsResp, bool, error) {
+			resp, err := chats.Retrieve(ctx, &RetrieveChatsReq{ConversationID: chat.ConversationID, ChatID: chat.ID}, callOpts...)
+			if err != nil {
+				return nil, false, err
+			}
+			return resp, resp.Status != ChatStatusCreated && resp.Status != ChatStatusInProgress, nil
+		})
+		polls += n
+		if err != nil {
+			return nil, err
+		}
+		chat = retrieved.Chat
+		if retrieved.httpResponse != nil {
+			logID = retrieved.LogID()
+		}
+		if chat.Status != ChatStatusRequiresAction || opts.OnRequiresAction == nil {
+			break
+		}
+
+		outputs, err := opts.OnRequiresAction(ctx, &chat)
+		if err != nil {
+			return nil, err
+		}
+		rounds++
+		if _, err := chats.SubmitToolOutputs(ctx, &SubmitToolOutputsChatReq{
+			ConversationID: chat.ConversationID,
+			ChatID:         chat.ID,
+			ToolOutputs:    outputs,
+		}, callOpts...); err != nil {
+			return nil, err
+		}
+	}
+
+	if chat.Status == ChatStatusFailed {
+		return nil, &ChatFailedError{Chat: &chat, LastError: chat.LastError, LogID: logID}
+	}
+	list, err := messages.List(ctx, &ListChatsMessagesReq{ConversationID: chat.ConversationID, ChatID: chat.ID}, callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ChatPoll{
+		Chat:     &chat,
+		Messages: list.Messages,
+		Polls:    polls,
+		Rounds:   rounds,
+		Duration: time.Since(started),
+	}, nil
+}
@@ -0,0 +1,136 @@
+package coze
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPollTestChats returns the chat api of a server whose retrievals of the chat return the
+// statuses in order, the last one repeated, and the tool outputs it receives.
+func newPollTestChats(t *testing.T, chats ...Chat) (*chat, *[][]*ToolOutput) {
+	var outputs [][]*ToolOutput
+	core := newCore(&http.Client{Transport: &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/v3/chat":
+				return mockResponse(http.StatusOK, &createChatsResp{Chat: &CreateChatsResp{Chat: Chat{
+					ID: "chat1", ConversationID: "conv1", Status: ChatStatusCreated,
+				}}})
+			case "/v3/chat/retrieve":
+				chat := chats[0]
+				if len(chats) > 1 {
+					chats = chats[1:]
+				}
+				chat.ID, chat.ConversationID = "chat1", "conv1"
+				resp, err := mockResponse(http.StatusOK, &retrieveChatsResp{Chat: &RetrieveChatsResp{Chat: chat}})
+				resp.Header.Set(logIDHeader, "retrieve_log_id")
+				return resp, err
+			case "/v3/chat/submit_tool_outputs":
+				var body SubmitToolOutputsChatReq
+				require.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+				outputs = append(outputs, body.ToolOutputs)
+				return mockResponse(http.StatusOK, &submitToolOutputsChatResp{Chat: &SubmitToolOutputsChatResp{}})
+			case "/v3/chat/message/list":
+				return mockResponse(http.StatusOK, &listChatsMessagesResp{ListChatsMessagesResp: &ListChatsMessagesResp{
+					Messages: []*Message{{ID: "msg1", Content: "Hello!"}},
+				}})
+			case "/v3/chat/cancel":
+				return mockResponse(http.StatusOK, &cancelChatsResp{Chat: &CancelChatsResp{}})
+			}
+			t.Fatalf("unexpected request %s", req.URL.Path)
+			return nil, nil
+		},
+	}}, ComBaseURL)
+	return newChats(core), &outputs
+}
+
+func TestCreateAndPoll(t *testing.T) {
+	ctx := context.Background()
+	req := func() *CreateChatsReq { return &CreateChatsReq{BotID: "bot1", UserID: "user1"} }
+	fast := &ChatPollOptions{PollOptions: PollOptions{InitialInterval: time.Millisecond}}
+
+	t.Run("completed", func(t *testing.T) {
+		chats, _ := newPollTestChats(t,
+			Chat{Status: ChatStatusInProgress},
+			Chat{Status: ChatStatusInProgress},
+			Chat{Status: ChatStatusCompleted, Usage: &ChatUsage{TokenCount: 10}},
+		)
+		poll, err := chats.CreateAndPoll(ctx, req(), fast)
+		require.NoError(t, err)
+		assert.Equal(t, ChatStatusCompleted, poll.Chat.Status)
+		assert.Equal(t, 10, poll.Usage().TokenCount)
+		assert.Equal(t, 3, poll.Polls)
+		assert.Equal(t, 0, poll.Rounds)
+		assert.Greater(t, poll.Duration, time.Duration(0))
+		require.Len(t, poll.Messages, 1)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		chats, _ := newPollTestChats(t, Chat{Status: ChatStatusCancelled})
+		poll, err := chats.CreateAndPoll(ctx, req(), fast)
+		require.NoError(t, err)
+		assert.Equal(t, ChatStatusCancelled, poll.Chat.Status)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		chats, _ := newPollTestChats(t, Chat{Status: ChatStatusFailed, LastError: &ChatError{Code: 4019, Msg: "insufficient balance"}})
+		_, err := chats.CreateAndPoll(ctx, req(), fast)
+		chatErr, ok := AsChatFailedError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "chat1", chatErr.Chat.ID)
+		assert.Equal(t, &ChatError{Code: 4019, Msg: "insufficient balance"}, chatErr.LastError)
+		assert.Equal(t, "retrieve_log_id", chatErr.LogID)
+		assert.Equal(t, "chat chat1 failed: code=4019, message=insufficient balance, logid=retrieve_log_id", err.Error())
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.False(t, IsRetryable(err))
+	})
+
+	t.Run("requires action without callback", func(t *testing.T) {
+		chats, outputs := newPollTestChats(t, requiresActionChat(toolCall("call1", "get_weather", "{}")))
+		poll, err := chats.CreateAndPoll(ctx, req(), fast)
+		require.NoError(t, err)
+		assert.Equal(t, ChatStatusRequiresAction, poll.Chat.Status)
+		assert.Equal(t, "call1", poll.Chat.RequiredAction.SubmitToolOutputs.ToolCalls[0].ID)
+		assert.Empty(t, *outputs)
+	})
+
+	t.Run("requires action callback", func(t *testing.T) {
+		chats, outputs := newPollTestChats(t,
+			requiresActionChat(toolCall("call1", "get_weather", "{}")),
+			Chat{Status: ChatStatusInProgress},
+			Chat{Status: ChatStatusCompleted},
+		)
+		var actions []string
+		poll, err := chats.CreateAndPoll(ctx, req(), &ChatPollOptions{
+			PollOptions: PollOptions{InitialInterval: time.Millisecond},
+			OnRequiresAction: func(ctx context.Context, chat *Chat) ([]*ToolOutput, error) {
+				actions = append(actions, chat.RequiredAction.SubmitToolOutputs.ToolCalls[0].ID)
+				return []*ToolOutput{{ToolCallID: "call1", Output: "sunny"}}, nil
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ChatStatusCompleted, poll.Chat.Status)
+		assert.Equal(t, []string{"call1"}, actions)
+		assert.Equal(t, [][]*ToolOutput{{{ToolCallID: "call1", Output: "sunny"}}}, *outputs)
+		assert.Equal(t, 3, poll.Polls)
+		assert.Equal(t, 1, poll.Rounds)
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		chats, _ := newPollTestChats(t, Chat{Status: ChatStatusInProgress})
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := chats.CreateAndPoll(ctx, req(), &ChatPollOptions{
+			PollOptions: PollOptions{InitialInterval: time.Hour},
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,295 @@
+package coze
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// NewChatSessionParam is the bot, the user and the defaults of a chat session, see NewChatSession.
+type NewChatSessionParam struct {
+	// BotID and UserID are the bot and the user chatting, required.
+	BotID  string
+	UserID string
+
+	// ConversationID continues an existing conversation. When empty, the conversation is created
+	// with the first message.
+	ConversationID string
+
+	// CustomVariables and MetaData are sent with every chat of the session.
+	CustomVariables map[string]string
+	MetaData        map[string]string
+
+	// MaxTranscript caps the number of messages kept in the transcript, and so in the saved state,
+	// the oldest ones are dropped first. Zero keeps all the messages, a negative value none: the
+	// messages of the conversation can still be listed with Conversations.Messages.List.
+	MaxTranscript int
+}
+
+// ChatSession is a multi-turn chat of a user with a bot, in a conversation created with the first
+// message. It keeps the transcript of the messages of the session, up to
+// NewChatSessionParam.MaxTranscript, and its state can be saved and restored, see MarshalState and
+// RestoreChatSession.
+//
+// The methods are safe for concurrent use, but the chats of a conversation must not overlap: a
+// message is sent once the previous chat ended.
+type ChatSession struct {
+	chats         ChatService
+	conversations ConversationsService
+
+	// create serializes the creation of the conversation.
+	create sync.Mutex
+
+	mu       sync.Mutex
+	state    ChatSessionState
+	pollOpts *ChatPollOptions
+}
+
+// ChatSessionState is the state of a chat session, see ChatSession.MarshalState.
+type ChatSessionState struct {
+	BotID           string            `json:"bot_id"`
+	UserID          string            `json:"user_id"`
+	ConversationID  string            `json:"conversation_id,omitempty"`
+	SectionID       string            `json:"section_id,omitempty"`
+	CustomVariables map[string]string `json:"custom_variables,omitempty"`
+	MetaData        map[string]string `json:"meta_data,omitempty"`
+	MaxTranscript   int               `json:"max_transcript,omitempty"`
+
+	// Transcript is the messages of the current section of the conversation, the questions of the
+	// user included, at most MaxTranscript of them.
+	Transcript []*Message `json:"transcript,omitempty"`
+}
+
+// NewChatSession returns a session of the user with the bot, chatting with the client.
+func NewChatSession(api CozeAPI, param NewChatSessionParam) *ChatSession {
+	return &ChatSession{
+		chats:         api.Chat,
+		conversations: api.Conversations,
+		state: ChatSessionState{
+			BotID:           param.BotID,
+			UserID:          param.UserID,
+			ConversationID:  param.ConversationID,
+			CustomVariables: copyStringMap(param.CustomVariables),
+			MetaData:        copyStringMap(param.MetaData),
+			MaxTranscript:   param.MaxTranscript,
+		},
+	}
+}
+
+// RestoreChatSession returns the session saved by ChatSession.MarshalState, chatting with the
+// client.
+func RestoreChatSession(api CozeAPI, data []byte) (*ChatSession, error) {
+	var state ChatSessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode chat session: %w", err)
+	}
+	if state.BotID == "" || state.UserID == "" {
+		return nil, errors.New("invalid chat session: bot_id and user_id are required")
+	}
+	return &ChatSession{chats: api.Chat, conversations: api.Conversations, state: state}, nil
+}
+
+// MarshalState returns the state of the session as JSON, to restore it with RestoreChatSession.
+func (s *ChatSession) MarshalState() ([]byte, error) {
+	return json.Marshal(s.State())
+}
+
+// State returns a copy of the state of the session.
+func (s *ChatSession) State() *ChatSessionState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.state
+	state.CustomVariables = copyStringMap(state.CustomVariables)
+	state.MetaData = copyStringMap(state.MetaData)
+	state.Transcript = copyMessages(state.Transcript)
+	return &state
+}
+
+// ConversationID returns the ID of the conversation of the session, empty before the first message.
+func (s *ChatSession) ConversationID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.ConversationID
+}
+
+// SectionID returns the ID of the current context section of the conversation, which changes once
+// the session is reset.
+func (s *ChatSession) SectionID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.SectionID
+}
+
+// Transcript returns the messages of the current section, the questions of the user included.
+func (s *ChatSession) Transcript() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyMessages(s.state.Transcript)
+}
+
+// SetCustomVariable sets a custom variable sent with the next chats.
+func (s *ChatSession) SetCustomVariable(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state.CustomVariables == nil {
+		s.state.CustomVariables = map[string]string{}
+	}
+	s.state.CustomVariables[key] = value
+}
+
+// SetPollOptions sets how Send waits for the end of the chats, see ChatPollOptions.
+func (s *ChatSession) SetPollOptions(opts *ChatPollOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pollOpts = opts
+}
+
+// Send sends the text message of the user, and waits for the end of the chat like
+// Chat.CreateAndPoll. The question and the messages of the bot are added to the transcript.
+func (s *ChatSession) Send(ctx context.Context, content string, opts ...CallOption) (*ChatPoll, error) {
+	question := BuildUserQuestionText(content, nil)
+	req, err := s.request(ctx, question, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	pollOpts := s.pollOpts
+	s.mu.Unlock()
+	poll, err := s.chats.CreateAndPoll(ctx, req, pollOpts, opts...)
+	if _, ok := AsChatFailedError(err); ok {
+		s.record(question)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.record(append([]*Message{question}, poll.Messages...)...)
+	return poll, nil
+}
+
+// SendStream sends the text message of the user, and streams the chat like Chat.Stream. The
+// question and the messages completed by the stream are added to the transcript.
+func (s *ChatSession) SendStream(ctx context.Context, content string, opts ...CallOption) (Stream[ChatEvent], error) {
+	question := BuildUserQuestionText(content, nil)
+	req, err := s.request(ctx, question, opts)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := s.chats.Stream(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s.record(question)
+	return &sessionStream{Stream: stream, session: s}, nil
+}
+
+// Reset starts a new context section of the conversation with Conversations.Clear, the bot
+// forgetting the previous messages, and empties the transcript.
+func (s *ChatSession) Reset(ctx context.Context, opts ...CallOption) error {
+	sectionID := ""
+	if conversationID := s.ConversationID(); conversationID != "" {
+		resp, err := s.conversations.Clear(ctx, &ClearConversationsReq{ConversationID: conversationID}, opts...)
+		if err != nil {
+			return err
+		}
+		sectionID = resp.SectionID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sectionID != "" {
+		s.state.SectionID = sectionID
+	}
+	s.state.Transcript = nil
+	return nil
+}
+
+// request returns the request of a chat sending the message, in the conversation of the session.
+// The options of the chat apply to the creation of the conversation.
+func (s *ChatSession) request(ctx context.Context, message *Message, opts []CallOption) (*CreateChatsReq, error) {
+	conversationID, err := s.conversation(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &CreateChatsReq{
+		ConversationID:  conversationID,
+		BotID:           s.state.BotID,
+		UserID:          s.state.UserID,
+		Messages:        []*Message{message},
+		CustomVariables: copyStringMap(s.state.CustomVariables),
+		MetaData:        copyStringMap(s.state.MetaData),
+	}, nil
+}
+
+// conversation returns the ID of the conversation of the session, creating it first if needed.
+func (s *ChatSession) conversation(ctx context.Context, opts []CallOption) (string, error) {
+	s.create.Lock()
+	defer s.create.Unlock()
+	if conversationID := s.ConversationID(); conversationID != "" {
+		return conversationID, nil
+	}
+	resp, err := s.conversations.Create(ctx, &CreateConversationsReq{BotID: s.state.BotID}, opts...)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.ConversationID, s.state.SectionID = resp.ID, resp.LastSectionID
+	return resp.ID, nil
+}
+
+// record adds the messages to the transcript, and follows the section of the conversation.
+func (s *ChatSession) record(messages ...*Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, message := range messages {
+		if message.SectionID != "" {
+			s.state.SectionID = message.SectionID
+		}
+		if s.state.MaxTranscript >= 0 {
+			s.state.Transcript = append(s.state.Transcript, copyMessage(message))
+		}
+	}
+	if limit := s.state.MaxTranscript; limit > 0 && len(s.state.Transcript) > limit {
+		// copied so that the dropped messages are released
+		s.state.Transcript = append([]*Message{}, s.state.Transcript[len(s.state.Transcript)-limit:]...)
+	}
+}
+
+// sessionStream adds the messages completed by a chat stream to the transcript of its session.
+type sessionStream struct {
+	Stream[ChatEvent]
+	session *ChatSession
+}
+
+func (s *sessionStream) Recv() (*ChatEvent, error) {
+	event, err := s.Stream.Recv()
+	if err == nil && event.Event == ChatEventConversationMessageCompleted && event.Message != nil {
+		s.session.record(event.Message)
+	}
+	return event, err
+}
+
+func copyMessages(messages []*Message) []*Message {
+	if messages == nil {
+		return nil
+	}
+	res := make([]*Message, 0, len(messages))
+	for _, message := range messages {
+		res = append(res, copyMessage(message))
+	}
+	return res
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
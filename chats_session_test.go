@@ -0,0 +1,179 @@
+package coze
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSessionTestAPI returns a client answering the chats with "pong", and the requests it
+// received by path.
+func newSessionTestAPI(t *testing.T) (CozeAPI, map[string][]map[string]interface{}) {
+	requests := map[string][]map[string]interface{}{}
+	answer := &Message{ID: "msg1", ConversationID: "conv1", Role: MessageRoleAssistant, Type: MessageTypeAnswer, Content: "pong", SectionID: "sec1"}
+	transport := &mockTransport{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+		body := map[string]interface{}{}
+		if req.Body != nil {
+			_ = json.NewDecoder(req.Body).Decode(&body)
+		}
+		requests[req.URL.Path] = append(requests[req.URL.Path], body)
+		switch req.URL.Path {
+		case "/v1/conversation/create":
+			return mockResponse(http.StatusOK, &createConversationsResp{Conversation: &CreateConversationsResp{
+				Conversation: Conversation{ID: "conv1", LastSectionID: "sec1"},
+			}})
+		case "/v1/conversations/conv1/clear":
+			return mockResponse(http.StatusOK, &clearConversationsResp{Data: &ClearConversationsResp{SectionID: "sec2", ConversationID: "conv1"}})
+		case "/v3/chat":
+			assert.Equal(t, "conv1", req.URL.Query().Get("conversation_id"))
+			if body["stream"] == true {
+				data, _ := json.Marshal(answer)
+				return mockStreamResponse("event: conversation.message.delta\ndata: " + string(data) + "\n\n" +
+					"event: conversation.message.completed\ndata: " + string(data) + "\n\n" +
+					"event: done\ndata: [DONE]\n\n")
+			}
+			return mockResponse(http.StatusOK, &createChatsResp{Chat: &CreateChatsResp{Chat: Chat{ID: "chat1", ConversationID: "conv1"}}})
+		case "/v3/chat/retrieve":
+			return mockResponse(http.StatusOK, &retrieveChatsResp{Chat: &RetrieveChatsResp{Chat: Chat{
+				ID: "chat1", ConversationID: "conv1", Status: ChatStatusCompleted,
+			}}})
+		case "/v3/chat/message/list":
+			return mockResponse(http.StatusOK, &listChatsMessagesResp{ListChatsMessagesResp: &ListChatsMessagesResp{Messages: []*Message{answer}}})
+		}
+		t.Fatalf("unexpected request %s", req.URL.Path)
+		return nil, nil
+	}}
+	return NewCozeAPI(NewTokenAuth("token"), WithTransport(transport)), requests
+}
+
+func TestChatSession(t *testing.T) {
+	ctx := context.Background()
+	fast := &ChatPollOptions{PollOptions: PollOptions{InitialInterval: time.Millisecond}}
+
+	t.Run("send", func(t *testing.T) {
+		api, requests := newSessionTestAPI(t)
+		session := NewChatSession(api, NewChatSessionParam{
+			BotID: "bot1", UserID: "user1",
+			CustomVariables: map[string]string{"name": "Ada"},
+			MetaData:        map[string]string{"source": "test"},
+		})
+		session.SetPollOptions(fast)
+		assert.Empty(t, session.ConversationID())
+
+		poll, err := session.Send(ctx, "ping")
+		require.NoError(t, err)
+		assert.Equal(t, "pong", poll.Messages[0].Content)
+		assert.Equal(t, "conv1", session.ConversationID())
+		assert.Equal(t, "sec1", session.SectionID())
+
+		session.SetCustomVariable("city", "Paris")
+		stream, err := session.SendStream(ctx, "ping again")
+		require.NoError(t, err)
+		_, err = NewChatStreamAccumulator().Consume(ctx, stream)
+		require.NoError(t, err)
+
+		// the conversation is created once, and the defaults are sent with every chat
+		assert.Len(t, requests["/v1/conversation/create"], 1)
+		assert.Equal(t, "bot1", requests["/v1/conversation/create"][0]["bot_id"])
+		chats := requests["/v3/chat"]
+		require.Len(t, chats, 2)
+		assert.Equal(t, map[string]interface{}{"name": "Ada"}, chats[0]["custom_variables"])
+		assert.Equal(t, map[string]interface{}{"name": "Ada", "city": "Paris"}, chats[1]["custom_variables"])
+		assert.Equal(t, map[string]interface{}{"source": "test"}, chats[1]["meta_data"])
+
+		var contents []string
+		for _, message := range session.Transcript() {
+			contents = append(contents, string(message.Role)+": "+message.Content)
+		}
+		assert.Equal(t, []string{"user: ping", "assistant: pong", "user: ping again", "assistant: pong"}, contents)
+
+		require.NoError(t, session.Reset(ctx))
+		assert.Equal(t, "sec2", session.SectionID())
+		assert.Empty(t, session.Transcript())
+		assert.Len(t, requests["/v1/conversations/conv1/clear"], 1)
+	})
+
+	t.Run("state", func(t *testing.T) {
+		api, requests := newSessionTestAPI(t)
+		session := NewChatSession(api, NewChatSessionParam{BotID: "bot1", UserID: "user1", MetaData: map[string]string{"source": "test"}})
+		session.SetPollOptions(fast)
+		_, err := session.Send(ctx, "ping")
+		require.NoError(t, err)
+
+		data, err := session.MarshalState()
+		require.NoError(t, err)
+		restored, err := RestoreChatSession(api, data)
+		require.NoError(t, err)
+		restored.SetPollOptions(fast)
+		assert.Equal(t, session.State(), restored.State())
+
+		_, err = restored.Send(ctx, "ping again")
+		require.NoError(t, err)
+		assert.Len(t, requests["/v1/conversation/create"], 1)
+		assert.Len(t, restored.Transcript(), 4)
+		assert.Len(t, session.Transcript(), 2)
+
+		_, err = RestoreChatSession(api, []byte(`{"bot_id":"bot1"}`))
+		assert.Error(t, err)
+		_, err = RestoreChatSession(api, []byte(`{`))
+		assert.Error(t, err)
+	})
+
+	t.Run("max transcript", func(t *testing.T) {
+		api, _ := newSessionTestAPI(t)
+		session := NewChatSession(api, NewChatSessionParam{BotID: "bot1", UserID: "user1", MaxTranscript: 3})
+		session.SetPollOptions(fast)
+		for _, content := range []string{"one", "two"} {
+			_, err := session.Send(ctx, content)
+			require.NoError(t, err)
+		}
+		var contents []string
+		for _, message := range session.Transcript() {
+			contents = append(contents, string(message.Role)+": "+message.Content)
+		}
+		assert.Equal(t, []string{"assistant: pong", "user: two", "assistant: pong"}, contents)
+
+		// the limit is saved with the state
+		data, err := session.MarshalState()
+		require.NoError(t, err)
+		restored, err := RestoreChatSession(api, data)
+		require.NoError(t, err)
+		restored.SetPollOptions(fast)
+		_, err = restored.Send(ctx, "three")
+		require.NoError(t, err)
+		assert.Len(t, restored.Transcript(), 3)
+		assert.Equal(t, "three", restored.Transcript()[1].Content)
+	})
+
+	t.Run("no transcript", func(t *testing.T) {
+		api, _ := newSessionTestAPI(t)
+		session := NewChatSession(api, NewChatSessionParam{BotID: "bot1", UserID: "user1", MaxTranscript: -1})
+		session.SetPollOptions(fast)
+		_, err := session.Send(ctx, "ping")
+		require.NoError(t, err)
+		assert.Empty(t, session.Transcript())
+		assert.Equal(t, "sec1", session.SectionID())
+
+		data, err := session.MarshalState()
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), `"transcript"`)
+		assert.Contains(t, string(data), `"conversation_id":"conv1"`)
+	})
+
+	t.Run("existing conversation", func(t *testing.T) {
+		api, requests := newSessionTestAPI(t)
+		session := NewChatSession(api, NewChatSessionParam{BotID: "bot1", UserID: "user1", ConversationID: "conv1"})
+		session.SetPollOptions(fast)
+		require.NoError(t, session.Reset(ctx))
+		_, err := session.Send(ctx, "ping")
+		require.NoError(t, err)
+		assert.Empty(t, requests["/v1/conversation/create"])
+		assert.True(t, strings.HasPrefix(session.SectionID(), "sec"))
+	})
+}
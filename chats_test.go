@@ -2,10 +2,12 @@ package coze
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -128,12 +130,11 @@ func TestChats(t *testing.T) {
 			core := newCore(&http.Client{Transport: mockTransport}, ComBaseURL)
 			chats := newChats(core)
 
-			timeout := 5
 			resp, err := chats.CreateAndPoll(context.Background(), &CreateChatsReq{
 				ConversationID: "test_conversation_id",
 				BotID:          "bot1",
 				UserID:         "user1",
-			}, &timeout)
+			}, &ChatPollOptions{PollOptions: PollOptions{InitialInterval: time.Millisecond}})
 
 			require.NoError(t, err)
 			assert.Equal(t, "chat1", resp.Chat.ID)
@@ -141,22 +142,31 @@ func TestChats(t *testing.T) {
 			require.Len(t, resp.Messages, 1)
 			assert.Equal(t, "Hello!", resp.Messages[0].Content)
 		})
-		t.Run("CreateAndPoll success with cancel chat", func(t *testing.T) {
-			core := newCore(&http.Client{Transport: mockTransport}, ComBaseURL)
+		t.Run("CreateAndPoll deadline with cancel chat", func(t *testing.T) {
+			var paths []string
+			inProgress := *mockTransport
+			inProgress.roundTripFunc = func(req *http.Request) (*http.Response, error) {
+				paths = append(paths, req.URL.Path)
+				if req.URL.Path == "/v3/chat/retrieve" {
+					return mockResponse(http.StatusOK, &retrieveChatsResp{Chat: &RetrieveChatsResp{Chat: Chat{
+						ID: "chat1", ConversationID: "test_conversation_id", Status: ChatStatusInProgress,
+					}}})
+				}
+				return mockTransport.roundTripFunc(req)
+			}
+			core := newCore(&http.Client{Transport: &inProgress}, ComBaseURL)
 			chats := newChats(core)
 
-			timeout := 0
-			resp, err := chats.CreateAndPoll(context.Background(), &CreateChatsReq{
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := chats.CreateAndPoll(ctx, &CreateChatsReq{
 				ConversationID: "test_conversation_id",
 				BotID:          "bot1",
 				UserID:         "user1",
-			}, &timeout)
+			}, &ChatPollOptions{PollOptions: PollOptions{InitialInterval: 10 * time.Millisecond}})
 
-			require.NoError(t, err)
-			assert.Equal(t, "chat1", resp.Chat.ID)
-			assert.Equal(t, ChatStatusCancelled, resp.Chat.Status)
-			require.Len(t, resp.Messages, 1)
-			assert.Equal(t, "Hello!", resp.Messages[0].Content)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Equal(t, "/v3/chat/cancel", paths[len(paths)-1])
 		})
 	})
 
@@ -385,3 +395,113 @@ data:
 		assert.Equal(t, ChatEventDone, event.Event)
 	})
 }
+
+func TestCancelChatOnContextDone(t *testing.T) {
+	// newCancelTestCore returns a core serving the chat stream or the created chat, and sending the
+	// cancel requests on the channel.
+	newCancelTestCore := func(stream func() *http.Response, canceled chan<- *CancelChatsReq) *core {
+		return newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				switch req.URL.Path {
+				case "/v3/chat", "/v3/chat/submit_tool_outputs":
+					if stream != nil {
+						return stream(), nil
+					}
+					return mockResponse(http.StatusOK, &createChatsResp{Chat: &CreateChatsResp{Chat: Chat{ID: "chat1", ConversationID: "conv1"}}})
+				case "/v3/chat/cancel":
+					if err := req.Context().Err(); err != nil {
+						return nil, err
+					}
+					cancelReq := &CancelChatsReq{}
+					if err := json.NewDecoder(req.Body).Decode(cancelReq); err != nil {
+						return nil, err
+					}
+					canceled <- cancelReq
+					return mockResponse(http.StatusOK, &cancelChatsResp{Chat: &CancelChatsResp{}})
+				}
+				return nil, req.Context().Err()
+			},
+		}}, ComBaseURL)
+	}
+	stalledStream := func(events string) func() *http.Response {
+		return func() *http.Response {
+			body, writer := io.Pipe()
+			go func() {
+				_, _ = writer.Write([]byte(events))
+			}()
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body}
+		}
+	}
+	const createdEvent = "event: conversation.chat.created\ndata: {\"id\":\"chat1\",\"conversation_id\":\"conv1\"}\n\n"
+
+	t.Run("stream", func(t *testing.T) {
+		canceled := make(chan *CancelChatsReq, 1)
+		metrics := NewInMemoryMetrics()
+		core := newCancelTestCore(stalledStream(createdEvent), canceled)
+		core.metrics = metrics
+		ctx, cancel := context.WithCancel(context.Background())
+
+		stream, err := newChats(core).Stream(ctx, &CreateChatsReq{BotID: "bot1"}, WithCallCancelChatOnContextDone())
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		cancel()
+
+		select {
+		case req := <-canceled:
+			assert.Equal(t, &CancelChatsReq{ConversationID: "conv1", ChatID: "chat1"}, req)
+		case <-time.After(time.Second):
+			t.Fatal("the chat was not canceled")
+		}
+		_ = stream.Close()
+		assert.Eventually(t, func() bool {
+			return metrics.Counter(MetricRequestsTotal, "operation", "chat.cancel", "outcome", OutcomeSuccess) == 1
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("completed stream is not canceled", func(t *testing.T) {
+		canceled := make(chan *CancelChatsReq, 1)
+		core := newCancelTestCore(func() *http.Response {
+			resp, _ := mockStreamResponse(createdEvent + "event: done\ndata: [DONE]\n\n")
+			return resp
+		}, canceled)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		stream, err := newChats(core).StreamSubmitToolOutputs(ctx, &SubmitToolOutputsChatReq{ConversationID: "conv1", ChatID: "chat1"},
+			WithCallCancelChatOnContextDone())
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			_, err = stream.Recv()
+			require.NoError(t, err)
+		}
+		cancel()
+		require.NoError(t, stream.Close())
+
+		select {
+		case <-canceled:
+			t.Fatal("the completed chat was canceled")
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("create and poll", func(t *testing.T) {
+		canceled := make(chan *CancelChatsReq, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		core := newCancelTestCore(func() *http.Response {
+			// the context is done once the chat is created
+			cancel()
+			resp, _ := mockResponse(http.StatusOK, &createChatsResp{Chat: &CreateChatsResp{Chat: Chat{ID: "chat1", ConversationID: "conv1"}}})
+			return resp
+		}, canceled)
+
+		// CreateAndPoll cancels its chat without the option
+		_, err := newChats(core).CreateAndPoll(ctx, &CreateChatsReq{BotID: "bot1"}, nil)
+		require.ErrorIs(t, err, context.Canceled)
+		select {
+		case req := <-canceled:
+			assert.Equal(t, &CancelChatsReq{ConversationID: "conv1", ChatID: "chat1"}, req)
+		default:
+			t.Fatal("the chat was not canceled")
+		}
+	})
+}
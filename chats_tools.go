@@ -0,0 +1,359 @@
+package coze
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ToolFunc is a tool answering the calls of a bot, see ToolRegistry. It receives the raw JSON
+// arguments of the call, and returns the output submitted to the bot. It must return once the
+// context is done: the SDK waits for the tools to return, it does not leave them running.
+type ToolFunc func(ctx context.Context, arguments string) (string, error)
+
+// ToolOption configures a tool of a ToolRegistry.
+type ToolOption func(t *registeredTool)
+
+// WithToolTimeout bounds the execution of the tool, overriding ToolRunOptions.ToolTimeout.
+func WithToolTimeout(timeout time.Duration) ToolOption {
+	return func(t *registeredTool) {
+		t.timeout = timeout
+	}
+}
+
+type registeredTool struct {
+	fn      ToolFunc
+	timeout time.Duration
+}
+
+// ToolRegistry holds the Go functions answering the tool calls of the bots, by function name, for
+// ChatAPI.RunWithTools and ChatAPI.StreamWithTools. It is safe for concurrent use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]*registeredTool
+}
+
+// NewToolRegistry returns an empty tool registry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: map[string]*registeredTool{}}
+}
+
+// Register registers the tool answering the calls of the function name, replacing the previous
+// one, if any.
+func (r *ToolRegistry) Register(name string, fn ToolFunc, opts ...ToolOption) {
+	tool := &registeredTool{fn: fn}
+	for _, opt := range opts {
+		opt(tool)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[name] = tool
+}
+
+// RegisterTool registers fn as the tool answering the calls of the function name. The JSON
+// arguments of the calls are decoded into A, and the result is submitted as is if it is a string,
+// or encoded to JSON.
+func RegisterTool[A any, R any](r *ToolRegistry, name string, fn func(ctx context.Context, args A) (R, error), opts ...ToolOption) {
+	r.Register(name, func(ctx context.Context, arguments string) (string, error) {
+		var args A
+		if arguments != "" {
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", fmt.Errorf("invalid arguments: %w", err)
+			}
+		}
+		res, err := fn(ctx, args)
+		if err != nil {
+			return "", err
+		}
+		if s, ok := any(res).(string); ok {
+			return s, nil
+		}
+		output, err := json.Marshal(res)
+		if err != nil {
+			return "", fmt.Errorf("invalid result: %w", err)
+		}
+		return string(output), nil
+	}, opts...)
+}
+
+// Names returns the names of the registered tools, sorted.
+func (r *ToolRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *ToolRegistry) tool(name string) *registeredTool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tools[name]
+}
+
+// ErrToolRoundsExceeded is returned once a chat required the outputs of the tools more times than
+// ToolRunOptions.MaxRounds. The chat is canceled.
+var ErrToolRoundsExceeded = errors.New("coze: too many tool rounds")
+
+// ToolRunOptions configures ChatAPI.RunWithTools and ChatAPI.StreamWithTools.
+type ToolRunOptions struct {
+	// MaxRounds is the number of times the outputs of the tools are submitted, 10 by default.
+	MaxRounds int
+
+	// ToolTimeout bounds the execution of each tool, unless set by WithToolTimeout. Zero does not
+	// bound it. The context of the tool is done once it times out, and the tool is reported as
+	// failed with the timeout once it returned, whatever its result.
+	ToolTimeout time.Duration
+
+	// PollOptions are the intervals between two retrievals of the chat by RunWithTools,
+	// DefaultPollOptions by default.
+	PollOptions
+
+	// FormatError returns the output reporting the failure of a tool to the bot, by default
+	// {"error": "<message>"}. The failures are the errors and panics of the tools, their timeouts,
+	// the invalid arguments and the unknown tools.
+	FormatError func(call *ChatToolCall, err error) string
+
+	// OnToolCall is called with each tool call and its output, before they are submitted.
+	OnToolCall func(call *ChatToolCall, output *ToolOutput, err error)
+}
+
+const defaultToolMaxRounds = 10
+
+func (o *ToolRunOptions) withDefaults() *ToolRunOptions {
+	res := ToolRunOptions{}
+	if o != nil {
+		res = *o
+	}
+	if res.MaxRounds <= 0 {
+		res.MaxRounds = defaultToolMaxRounds
+	}
+	if res.FormatError == nil {
+		res.FormatError = formatToolError
+	}
+	return &res
+}
+
+func formatToolError(_ *ChatToolCall, err error) string {
+	output, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(output)
+}
+
+// runTools runs the tool calls concurrently, and returns their outputs in the order of the calls.
+// The failures of the tools are reported as outputs, only the end of the context fails.
+func (r *ToolRegistry) runTools(ctx context.Context, calls []*ChatToolCall, opts *ToolRunOptions) ([]*ToolOutput, error) {
+	outputs := make([]*ToolOutput, len(calls))
+	errs := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call *ChatToolCall) {
+			defer wg.Done()
+			output, err := r.runTool(ctx, call, opts)
+			if err != nil {
+				output = opts.FormatError(call, err)
+			}
+			outputs[i], errs[i] = &ToolOutput{ToolCallID: call.ID, Output: output}, err
+		}(i, call)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.OnToolCall != nil {
+		for i, call := range calls {
+			opts.OnToolCall(call, outputs[i], errs[i])
+		}
+	}
+	return outputs, nil
+}
+
+// runTool runs the tool of the call, recovering its panic, and reports its timeout.
+func (r *ToolRegistry) runTool(ctx context.Context, call *ChatToolCall, opts *ToolRunOptions) (string, error) {
+	if call.Function == nil {
+		return "", fmt.Errorf("tool call %s has no function", call.ID)
+	}
+	tool := r.tool(call.Function.Name)
+	if tool == nil {
+		return "", fmt.Errorf("tool %s is not registered", call.Function.Name)
+	}
+	timeout := opts.ToolTimeout
+	if tool.timeout > 0 {
+		timeout = tool.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	output, err := tool.call(ctx, call.Function.Name, call.Function.Arguments)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", fmt.Errorf("tool %s: %w", call.Function.Name, ctxErr)
+	}
+	return output, err
+}
+
+// call runs the tool, turning its panic into an error.
+func (t *registeredTool) call(ctx context.Context, name, arguments string) (output string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			output, err = "", fmt.Errorf("tool %s panicked: %v", name, p)
+		}
+	}()
+	return t.fn(ctx, arguments)
+}
+
+// toolCalls returns the tool calls required by the chat, if any.
+func toolCalls(chat *Chat) []*ChatToolCall {
+	if chat == nil || chat.Status != ChatStatusRequiresAction || chat.RequiredAction == nil || chat.RequiredAction.SubmitToolOutputs == nil {
+		return nil
+	}
+	return chat.RequiredAction.SubmitToolOutputs.ToolCalls
+}
+
+// cancelToolChat cancels a chat exceeding its tool rounds, even if the context is done.
+func cancelToolChat(ctx context.Context, chats ChatService, chat *Chat, maxRounds int) error {
+	ctx, cancel := context.WithTimeout(detachContext(ctx), cancelChatTimeout)
+	defer cancel()
+	err := fmt.Errorf("%w: chat %s still requires action after %d rounds", ErrToolRoundsExceeded, chat.ID, maxRounds)
+	if _, cancelErr := chats.Cancel(ctx, &CancelChatsReq{ConversationID: chat.ConversationID, ChatID: chat.ID}); cancelErr != nil {
+		return fmt.Errorf("%w, cancel failed: %v", err, cancelErr)
+	}
+	return err
+}
+
+// RunWithTools creates the chat, and answers its tool calls with the tools of the registry until
+// it ends, see ToolRunOptions. It returns the chat and its messages like CreateAndPoll, the chat
+// being completed or canceled, and a *ChatFailedError once it failed.
+func (c *ChatAPI) RunWithTools(ctx context.Context, req *CreateChatsReq, tools *ToolRegistry, opts *ToolRunOptions, callOpts ...CallOption) (*ChatPoll, error) {
+	opts = opts.withDefaults()
+	created, err := c.Create(ctx, req, callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	rounds := 0
+	return pollChat(ctx, c, c.Messages, created.Chat, &ChatPollOptions{
+		PollOptions: opts.PollOptions,
+		OnRequiresAction: func(ctx context.Context, chat *Chat) ([]*ToolOutput, error) {
+			if rounds == opts.MaxRounds {
+				return nil, cancelToolChat(ctx, c, chat, opts.MaxRounds)
+			}
+			rounds++
+			return tools.runTools(ctx, toolCalls(chat), opts)
+		},
+	}, callOpts...)
+}
+
+// StreamWithTools streams the chat, and answers its tool calls with the tools of the registry
+// until it ends, see ToolRunOptions. The stream returns the events of every round in order, the
+// requires action events included, and a single done event at the end.
+func (c *ChatAPI) StreamWithTools(ctx context.Context, req *CreateChatsReq, tools *ToolRegistry, opts *ToolRunOptions, callOpts ...CallOption) (Stream[ChatEvent], error) {
+	stream, err := c.Stream(ctx, req, callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &toolChatStream{
+		ctx:      ctx,
+		chats:    c,
+		tools:    tools,
+		opts:     opts.withDefaults(),
+		callOpts: callOpts,
+		current:  stream,
+	}, nil
+}
+
+// toolChatStream chains the streams of the rounds of a chat answering its tool calls.
+type toolChatStream struct {
+	ctx      context.Context
+	chats    ChatService
+	tools    *ToolRegistry
+	opts     *ToolRunOptions
+	callOpts []CallOption
+
+	mu      sync.Mutex
+	current Stream[ChatEvent]
+	closed  bool
+	rounds  int
+	// action is the chat of the requires action event of the current round.
+	action *Chat
+}
+
+func (s *toolChatStream) Recv() (*ChatEvent, error) {
+	for {
+		event, err := s.stream().Recv()
+		if err != nil && !(errors.Is(err, io.EOF) && s.action != nil) {
+			return nil, err
+		}
+		if err == nil && event.Event == ChatEventConversationChatRequiresAction && event.Chat != nil {
+			s.action = event.Chat
+		}
+		if err == nil && (event.Event != ChatEventDone || s.action == nil) {
+			return event, nil
+		}
+		// the round ended requiring action, its done event is skipped
+		if err := s.submit(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// submit runs the tools of the requires action event, and continues with the stream of their
+// outputs.
+func (s *toolChatStream) submit() error {
+	chat := s.action
+	s.action = nil
+	_ = s.stream().Close()
+	if s.rounds == s.opts.MaxRounds {
+		return cancelToolChat(s.ctx, s.chats, chat, s.opts.MaxRounds)
+	}
+	s.rounds++
+	outputs, err := s.tools.runTools(s.ctx, toolCalls(chat), s.opts)
+	if err != nil {
+		return err
+	}
+	stream, err := s.chats.StreamSubmitToolOutputs(s.ctx, &SubmitToolOutputsChatReq{
+		ConversationID: chat.ConversationID,
+		ChatID:         chat.ID,
+		ToolOutputs:    outputs,
+	}, s.callOpts...)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		_ = stream.Close()
+		return io.EOF
+	}
+	s.current = stream
+	return nil
+}
+
+func (s *toolChatStream) stream() Stream[ChatEvent] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// Close closes the stream of the current round, and stops the next ones.
+func (s *toolChatStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.current.Close()
+}
+
+// Response returns the response of the stream of the current round.
+func (s *toolChatStream) Response() HTTPResponse {
+	return s.stream().Response()
+}
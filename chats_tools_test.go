@@ -0,0 +1,252 @@
+package coze
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeToolChats answers the chats requiring tool outputs: Retrieve returns the chats in order,
+// and Stream and StreamSubmitToolOutputs the streams in order.
+type fakeToolChats struct {
+	ChatService
+
+	chats    []Chat
+	streams  []string
+	outputs  [][]*ToolOutput
+	canceled []string
+}
+
+func (f *fakeToolChats) Create(ctx context.Context, req *CreateChatsReq, opts ...CallOption) (*CreateChatsResp, error) {
+	return &CreateChatsResp{Chat: Chat{ID: "chat_1", ConversationID: "conv_1", Status: ChatStatusCreated}}, nil
+}
+
+func (f *fakeToolChats) Retrieve(ctx context.Context, req *RetrieveChatsReq, opts ...CallOption) (*RetrieveChatsResp, error) {
+	chat := f.chats[0]
+	f.chats = f.chats[1:]
+	return &RetrieveChatsResp{Chat: chat}, nil
+}
+
+func (f *fakeToolChats) SubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq, opts ...CallOption) (*SubmitToolOutputsChatResp, error) {
+	f.outputs = append(f.outputs, req.ToolOutputs)
+	return &SubmitToolOutputsChatResp{}, nil
+}
+
+func (f *fakeToolChats) Cancel(ctx context.Context, req *CancelChatsReq, opts ...CallOption) (*CancelChatsResp, error) {
+	f.canceled = append(f.canceled, req.ChatID)
+	return &CancelChatsResp{}, nil
+}
+
+func (f *fakeToolChats) Stream(ctx context.Context, req *CreateChatsReq, opts ...CallOption) (Stream[ChatEvent], error) {
+	return f.nextStream(), nil
+}
+
+func (f *fakeToolChats) StreamSubmitToolOutputs(ctx context.Context, req *SubmitToolOutputsChatReq, opts ...CallOption) (Stream[ChatEvent], error) {
+	f.outputs = append(f.outputs, req.ToolOutputs)
+	return f.nextStream(), nil
+}
+
+func (f *fakeToolChats) nextStream() Stream[ChatEvent] {
+	body := f.streams[0]
+	f.streams = f.streams[1:]
+	stream, _ := newTestChatStream(strings.NewReader(body))
+	return stream
+}
+
+type fakeChatMessages struct{}
+
+func (fakeChatMessages) List(ctx context.Context, req *ListChatsMessagesReq, opts ...CallOption) (*ListChatsMessagesResp, error) {
+	return &ListChatsMessagesResp{Messages: []*Message{{Content: "sunny"}}}, nil
+}
+
+func requiresActionChat(calls ...*ChatToolCall) Chat {
+	return Chat{
+		ID: "chat_1", ConversationID: "conv_1", Status: ChatStatusRequiresAction,
+		RequiredAction: &ChatRequiredAction{Type: "submit_tool_outputs", SubmitToolOutputs: &ChatSubmitToolOutputs{ToolCalls: calls}},
+	}
+}
+
+func toolCall(id, name, arguments string) *ChatToolCall {
+	return &ChatToolCall{ID: id, Type: "function", Function: &ChatToolCallFunction{Name: name, Arguments: arguments}}
+}
+
+func chatEventData(t *testing.T, event ChatEventType, chat Chat) string {
+	data, err := json.Marshal(chat)
+	require.NoError(t, err)
+	return fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)
+}
+
+type weatherArgs struct {
+	City string `json:"city"`
+}
+
+func newWeatherTools() *ToolRegistry {
+	tools := NewToolRegistry()
+	RegisterTool(tools, "get_weather", func(ctx context.Context, args weatherArgs) (string, error) {
+		if args.City == "" {
+			return "", errors.New("city is required")
+		}
+		return "sunny in " + args.City, nil
+	})
+	return tools
+}
+
+func TestToolRegistry(t *testing.T) {
+	ctx := context.Background()
+	opts := (&ToolRunOptions{ToolTimeout: 50 * time.Millisecond}).withDefaults()
+
+	tools := newWeatherTools()
+	RegisterTool(tools, "get_time", func(ctx context.Context, args struct{}) (map[string]int, error) {
+		return map[string]int{"hour": 12}, nil
+	})
+	tools.Register("panic", func(ctx context.Context, arguments string) (string, error) {
+		panic("boom")
+	})
+	// slow ignores its context, its result is replaced by the timeout once it returns
+	var slowReturned int32
+	tools.Register("slow", func(ctx context.Context, arguments string) (string, error) {
+		time.Sleep(100 * time.Millisecond)
+		atomic.StoreInt32(&slowReturned, 1)
+		return "late", nil
+	})
+	tools.Register("fast", func(ctx context.Context, arguments string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, WithToolTimeout(time.Millisecond))
+	assert.Equal(t, []string{"fast", "get_time", "get_weather", "panic", "slow"}, tools.Names())
+
+	var mu sync.Mutex
+	var failed []string
+	opts.OnToolCall = func(call *ChatToolCall, output *ToolOutput, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			failed = append(failed, call.ID)
+		}
+	}
+	outputs, err := tools.runTools(ctx, []*ChatToolCall{
+		toolCall("1", "get_weather", `{"city":"Paris"}`),
+		toolCall("2", "get_time", ""),
+		toolCall("3", "get_weather", `{}`),
+		toolCall("4", "get_weather", `{"city":`),
+		toolCall("5", "missing", ""),
+		toolCall("6", "panic", ""),
+		toolCall("7", "slow", ""),
+		toolCall("8", "fast", ""),
+	}, opts)
+	require.NoError(t, err)
+	require.Len(t, outputs, 8)
+	assert.Equal(t, &ToolOutput{ToolCallID: "1", Output: "sunny in Paris"}, outputs[0])
+	assert.Equal(t, `{"hour":12}`, outputs[1].Output)
+	assert.Equal(t, `{"error":"city is required"}`, outputs[2].Output)
+	assert.Contains(t, outputs[3].Output, "invalid arguments")
+	assert.Equal(t, `{"error":"tool missing is not registered"}`, outputs[4].Output)
+	assert.Equal(t, `{"error":"tool panic panicked: boom"}`, outputs[5].Output)
+	assert.Equal(t, `{"error":"tool slow: context deadline exceeded"}`, outputs[6].Output)
+	assert.Equal(t, `{"error":"tool fast: context deadline exceeded"}`, outputs[7].Output)
+	assert.Equal(t, []string{"3", "4", "5", "6", "7", "8"}, failed)
+	// no tool is left running
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slowReturned))
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = tools.runTools(canceled, []*ChatToolCall{toolCall("1", "fast", "")}, opts)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunWithTools(t *testing.T) {
+	ctx := context.Background()
+	opts := &ToolRunOptions{PollOptions: PollOptions{InitialInterval: time.Millisecond}}
+
+	t.Run("poll", func(t *testing.T) {
+		chats := &fakeToolChats{chats: []Chat{
+			{ID: "chat_1", ConversationID: "conv_1", Status: ChatStatusInProgress},
+			requiresActionChat(toolCall("call_1", "get_weather", `{"city":"Paris"}`), toolCall("call_2", "get_weather", `{"city":"Rome"}`)),
+			requiresActionChat(toolCall("call_3", "get_weather", `{}`)),
+			{ID: "chat_1", ConversationID: "conv_1", Status: ChatStatusCompleted},
+		}}
+		api := &ChatAPI{ChatService: chats, Messages: fakeChatMessages{}}
+
+		poll, err := api.RunWithTools(ctx, &CreateChatsReq{BotID: "bot"}, newWeatherTools(), opts)
+		require.NoError(t, err)
+		assert.Equal(t, ChatStatusCompleted, poll.Chat.Status)
+		assert.Equal(t, "sunny", poll.Messages[0].Content)
+		assert.Equal(t, [][]*ToolOutput{
+			{{ToolCallID: "call_1", Output: "sunny in Paris"}, {ToolCallID: "call_2", Output: "sunny in Rome"}},
+			{{ToolCallID: "call_3", Output: `{"error":"city is required"}`}},
+		}, chats.outputs)
+	})
+
+	t.Run("poll max rounds", func(t *testing.T) {
+		chats := &fakeToolChats{chats: []Chat{
+			requiresActionChat(toolCall("call_1", "get_weather", `{"city":"Paris"}`)),
+			requiresActionChat(toolCall("call_2", "get_weather", `{"city":"Paris"}`)),
+		}}
+		api := &ChatAPI{ChatService: chats, Messages: fakeChatMessages{}}
+
+		_, err := api.RunWithTools(ctx, &CreateChatsReq{BotID: "bot"}, newWeatherTools(), &ToolRunOptions{MaxRounds: 1, PollOptions: PollOptions{InitialInterval: time.Millisecond}})
+		assert.ErrorIs(t, err, ErrToolRoundsExceeded)
+		assert.Len(t, chats.outputs, 1)
+		assert.Equal(t, []string{"chat_1"}, chats.canceled)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		chats := &fakeToolChats{streams: []string{
+			chatEventData(t, ChatEventConversationChatCreated, Chat{ID: "chat_1", ConversationID: "conv_1"}) +
+				chatEventData(t, ChatEventConversationChatRequiresAction, requiresActionChat(toolCall("call_1", "get_weather", `{"city":"Paris"}`))) +
+				"event: done\ndata: [DONE]\n\n",
+			chatEventData(t, ChatEventConversationChatRequiresAction, requiresActionChat(toolCall("call_2", "get_weather", `{"city":"Rome"}`))),
+			"event: conversation.message.delta\ndata: {\"id\":\"msg_1\",\"role\":\"assistant\",\"type\":\"answer\",\"content\":\"sunny\"}\n\n" +
+				chatEventData(t, ChatEventConversationChatCompleted, Chat{ID: "chat_1", ConversationID: "conv_1", Status: ChatStatusCompleted}) +
+				"event: done\ndata: [DONE]\n\n",
+		}}
+		api := &ChatAPI{ChatService: chats, Messages: fakeChatMessages{}}
+
+		stream, err := api.StreamWithTools(ctx, &CreateChatsReq{BotID: "bot"}, newWeatherTools(), opts)
+		require.NoError(t, err)
+		var events []ChatEventType
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				break
+			}
+			events = append(events, event.Event)
+		}
+		require.NoError(t, stream.Close())
+		assert.Equal(t, []ChatEventType{
+			ChatEventConversationChatCreated, ChatEventConversationChatRequiresAction, ChatEventConversationChatRequiresAction,
+			ChatEventConversationMessageDelta, ChatEventConversationChatCompleted, ChatEventDone,
+		}, events)
+		assert.Equal(t, [][]*ToolOutput{
+			{{ToolCallID: "call_1", Output: "sunny in Paris"}},
+			{{ToolCallID: "call_2", Output: "sunny in Rome"}},
+		}, chats.outputs)
+	})
+
+	t.Run("stream max rounds", func(t *testing.T) {
+		chats := &fakeToolChats{streams: []string{
+			chatEventData(t, ChatEventConversationChatRequiresAction, requiresActionChat(toolCall("call_1", "get_weather", `{"city":"Paris"}`))),
+			chatEventData(t, ChatEventConversationChatRequiresAction, requiresActionChat(toolCall("call_2", "get_weather", `{"city":"Paris"}`))),
+		}}
+		api := &ChatAPI{ChatService: chats, Messages: fakeChatMessages{}}
+
+		stream, err := api.StreamWithTools(ctx, &CreateChatsReq{BotID: "bot"}, newWeatherTools(), &ToolRunOptions{MaxRounds: 1})
+		require.NoError(t, err)
+		acc := NewChatStreamAccumulator()
+		_, err = acc.Consume(ctx, stream)
+		assert.ErrorIs(t, err, ErrToolRoundsExceeded)
+		assert.Len(t, chats.outputs, 1)
+		assert.Equal(t, []string{"chat_1"}, chats.canceled)
+	})
+}
@@ -1,27 +1,39 @@
 package coze
 
 import (
+	"net"
 	"net/http"
+	"time"
 )
 
+// CozeAPI is the client of the Coze API. Its services are interfaces, which the tests of the
+// callers can replace, such as with the mocks of the cozemock package.
 type CozeAPI struct {
-	Audio         *audio
-	Bots          *bots
-	Chat          *chat
-	Conversations *conversations
-	Workflows     *workflows
-	Workspaces    *workspace
-	Datasets      *datasets
-	Files         *files
-	Templates     *templates
-	Users         *users
+	Audio         *AudioAPI
+	Bots          BotsService
+	Chat          *ChatAPI
+	Conversations *ConversationsAPI
+	Workflows     *WorkflowsAPI
+	Workspaces    WorkspacesService
+	Datasets      *DatasetsAPI
+	Files         FilesService
+	Templates     TemplatesService
+	Users         UsersService
 	baseURL       string
 }
 
 type newCozeAPIOpt struct {
-	baseURL  string
-	client   *http.Client
-	logLevel LogLevel
+	baseURL     string
+	client      *http.Client
+	transport   http.RoundTripper
+	logLevel    LogLevel
+	logger      Logger
+	redaction   *RedactionConfig
+	retryPolicy *RetryPolicy
+	rateLimit   *RateLimitConfig
+	middlewares []Middleware
+	tracer      Tracer
+	metrics     Metrics
 }
 
 type CozeAPIOption func(*newCozeAPIOpt)
@@ -33,23 +45,55 @@ func WithBaseURL(baseURL string) CozeAPIOption {
 	}
 }
 
-// WithHttpClient sets a custom HTTP core
+// WithHttpClient sets a custom HTTP core. The client is not modified, the SDK sends the requests
+// through a copy of it, so that it can be shared with other clients.
 func WithHttpClient(client *http.Client) CozeAPIOption {
 	return func(opt *newCozeAPIOpt) {
 		opt.client = client
 	}
 }
 
-// WithLogLevel sets the logging level
+// WithTransport sets the transport sending the requests, it replaces the transport of the client
+// set by WithHttpClient. The default one is shared by all the clients, see NewDefaultTransport.
+func WithTransport(transport http.RoundTripper) CozeAPIOption {
+	return func(opt *newCozeAPIOpt) {
+		opt.transport = transport
+	}
+}
+
+// WithLogLevel sets the logging level of the client
 func WithLogLevel(level LogLevel) CozeAPIOption {
 	return func(opt *newCozeAPIOpt) {
 		opt.logLevel = level
 	}
 }
 
+// WithLogRedaction configures the masking of secrets and personal data in the logs of the client,
+// see RedactionConfig for the fields masked by default
+func WithLogRedaction(config *RedactionConfig) CozeAPIOption {
+	return func(opt *newCozeAPIOpt) {
+		opt.redaction = config
+	}
+}
+
+// WithRetryPolicy enables automatic retry of transient failures, see DefaultRetryPolicy
+func WithRetryPolicy(policy *RetryPolicy) CozeAPIOption {
+	return func(opt *newCozeAPIOpt) {
+		opt.retryPolicy = policy
+	}
+}
+
+// WithRateLimit bounds the request rate and the number of in-flight requests of the client
+func WithRateLimit(config *RateLimitConfig) CozeAPIOption {
+	return func(opt *newCozeAPIOpt) {
+		opt.rateLimit = config
+	}
+}
+
+// WithLogger sets the logger of the client, the default one writes to stderr
 func WithLogger(logger Logger) CozeAPIOption {
 	return func(opt *newCozeAPIOpt) {
-		setLogger(logger)
+		opt.logger = logger
 	}
 }
 
@@ -61,28 +105,41 @@ func NewCozeAPI(auth Auth, opts ...CozeAPIOption) CozeAPI {
 	for _, option := range opts {
 		option(opt)
 	}
-	if opt.client == nil {
-		opt.client = &http.Client{}
+	// the client of the caller may be shared, the auth transport is set on a copy of it
+	var client http.Client
+	if opt.client != nil {
+		client = *opt.client
+	} else {
+		client = *defaultHTTPClient
+	}
+	next := client.Transport
+	if opt.transport != nil {
+		next = opt.transport
 	}
-	saveTransport := opt.client.Transport
-	if saveTransport == nil {
-		saveTransport = http.DefaultTransport
+	if next == nil {
+		next = http.DefaultTransport
 	}
-	opt.client.Transport = &authTransport{
-		auth: auth,
-		next: saveTransport,
+	clientLogger := newClientLogger(opt.logger, opt.logLevel, opt.redaction)
+	client.Transport = &authTransport{
+		auth:   auth,
+		next:   next,
+		logger: clientLogger,
 	}
-	core := newCore(opt.client, opt.baseURL)
-	setLevel(opt.logLevel)
-	// Set log level
+	core := newCore(&client, opt.baseURL)
+	core.logger = clientLogger
+	core.retryPolicy = opt.retryPolicy
+	core.rateLimiter = newRateLimiter(opt.rateLimit)
+	core.middlewares = opt.middlewares
+	core.tracer = opt.tracer
+	core.metrics = opt.metrics
 	cozeClient := CozeAPI{
 		Audio:         newAudio(core),
 		Bots:          newBots(core),
-		Chat:          newChats(core),
-		Conversations: newConversations(core),
+		Chat:          newChatAPI(core),
+		Conversations: newConversationsAPI(core),
 		Workflows:     newWorkflows(core),
 		Workspaces:    newWorkspace(core),
-		Datasets:      newDatasets(core),
+		Datasets:      newDatasetsAPI(core),
 		Files:         newFiles(core),
 		Templates:     newTemplates(core),
 		Users:         newUsers(core),
@@ -92,8 +149,9 @@ func NewCozeAPI(auth Auth, opts ...CozeAPIOption) CozeAPI {
 }
 
 type authTransport struct {
-	auth Auth
-	next http.RoundTripper
+	auth   Auth
+	next   http.RoundTripper
+	logger *levelLogger
 }
 
 func (h *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -102,10 +160,41 @@ func (h *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	}
 	accessToken, err := h.auth.Token(req.Context())
 	if err != nil {
-		logger.Errorf(req.Context(), "Failed to get access token: %v", err)
+		h.logger.Errorf(req.Context(), "Failed to get access token: %v", err)
 		return nil, err
 	}
+	// a RoundTripper must not modify the request of the caller
+	req = req.Clone(req.Context())
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
 	return h.next.RoundTrip(req)
 }
+
+// NewDefaultTransport returns the transport used by the clients created without WithHttpClient
+// nor WithTransport: proxy from the environment, HTTP/2, bounded dial and TLS handshake, and a
+// connection pool sized for a single API host.
+func NewDefaultTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// NewDefaultHTTPClient returns a client using NewDefaultTransport. It has no overall timeout,
+// since streams can last minutes, use WithCallTimeout to bound a call.
+func NewDefaultHTTPClient() *http.Client {
+	return &http.Client{Transport: NewDefaultTransport()}
+}
+
+// defaultHTTPClient is shared by the clients created without WithHttpClient, so that they share
+// the connection pool.
+var defaultHTTPClient = NewDefaultHTTPClient()
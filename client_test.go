@@ -3,7 +3,9 @@ package coze
 import (
 	"context"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -94,6 +96,86 @@ func TestNewCozeAPI(t *testing.T) {
 	})
 }
 
+func TestNewCozeAPIHTTPClient(t *testing.T) {
+	t.Run("clients sharing an http client keep their own auth", func(t *testing.T) {
+		var mu sync.Mutex
+		authorizations := map[string]string{}
+		transport := &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				authorizations[req.Header.Get("X-Client")] = req.Header.Get("Authorization")
+				mu.Unlock()
+				return mockResponse(http.StatusOK, &meResp{User: &User{UserID: "user1"}})
+			},
+		}
+		shared := &http.Client{Transport: transport, Timeout: time.Minute}
+		api1 := NewCozeAPI(NewTokenAuth("token1"), WithHttpClient(shared))
+		api2 := NewCozeAPI(NewTokenAuth("token2"), WithHttpClient(shared))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				_, err := api1.Users.Me(context.Background(), WithCallHeader("X-Client", "1"))
+				assert.NoError(t, err)
+			}()
+			go func() {
+				defer wg.Done()
+				_, err := api2.Users.Me(context.Background(), WithCallHeader("X-Client", "2"))
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, "Bearer token1", authorizations["1"])
+		assert.Equal(t, "Bearer token2", authorizations["2"])
+		assert.Same(t, transport, shared.Transport)
+		assert.Equal(t, time.Minute, shared.Timeout)
+
+		// the shared client does not send the auth of the coze clients
+		req, err := http.NewRequest(http.MethodGet, ComBaseURL, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Client", "other")
+		_, err = shared.Do(req)
+		require.NoError(t, err)
+		assert.Empty(t, authorizations["other"])
+	})
+
+	t.Run("transport option", func(t *testing.T) {
+		var authorization string
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithHttpClient(&http.Client{Transport: http.DefaultTransport}),
+			WithTransport(&mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					authorization = req.Header.Get("Authorization")
+					return mockResponse(http.StatusOK, &meResp{User: &User{UserID: "user1"}})
+				},
+			}),
+		)
+		_, err := api.Users.Me(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer token", authorization)
+	})
+
+	t.Run("default client", func(t *testing.T) {
+		api1 := NewCozeAPI(NewTokenAuth("token1"))
+		api2 := NewCozeAPI(NewTokenAuth("token2"))
+		client1 := api1.Users.(*users).client.httpClient.(*http.Client)
+		client2 := api2.Users.(*users).client.httpClient.(*http.Client)
+		assert.NotSame(t, client1, client2)
+		assert.Same(t, client1.Transport.(*authTransport).next, client2.Transport.(*authTransport).next)
+		assert.Zero(t, defaultHTTPClient.Timeout)
+		assert.Same(t, defaultHTTPClient.Transport, client1.Transport.(*authTransport).next)
+
+		transport := NewDefaultTransport()
+		assert.True(t, transport.ForceAttemptHTTP2)
+		assert.NotNil(t, transport.Proxy)
+		assert.NotZero(t, transport.TLSHandshakeTimeout)
+		assert.NotZero(t, transport.MaxIdleConnsPerHost)
+	})
+}
+
 func TestAuthTransport(t *testing.T) {
 	// Test successful authentication
 	t.Run("successful authentication", func(t *testing.T) {
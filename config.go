@@ -0,0 +1,448 @@
+package coze
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the configuration of a client, read from a JSON file by LoadConfig or from the
+// environment by LoadConfigFromEnv, see NewCozeAPIFromConfig. It embeds OAuthConfig, so that the
+// files of LoadOAuthAppFromConfig are valid configs.
+//
+// A file holds either a single config, or named profiles:
+//
+//	{
+//	  "default_profile": "cn",
+//	  "profiles": {
+//	    "cn": {"api_token": "pat_xxx", "timeout": "30s"},
+//	    "com": {"client_type": "jwt", "client_id": "xxx", "public_key_id": "xxx", "private_key_file": "key.pem"}
+//	  }
+//	}
+type Config struct {
+	OAuthConfig
+
+	// APIToken is a personal access token. When set, the client uses it instead of OAuth.
+	APIToken string `json:"api_token,omitempty"`
+
+	// Region selects the base URL when CozeAPIBase is empty: "com" for ComBaseURL, the default,
+	// or "cn" for CnBaseURL. The profiles named com and cn default to their region.
+	Region string `json:"region,omitempty"`
+
+	// PrivateKeyFile is the path of the PEM private key of a JWT client, instead of PrivateKey.
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+
+	// LogLevel is the level of the logs of the client: trace, debug, info, warn or error.
+	LogLevel string `json:"log_level,omitempty"`
+
+	// Timeout bounds every request, such as "30s", reading the response included. It is unset by
+	// default, since streams can last minutes.
+	Timeout string `json:"timeout,omitempty"`
+
+	// Retry enables the retry of the transient failures, see RetryConfig.
+	Retry *RetryConfig `json:"retry,omitempty"`
+}
+
+// RetryConfig overrides the fields of DefaultRetryPolicy, the unset ones keep their default.
+type RetryConfig struct {
+	MaxAttempts          int      `json:"max_attempts,omitempty"`
+	BaseDelay            string   `json:"base_delay,omitempty"`
+	MaxDelay             string   `json:"max_delay,omitempty"`
+	Jitter               *float64 `json:"jitter,omitempty"`
+	RetryableStatusCodes []int    `json:"retryable_status_codes,omitempty"`
+	RetryableCodes       []int    `json:"retryable_codes,omitempty"`
+	RetryNonIdempotent   bool     `json:"retry_non_idempotent,omitempty"`
+}
+
+// ConfigError reports an invalid or missing key of a config. Key is the JSON key, prefixed by the
+// profile in a profiles file, or the environment variable it was read from.
+type ConfigError struct {
+	Key     string
+	Message string
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config %s: %s", e.Key, e.Message)
+}
+
+// AsConfigError checks if the error is of type ConfigError
+func AsConfigError(err error) (*ConfigError, bool) {
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		return configErr, true
+	}
+	return nil, false
+}
+
+// NewCozeAPIFromConfig creates a client from the config, authenticated with its API token, or
+// with a JWT OAuth client. The options are applied after the ones of the config.
+func NewCozeAPIFromConfig(config *Config, opts ...CozeAPIOption) (CozeAPI, error) {
+	resolved, err := config.resolve()
+	if err != nil {
+		return CozeAPI{}, err
+	}
+	auth, err := resolved.auth(opts)
+	if err != nil {
+		return CozeAPI{}, err
+	}
+	return NewCozeAPI(auth, append(append([]CozeAPIOption{}, resolved.opts...), opts...)...), nil
+}
+
+// NewCozeAPIFromEnv creates a client from the environment, see LoadConfigFromEnv.
+func NewCozeAPIFromEnv(opts ...CozeAPIOption) (CozeAPI, error) {
+	config, err := LoadConfigFromEnv()
+	if err != nil {
+		return CozeAPI{}, err
+	}
+	return NewCozeAPIFromConfig(config, opts...)
+}
+
+// LoadConfig reads the config file at path, and returns its profile. An empty profile selects the
+// default_profile of the file, or its only profile. The profile is ignored by the files holding a
+// single config.
+func LoadConfig(path, profile string) (*Config, error) {
+	config, prefix, err := readConfig(path, profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.validate(prefix, nil); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// readConfig reads the profile of the config file, and returns the prefix of its keys in the file.
+func readConfig(path, profile string) (*Config, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config: %w", err)
+	}
+	var file struct {
+		DefaultProfile string                     `json:"default_profile"`
+		Profiles       map[string]json.RawMessage `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, "", configDecodeError("", err)
+	}
+	if file.Profiles == nil {
+		config, err := decodeConfig("", data)
+		return config, "", err
+	}
+
+	if profile == "" {
+		profile = file.DefaultProfile
+	}
+	if profile == "" && len(file.Profiles) == 1 {
+		for name := range file.Profiles {
+			profile = name
+		}
+	}
+	if profile == "" {
+		return nil, "", &ConfigError{Key: "default_profile", Message: "is required to choose between the profiles " + strings.Join(profileNames(file.Profiles), ", ")}
+	}
+	raw, ok := file.Profiles[profile]
+	if !ok {
+		return nil, "", &ConfigError{Key: "profiles." + profile, Message: "profile not found"}
+	}
+	prefix := "profiles." + profile + "."
+	config, err := decodeConfig(prefix, raw)
+	if err != nil {
+		return nil, "", err
+	}
+	if config.Region == "" && config.CozeAPIBase == "" && (profile == "com" || profile == "cn") {
+		config.Region = profile
+	}
+	return config, prefix, nil
+}
+
+// configEnv maps the environment variables to the keys of the config.
+var configEnv = []struct {
+	name  string
+	key   string
+	field func(c *Config) *string
+}{
+	{"COZE_API_TOKEN", "api_token", func(c *Config) *string { return &c.APIToken }},
+	{"COZE_API_BASE", "coze_api_base", func(c *Config) *string { return &c.CozeAPIBase }},
+	{"COZE_WWW_BASE", "coze_www_base", func(c *Config) *string { return &c.CozeWWWBase }},
+	{"COZE_REGION", "region", func(c *Config) *string { return &c.Region }},
+	{"COZE_CLIENT_TYPE", "client_type", func(c *Config) *string { return &c.ClientType }},
+	{"COZE_CLIENT_ID", "client_id", func(c *Config) *string { return &c.ClientID }},
+	{"COZE_PUBLIC_KEY_ID", "public_key_id", func(c *Config) *string { return &c.PublicKeyID }},
+	{"COZE_PRIVATE_KEY", "private_key", func(c *Config) *string { return &c.PrivateKey }},
+	{"COZE_PRIVATE_KEY_FILE", "private_key_file", func(c *Config) *string { return &c.PrivateKeyFile }},
+	{"COZE_LOG_LEVEL", "log_level", func(c *Config) *string { return &c.LogLevel }},
+	{"COZE_TIMEOUT", "timeout", func(c *Config) *string { return &c.Timeout }},
+}
+
+// LoadConfigFromEnv reads the config file at COZE_CONFIG_FILE, if set, with the profile
+// COZE_PROFILE, then overrides its keys with the environment variables: COZE_API_TOKEN,
+// COZE_API_BASE, COZE_WWW_BASE, COZE_REGION, COZE_CLIENT_TYPE, COZE_CLIENT_ID,
+// COZE_PUBLIC_KEY_ID, COZE_PRIVATE_KEY, COZE_PRIVATE_KEY_FILE, COZE_LOG_LEVEL, COZE_TIMEOUT and
+// COZE_RETRY_MAX_ATTEMPTS. The errors name the variable of the invalid key.
+func LoadConfigFromEnv() (*Config, error) {
+	config, prefix := &Config{}, ""
+	if path := os.Getenv("COZE_CONFIG_FILE"); path != "" {
+		var err error
+		if config, prefix, err = readConfig(path, os.Getenv("COZE_PROFILE")); err != nil {
+			return nil, err
+		}
+	}
+
+	envKeys := map[string]string{}
+	for _, env := range configEnv {
+		if value, ok := os.LookupEnv(env.name); ok && value != "" {
+			*env.field(config) = value
+			envKeys[env.key] = env.name
+		}
+	}
+	if value := os.Getenv("COZE_RETRY_MAX_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, &ConfigError{Key: "COZE_RETRY_MAX_ATTEMPTS", Message: "must be an integer"}
+		}
+		if config.Retry == nil {
+			config.Retry = &RetryConfig{}
+		}
+		config.Retry.MaxAttempts = attempts
+		envKeys["retry.max_attempts"] = "COZE_RETRY_MAX_ATTEMPTS"
+	}
+
+	if err := config.validate(prefix, envKeys); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// validate validates the config, and names the invalid key with its environment variable in
+// envKeys, or its path in the file.
+func (c *Config) validate(prefix string, envKeys map[string]string) error {
+	_, err := c.resolve()
+	configErr, ok := AsConfigError(err)
+	if !ok {
+		return err
+	}
+	if name, ok := envKeys[configErr.Key]; ok {
+		return &ConfigError{Key: name, Message: configErr.Message}
+	}
+	return &ConfigError{Key: prefix + configErr.Key, Message: configErr.Message}
+}
+
+// resolvedConfig is a validated config: the options of its client, and its API token or the
+// param of its JWT OAuth client.
+type resolvedConfig struct {
+	opts   []CozeAPIOption
+	token  string
+	jwt    NewJWTOAuthClientParam
+	wwwURL string
+}
+
+// resolve validates the config, reading its private key file, and returns the options and the
+// credentials of its client.
+func (c *Config) resolve() (*resolvedConfig, error) {
+	resolved := &resolvedConfig{wwwURL: c.CozeWWWBase}
+
+	baseURL := c.CozeAPIBase
+	switch c.Region {
+	case "", "com":
+		if baseURL == "" {
+			baseURL = ComBaseURL
+		}
+	case "cn":
+		if baseURL == "" {
+			baseURL = CnBaseURL
+		}
+	default:
+		return nil, &ConfigError{Key: "region", Message: fmt.Sprintf("must be com or cn, not %q", c.Region)}
+	}
+	resolved.opts = append(resolved.opts, WithBaseURL(baseURL))
+
+	if c.LogLevel != "" {
+		level, ok := parseLogLevel(c.LogLevel)
+		if !ok {
+			return nil, &ConfigError{Key: "log_level", Message: fmt.Sprintf("must be trace, debug, info, warn or error, not %q", c.LogLevel)}
+		}
+		resolved.opts = append(resolved.opts, WithLogLevel(level))
+	}
+
+	if c.Timeout != "" {
+		timeout, err := parseConfigDuration("timeout", c.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		resolved.opts = append(resolved.opts, WithHttpClient(&http.Client{Transport: defaultHTTPClient.Transport, Timeout: timeout}))
+	}
+
+	if c.Retry != nil {
+		policy, err := c.Retry.policy()
+		if err != nil {
+			return nil, err
+		}
+		resolved.opts = append(resolved.opts, WithRetryPolicy(policy))
+	}
+
+	if err := c.resolveCredentials(resolved); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+func (c *Config) resolveCredentials(resolved *resolvedConfig) error {
+	if c.APIToken != "" {
+		resolved.token = c.APIToken
+		return nil
+	}
+	if c.ClientID == "" && c.ClientType == "" {
+		return &ConfigError{Key: "api_token", Message: "is required, unless client_type and client_id are set"}
+	}
+	if c.ClientType != "jwt" {
+		return &ConfigError{Key: "client_type", Message: fmt.Sprintf("must be jwt, the only client type authorizing without a user, not %q", c.ClientType)}
+	}
+	if c.ClientID == "" {
+		return &ConfigError{Key: "client_id", Message: "is required for JWT client"}
+	}
+	if c.PublicKeyID == "" {
+		return &ConfigError{Key: "public_key_id", Message: "is required for JWT client"}
+	}
+
+	privateKey, privateKeyKey := c.PrivateKey, "private_key"
+	if c.PrivateKeyFile != "" {
+		if privateKey != "" {
+			return &ConfigError{Key: "private_key_file", Message: "is exclusive with private_key"}
+		}
+		data, err := os.ReadFile(c.PrivateKeyFile)
+		if err != nil {
+			return &ConfigError{Key: "private_key_file", Message: err.Error()}
+		}
+		privateKey, privateKeyKey = string(data), "private_key_file"
+	}
+	if privateKey == "" {
+		return &ConfigError{Key: "private_key", Message: "is required for JWT client, or private_key_file"}
+	}
+	if _, err := parsePrivateKey(privateKey); err != nil {
+		return &ConfigError{Key: privateKeyKey, Message: fmt.Sprintf("failed to parse private key: %s", err)}
+	}
+	resolved.jwt = NewJWTOAuthClientParam{ClientID: c.ClientID, PublicKey: c.PublicKeyID, PrivateKeyPEM: privateKey}
+	return nil
+}
+
+// auth returns the auth of the client. The OAuth client of the auth sends its requests like the
+// API client, extra options included.
+func (r *resolvedConfig) auth(extra []CozeAPIOption) (Auth, error) {
+	if r.token != "" {
+		return NewTokenAuth(r.token), nil
+	}
+	opt := &newCozeAPIOpt{}
+	for _, option := range append(append([]CozeAPIOption{}, r.opts...), extra...) {
+		option(opt)
+	}
+	client := *defaultHTTPClient
+	if opt.client != nil {
+		client = *opt.client
+	}
+	if opt.transport != nil {
+		client.Transport = opt.transport
+	}
+	authOpts := []OAuthClientOption{
+		WithAuthBaseURL(opt.baseURL),
+		WithAuthHttpClient(&client),
+		WithAuthLogger(opt.logger),
+		WithAuthLogLevel(opt.logLevel),
+		WithAuthLogRedaction(opt.redaction),
+	}
+	if r.wwwURL != "" {
+		authOpts = append(authOpts, WithAuthWWWURL(r.wwwURL))
+	}
+	jwtClient, err := NewJWTOAuthClient(r.jwt, authOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewJWTAuth(jwtClient, nil), nil
+}
+
+func (r *RetryConfig) policy() (*RetryPolicy, error) {
+	policy := DefaultRetryPolicy()
+	if r.MaxAttempts < 0 {
+		return nil, &ConfigError{Key: "retry.max_attempts", Message: "must not be negative"}
+	}
+	if r.MaxAttempts > 0 {
+		policy.MaxAttempts = r.MaxAttempts
+	}
+	if r.BaseDelay != "" {
+		delay, err := parseConfigDuration("retry.base_delay", r.BaseDelay)
+		if err != nil {
+			return nil, err
+		}
+		policy.BaseDelay = delay
+	}
+	if r.MaxDelay != "" {
+		delay, err := parseConfigDuration("retry.max_delay", r.MaxDelay)
+		if err != nil {
+			return nil, err
+		}
+		policy.MaxDelay = delay
+	}
+	if r.Jitter != nil {
+		if *r.Jitter < 0 || *r.Jitter > 1 {
+			return nil, &ConfigError{Key: "retry.jitter", Message: "must be between 0 and 1"}
+		}
+		policy.Jitter = *r.Jitter
+	}
+	if r.RetryableStatusCodes != nil {
+		policy.RetryableStatusCodes = r.RetryableStatusCodes
+	}
+	if r.RetryableCodes != nil {
+		policy.RetryableCodes = r.RetryableCodes
+	}
+	policy.RetryNonIdempotent = r.RetryNonIdempotent
+	return policy, nil
+}
+
+// decodeConfig decodes a config, prefix being the path of its keys in the file.
+func decodeConfig(prefix string, data []byte) (*Config, error) {
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, configDecodeError(prefix, err)
+	}
+	return config, nil
+}
+
+func configDecodeError(prefix string, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &ConfigError{Key: prefix + typeErr.Field, Message: fmt.Sprintf("must be a %s, not a %s", typeErr.Type, typeErr.Value)}
+	}
+	return fmt.Errorf("failed to decode config: %w", err)
+}
+
+func parseConfigDuration(key, value string) (time.Duration, error) {
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, &ConfigError{Key: key, Message: fmt.Sprintf("must be a duration such as 30s, not %q", value)}
+	}
+	return duration, nil
+}
+
+func parseLogLevel(level string) (LogLevel, bool) {
+	for _, l := range []LogLevel{LogLevelTrace, LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError} {
+		if strings.EqualFold(level, l.String()) {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+func profileNames(profiles map[string]json.RawMessage) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,246 @@
+package coze
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestNewCozeAPIFromConfig(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("api token", func(t *testing.T) {
+		var paths, authorizations []string
+		transport := &mockTransport{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.String())
+			authorizations = append(authorizations, req.Header.Get(authorizeHeader))
+			return mockResponse(http.StatusOK, map[string]interface{}{"data": map[string]string{"user_id": "1"}})
+		}}
+
+		api, err := NewCozeAPIFromConfig(&Config{APIToken: "pat_token", Region: "cn"}, WithTransport(transport))
+		require.NoError(t, err)
+		assert.Equal(t, CnBaseURL, api.baseURL)
+		_, err = api.Users.Me(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{CnBaseURL + "/v1/users/me"}, paths)
+		assert.Equal(t, []string{"Bearer pat_token"}, authorizations)
+	})
+
+	t.Run("jwt private key file", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		require.NoError(t, err)
+		keyFile := writeConfigFile(t, "key.pem", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyDER})))
+
+		var paths, authorizations []string
+		transport := &mockTransport{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			paths = append(paths, req.URL.Path)
+			authorizations = append(authorizations, req.Header.Get(authorizeHeader))
+			if req.URL.Path == getTokenPath {
+				return mockResponse(http.StatusOK, map[string]interface{}{
+					"access_token": "jwt_token", "expires_in": time.Now().Add(time.Hour).Unix(),
+				})
+			}
+			return mockResponse(http.StatusOK, map[string]interface{}{"data": map[string]string{"user_id": "1"}})
+		}}
+
+		api, err := NewCozeAPIFromConfig(&Config{
+			OAuthConfig:    OAuthConfig{ClientType: "jwt", ClientID: "client", PublicKeyID: "key_id", CozeAPIBase: "https://coze.example.com"},
+			PrivateKeyFile: keyFile,
+		}, WithTransport(transport))
+		require.NoError(t, err)
+		_, err = api.Users.Me(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{getTokenPath, "/v1/users/me"}, paths)
+		assert.Equal(t, "Bearer jwt_token", authorizations[1])
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		cases := map[string]struct {
+			config *Config
+			key    string
+		}{
+			"no auth":         {&Config{}, "api_token"},
+			"region":          {&Config{APIToken: "pat", Region: "eu"}, "region"},
+			"log level":       {&Config{APIToken: "pat", LogLevel: "verbose"}, "log_level"},
+			"timeout":         {&Config{APIToken: "pat", Timeout: "soon"}, "timeout"},
+			"retry delay":     {&Config{APIToken: "pat", Retry: &RetryConfig{MaxDelay: "-1s"}}, "retry.max_delay"},
+			"retry jitter":    {&Config{APIToken: "pat", Retry: &RetryConfig{Jitter: ptr(2.0)}}, "retry.jitter"},
+			"client type":     {&Config{OAuthConfig: OAuthConfig{ClientType: "web", ClientID: "client"}}, "client_type"},
+			"public key id":   {&Config{OAuthConfig: OAuthConfig{ClientType: "jwt", ClientID: "client"}}, "public_key_id"},
+			"private key":     {&Config{OAuthConfig: OAuthConfig{ClientType: "jwt", ClientID: "client", PublicKeyID: "id"}}, "private_key"},
+			"bad private key": {&Config{OAuthConfig: OAuthConfig{ClientType: "jwt", ClientID: "client", PublicKeyID: "id", PrivateKey: "bad"}}, "private_key"},
+			"key file":        {&Config{OAuthConfig: OAuthConfig{ClientType: "jwt", ClientID: "client", PublicKeyID: "id"}, PrivateKeyFile: "missing.pem"}, "private_key_file"},
+		}
+		for name, c := range cases {
+			c := c
+			t.Run(name, func(t *testing.T) {
+				_, err := NewCozeAPIFromConfig(c.config)
+				configErr, ok := AsConfigError(err)
+				require.True(t, ok, "%v", err)
+				assert.Equal(t, c.key, configErr.Key)
+				assert.Contains(t, err.Error(), c.key)
+			})
+		}
+	})
+}
+
+func TestRetryConfig(t *testing.T) {
+	jitter := 0.0
+	policy, err := (&RetryConfig{MaxAttempts: 5, BaseDelay: "100ms", Jitter: &jitter, RetryNonIdempotent: true}).policy()
+	require.NoError(t, err)
+	assert.Equal(t, 5, policy.MaxAttempts)
+	assert.Equal(t, 100*time.Millisecond, policy.BaseDelay)
+	assert.Equal(t, DefaultRetryPolicy().MaxDelay, policy.MaxDelay)
+	assert.Equal(t, 0.0, policy.Jitter)
+	assert.Equal(t, DefaultRetryPolicy().RetryableStatusCodes, policy.RetryableStatusCodes)
+	assert.True(t, policy.RetryNonIdempotent)
+}
+
+func TestLoadConfig(t *testing.T) {
+	profiles := `{
+		"default_profile": "com",
+		"profiles": {
+			"com": {"api_token": "pat_com", "log_level": "debug"},
+			"cn": {"api_token": "pat_cn", "timeout": "30s", "retry": {"max_attempts": 2}},
+			"broken": {"api_token": "pat", "timeout": "soon"}
+		}
+	}`
+
+	t.Run("profiles", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", profiles)
+
+		config, err := LoadConfig(path, "")
+		require.NoError(t, err)
+		assert.Equal(t, "pat_com", config.APIToken)
+		assert.Equal(t, "com", config.Region)
+		assert.Equal(t, "debug", config.LogLevel)
+
+		config, err = LoadConfig(path, "cn")
+		require.NoError(t, err)
+		assert.Equal(t, "cn", config.Region)
+		assert.Equal(t, "30s", config.Timeout)
+		assert.Equal(t, 2, config.Retry.MaxAttempts)
+		api, err := NewCozeAPIFromConfig(config)
+		require.NoError(t, err)
+		assert.Equal(t, CnBaseURL, api.baseURL)
+
+		_, err = LoadConfig(path, "broken")
+		configErr, ok := AsConfigError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "profiles.broken.timeout", configErr.Key)
+
+		_, err = LoadConfig(path, "missing")
+		configErr, ok = AsConfigError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "profiles.missing", configErr.Key)
+	})
+
+	t.Run("default profile", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{"profiles": {"a": {"api_token": "a"}, "b": {"api_token": "b"}}}`)
+		_, err := LoadConfig(path, "")
+		configErr, ok := AsConfigError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "default_profile", configErr.Key)
+		assert.Contains(t, configErr.Message, "a, b")
+	})
+
+	t.Run("oauth config", func(t *testing.T) {
+		path := writeConfigFile(t, "oauth.json", `{"client_id": "client", "client_type": "device", "coze_api_base": "https://api.coze.cn"}`)
+		_, err := LoadConfig(path, "")
+		configErr, ok := AsConfigError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "client_type", configErr.Key)
+
+		path = writeConfigFile(t, "config.json", `{"api_token": "pat", "timeout": 30}`)
+		_, err = LoadConfig(path, "")
+		configErr, ok = AsConfigError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "timeout", configErr.Key)
+	})
+	t.Run("changes after loading", func(t *testing.T) {
+		path := writeConfigFile(t, "config.json", `{"api_token": "pat_file"}`)
+		config, err := LoadConfig(path, "")
+		require.NoError(t, err)
+
+		var authorizations []string
+		transport := &mockTransport{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			authorizations = append(authorizations, req.Header.Get(authorizeHeader))
+			return mockResponse(http.StatusOK, map[string]interface{}{"data": map[string]string{"user_id": "1"}})
+		}}
+		config.APIToken, config.Region = "pat_changed", "cn"
+		api, err := NewCozeAPIFromConfig(config, WithTransport(transport))
+		require.NoError(t, err)
+		assert.Equal(t, CnBaseURL, api.baseURL)
+		_, err = api.Users.Me(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bearer pat_changed"}, authorizations)
+	})
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	for _, env := range configEnv {
+		t.Setenv(env.name, "")
+	}
+	t.Setenv("COZE_RETRY_MAX_ATTEMPTS", "")
+	t.Setenv("COZE_PROFILE", "")
+
+	t.Run("env only", func(t *testing.T) {
+		t.Setenv("COZE_CONFIG_FILE", "")
+		t.Setenv("COZE_API_TOKEN", "pat_env")
+		t.Setenv("COZE_API_BASE", "https://coze.example.com")
+		t.Setenv("COZE_RETRY_MAX_ATTEMPTS", "4")
+
+		config, err := LoadConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "pat_env", config.APIToken)
+		assert.Equal(t, 4, config.Retry.MaxAttempts)
+		api, err := NewCozeAPIFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "https://coze.example.com", api.baseURL)
+	})
+
+	t.Run("file and env", func(t *testing.T) {
+		t.Setenv("COZE_CONFIG_FILE", writeConfigFile(t, "config.json", `{"profiles": {"cn": {"log_level": "warn"}, "com": {}}}`))
+		t.Setenv("COZE_PROFILE", "cn")
+		t.Setenv("COZE_API_TOKEN", "pat_env")
+
+		config, err := LoadConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, "pat_env", config.APIToken)
+		assert.Equal(t, "cn", config.Region)
+		assert.Equal(t, "warn", config.LogLevel)
+
+		t.Setenv("COZE_TIMEOUT", "soon")
+		_, err = LoadConfigFromEnv()
+		configErr, ok := AsConfigError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "COZE_TIMEOUT", configErr.Key)
+	})
+
+	t.Run("invalid integer", func(t *testing.T) {
+		t.Setenv("COZE_API_TOKEN", "pat_env")
+		t.Setenv("COZE_RETRY_MAX_ATTEMPTS", "three")
+		_, err := NewCozeAPIFromEnv()
+		configErr, ok := AsConfigError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "COZE_RETRY_MAX_ATTEMPTS", configErr.Key)
+	})
+}
@@ -7,7 +7,8 @@ import (
 	"strconv"
 )
 
-func (r *conversations) List(ctx context.Context, req *ListConversationsReq) (NumberPaged[Conversation], error) {
+func (r *conversations) List(ctx context.Context, req *ListConversationsReq, opts ...CallOption) (NumberPaged[Conversation], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.PageSize == 0 {
 		req.PageSize = 20
 	}
@@ -33,7 +34,8 @@ func (r *conversations) List(ctx context.Context, req *ListConversationsReq) (Nu
 		}, req.PageSize, req.PageNum)
 }
 
-func (r *conversations) Create(ctx context.Context, req *CreateConversationsReq) (*CreateConversationsResp, error) {
+func (r *conversations) Create(ctx context.Context, req *CreateConversationsReq, opts ...CallOption) (*CreateConversationsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	uri := "/v1/conversation/create"
 	resp := &createConversationsResp{}
 	err := r.client.Request(ctx, http.MethodPost, uri, req, resp)
@@ -44,7 +46,8 @@ func (r *conversations) Create(ctx context.Context, req *CreateConversationsReq)
 	return resp.Conversation, nil
 }
 
-func (r *conversations) Retrieve(ctx context.Context, req *RetrieveConversationsReq) (*RetrieveConversationsResp, error) {
+func (r *conversations) Retrieve(ctx context.Context, req *RetrieveConversationsReq, opts ...CallOption) (*RetrieveConversationsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	uri := "/v1/conversation/retrieve"
 	resp := &retrieveConversationsResp{}
 	err := r.client.Request(ctx, http.MethodGet, uri, nil, resp, withHTTPQuery("conversation_id", req.ConversationID))
@@ -55,7 +58,8 @@ func (r *conversations) Retrieve(ctx context.Context, req *RetrieveConversations
 	return resp.Conversation, nil
 }
 
-func (r *conversations) Clear(ctx context.Context, req *ClearConversationsReq) (*ClearConversationsResp, error) {
+func (r *conversations) Clear(ctx context.Context, req *ClearConversationsReq, opts ...CallOption) (*ClearConversationsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	uri := fmt.Sprintf("/v1/conversations/%s/clear", req.ConversationID)
 	resp := &clearConversationsResp{}
 	err := r.client.Request(ctx, http.MethodPost, uri, nil, resp)
@@ -66,6 +70,27 @@ func (r *conversations) Clear(ctx context.Context, req *ClearConversationsReq) (
 	return resp.Data, nil
 }
 
+// ConversationsService is the conversation api, see CozeAPI.Conversations.
+type ConversationsService interface {
+	List(ctx context.Context, req *ListConversationsReq, opts ...CallOption) (NumberPaged[Conversation], error)
+	Create(ctx context.Context, req *CreateConversationsReq, opts ...CallOption) (*CreateConversationsResp, error)
+	Retrieve(ctx context.Context, req *RetrieveConversationsReq, opts ...CallOption) (*RetrieveConversationsResp, error)
+	Clear(ctx context.Context, req *ClearConversationsReq, opts ...CallOption) (*ClearConversationsResp, error)
+}
+
+// ConversationsAPI is the conversation api and its sub-services.
+type ConversationsAPI struct {
+	ConversationsService
+	Messages ConversationMessagesService
+}
+
+func newConversationsAPI(core *core) *ConversationsAPI {
+	conversations := newConversations(core)
+	return &ConversationsAPI{ConversationsService: conversations, Messages: conversations.Messages}
+}
+
+var _ ConversationsService = (*conversations)(nil)
+
 type conversations struct {
 	client   *core
 	Messages *conversationsMessages
@@ -175,5 +200,7 @@ type clearConversationsResp struct {
 
 type ClearConversationsResp struct {
 	baseModel
+	// The ID of the new context section of the conversation.
+	SectionID      string `json:"id"`
 	ConversationID string `json:"conversation_id"`
 }
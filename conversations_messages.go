@@ -5,7 +5,8 @@ import (
 	"net/http"
 )
 
-func (r *conversationsMessages) Create(ctx context.Context, req *CreateMessageReq) (*CreateMessageResp, error) {
+func (r *conversationsMessages) Create(ctx context.Context, req *CreateMessageReq, opts ...CallOption) (*CreateMessageResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/conversation/message/create"
 	resp := &createMessageResp{}
@@ -19,7 +20,8 @@ func (r *conversationsMessages) Create(ctx context.Context, req *CreateMessageRe
 	return resp.Message, nil
 }
 
-func (r *conversationsMessages) List(ctx context.Context, req *ListConversationsMessagesReq) (LastIDPaged[Message], error) {
+func (r *conversationsMessages) List(ctx context.Context, req *ListConversationsMessagesReq, opts ...CallOption) (LastIDPaged[Message], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.Limit == 0 {
 		req.Limit = 20
 	}
@@ -52,7 +54,8 @@ func (r *conversationsMessages) List(ctx context.Context, req *ListConversations
 		}, req.Limit, req.AfterID)
 }
 
-func (r *conversationsMessages) Retrieve(ctx context.Context, req *RetrieveConversationsMessagesReq) (*RetrieveConversationsMessagesResp, error) {
+func (r *conversationsMessages) Retrieve(ctx context.Context, req *RetrieveConversationsMessagesReq, opts ...CallOption) (*RetrieveConversationsMessagesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodGet
 	uri := "/v1/conversation/message/retrieve"
 	resp := &retrieveConversationsMessagesResp{}
@@ -67,7 +70,8 @@ func (r *conversationsMessages) Retrieve(ctx context.Context, req *RetrieveConve
 	return resp.Message, nil
 }
 
-func (r *conversationsMessages) Update(ctx context.Context, req *UpdateConversationMessagesReq) (*UpdateConversationMessagesResp, error) {
+func (r *conversationsMessages) Update(ctx context.Context, req *UpdateConversationMessagesReq, opts ...CallOption) (*UpdateConversationMessagesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/conversation/message/modify"
 	resp := &updateConversationMessagesResp{}
@@ -86,7 +90,8 @@ func (r *conversationsMessages) Update(ctx context.Context, req *UpdateConversat
 	return resp.Message, nil
 }
 
-func (r *conversationsMessages) Delete(ctx context.Context, req *DeleteConversationsMessagesReq) (*DeleteConversationsMessagesResp, error) {
+func (r *conversationsMessages) Delete(ctx context.Context, req *DeleteConversationsMessagesReq, opts ...CallOption) (*DeleteConversationsMessagesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/conversation/message/delete"
 	resp := &deleteConversationsMessagesResp{}
@@ -101,6 +106,18 @@ func (r *conversationsMessages) Delete(ctx context.Context, req *DeleteConversat
 	return resp.Message, nil
 }
 
+// ConversationMessagesService is the api of the messages of a conversation, see
+// ConversationsAPI.Messages.
+type ConversationMessagesService interface {
+	Create(ctx context.Context, req *CreateMessageReq, opts ...CallOption) (*CreateMessageResp, error)
+	List(ctx context.Context, req *ListConversationsMessagesReq, opts ...CallOption) (LastIDPaged[Message], error)
+	Retrieve(ctx context.Context, req *RetrieveConversationsMessagesReq, opts ...CallOption) (*RetrieveConversationsMessagesResp, error)
+	Update(ctx context.Context, req *UpdateConversationMessagesReq, opts ...CallOption) (*UpdateConversationMessagesResp, error)
+	Delete(ctx context.Context, req *DeleteConversationsMessagesReq, opts ...CallOption) (*DeleteConversationsMessagesResp, error)
+}
+
+var _ ConversationMessagesService = (*conversationsMessages)(nil)
+
 type conversationsMessages struct {
 	core *core
 }
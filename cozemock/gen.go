@@ -0,0 +1,282 @@
+//go:build ignore
+
+// gen generates the mocks of the service interfaces of the coze package into mocks.go, see
+// the go:generate directive of the cozemock package.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const cozeImport = "github.com/coze-dev/coze-go"
+
+type param struct {
+	name     string
+	typ      string
+	variadic bool
+}
+
+type method struct {
+	name    string
+	params  []*param
+	results []string
+}
+
+type service struct {
+	name    string
+	methods []*method
+}
+
+func main() {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, "..", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pkg, ok := pkgs["coze"]
+	if !ok {
+		log.Fatal("package coze not found")
+	}
+
+	imports := map[string]string{"coze": cozeImport}
+	var services []*service
+	for _, file := range pkg.Files {
+		fileImports := map[string]string{}
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			fileImports[name] = path
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				iface, ok := typeSpec.Type.(*ast.InterfaceType)
+				if !ok || !strings.HasSuffix(typeSpec.Name.Name, "Service") {
+					continue
+				}
+				// The imports are collected first, since qualify adds the coze selectors.
+				for name := range selectors(iface) {
+					path, ok := fileImports[name]
+					if !ok {
+						log.Fatalf("import of %s not found", name)
+					}
+					imports[name] = path
+				}
+				svc := &service{name: typeSpec.Name.Name}
+				for _, field := range iface.Methods.List {
+					fn, ok := field.Type.(*ast.FuncType)
+					if !ok {
+						log.Fatalf("%s embeds %s, which is not supported", svc.name, typeString(field.Type))
+					}
+					svc.methods = append(svc.methods, newMethod(field.Names[0].Name, fn))
+				}
+				services = append(services, svc)
+			}
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].name < services[j].name })
+
+	src, err := format.Source(generate(services, imports))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("mocks.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func newMethod(name string, fn *ast.FuncType) *method {
+	m := &method{name: name}
+	for _, field := range fn.Params.List {
+		typ := field.Type
+		variadic := false
+		if ellipsis, ok := typ.(*ast.Ellipsis); ok {
+			typ, variadic = ellipsis.Elt, true
+		}
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("p%d", len(m.params)))}
+		}
+		for _, n := range names {
+			m.params = append(m.params, &param{name: n.Name, typ: typeString(qualify(typ)), variadic: variadic})
+		}
+	}
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			for i := 0; i < len(field.Names) || i == 0; i++ {
+				m.results = append(m.results, typeString(qualify(field.Type)))
+			}
+		}
+	}
+	return m
+}
+
+// qualify qualifies the identifiers of the coze package in the type with the package name.
+func qualify(expr ast.Expr) ast.Expr {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if t.IsExported() {
+			return &ast.SelectorExpr{X: ast.NewIdent("coze"), Sel: t}
+		}
+	case *ast.StarExpr:
+		t.X = qualify(t.X)
+	case *ast.ArrayType:
+		t.Elt = qualify(t.Elt)
+	case *ast.MapType:
+		t.Key, t.Value = qualify(t.Key), qualify(t.Value)
+	case *ast.IndexExpr:
+		t.X, t.Index = qualify(t.X), qualify(t.Index)
+	case *ast.SelectorExpr:
+	default:
+		log.Fatalf("unsupported type %T", expr)
+	}
+	return expr
+}
+
+// selectors returns the packages referenced by the node.
+func selectors(node ast.Node) map[string]bool {
+	res := map[string]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok {
+				res[x.Name] = true
+			}
+		}
+		return true
+	})
+	return res
+}
+
+func typeString(expr ast.Expr) string {
+	return types.ExprString(expr)
+}
+
+func generate(services []*service, imports map[string]string) []byte {
+	var b bytes.Buffer
+	p := func(format string, args ...interface{}) { fmt.Fprintf(&b, format+"\n", args...) }
+
+	p("// Code generated by gen.go. DO NOT EDIT.")
+	p("")
+	p("package cozemock")
+	p("")
+	p("import (")
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "coze" {
+			continue
+		}
+		p("\t%q", imports[name])
+	}
+	p("")
+	p("\t%q", cozeImport)
+	p(")")
+
+	for _, svc := range services {
+		p("")
+		p("// %s is a mock of coze.%s. Each method records its call, and returns the", svc.name, svc.name)
+		p("// result of the function of the same name suffixed by Func, or ErrNotMocked if it is nil.")
+		p("type %s struct {", svc.name)
+		p("\tmock")
+		p("")
+		for _, m := range svc.methods {
+			p("\t%sFunc func%s", m.name, m.signature())
+		}
+		p("}")
+		p("")
+		p("var _ coze.%s = (*%s)(nil)", svc.name, svc.name)
+		for _, m := range svc.methods {
+			p("")
+			p("func (m *%s) %s%s {", svc.name, m.name, m.signature())
+			var args, callArgs []string
+			ctx := "nil"
+			for i, prm := range m.params {
+				if i == 0 && prm.typ == "context.Context" {
+					ctx = prm.name
+				} else {
+					args = append(args, prm.name)
+				}
+				if prm.variadic {
+					callArgs = append(callArgs, prm.name+"...")
+				} else {
+					callArgs = append(callArgs, prm.name)
+				}
+			}
+			p("\tm.record(%q, %q, %s%s)", svc.name, m.name, ctx, prefixed(", ", args))
+			p("\tif m.%sFunc == nil {", m.name)
+			var zeros []string
+			for _, res := range m.results {
+				zeros = append(zeros, zero(res))
+			}
+			zeros[len(zeros)-1] = fmt.Sprintf("notMocked(%q, %q)", svc.name, m.name)
+			p("\t\treturn %s", strings.Join(zeros, ", "))
+			p("\t}")
+			p("\treturn m.%sFunc(%s)", m.name, strings.Join(callArgs, ", "))
+			p("}")
+		}
+	}
+	return b.Bytes()
+}
+
+func (m *method) signature() string {
+	var params []string
+	for _, prm := range m.params {
+		if prm.variadic {
+			params = append(params, prm.name+" ..."+prm.typ)
+		} else {
+			params = append(params, prm.name+" "+prm.typ)
+		}
+	}
+	if m.results[len(m.results)-1] != "error" {
+		log.Fatalf("method %s must return an error", m.name)
+	}
+	results := strings.Join(m.results, ", ")
+	if len(m.results) > 1 {
+		results = "(" + results + ")"
+	}
+	return "(" + strings.Join(params, ", ") + ") " + results
+}
+
+func zero(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "*"), strings.HasPrefix(typ, "[]"), strings.HasPrefix(typ, "map["),
+		strings.Contains(typ, "["), typ == "error":
+		return "nil"
+	case typ == "string":
+		return `""`
+	case typ == "bool":
+		return "false"
+	default:
+		return "*new(" + typ + ")"
+	}
+}
+
+func prefixed(prefix string, items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return prefix + strings.Join(items, ", ")
+}
@@ -0,0 +1,196 @@
+// Package cozemock provides mocks of the services of the Coze API, to test the code calling a
+// coze.CozeAPI without a server.
+//
+// Each mock records its calls, and returns the results of its functions, one per method:
+//
+//	mocks := cozemock.New()
+//	mocks.Chat.CreateFunc = func(ctx context.Context, req *coze.CreateChatsReq, opts ...coze.CallOption) (*coze.CreateChatsResp, error) {
+//		return &coze.CreateChatsResp{Chat: coze.Chat{ID: "chat_1"}}, nil
+//	}
+//	api := mocks.CozeAPI()
+//	// ... code under test calling api.Chat.Create ...
+//	calls := mocks.Chat.CallsTo("Create")
+//
+// A method whose function is not set fails with ErrNotMocked. The mocks are safe for concurrent
+// use, as long as their functions are set before.
+//
+// The fields of coze.CozeAPI grouping a service with its sub-services, such as Chat, are structs
+// embedding the service interfaces. A mock replaces a single service of an existing client
+// through the embedded field:
+//
+//	api := coze.NewCozeAPI(auth)
+//	api.Chat.ChatService = mocks.Chat
+//	api.Chat.Messages = mocks.ChatMessages
+package cozemock
+
+//go:generate go run gen.go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// ErrNotMocked is returned by the methods of the mocks whose function is not set.
+var ErrNotMocked = errors.New("cozemock: method not mocked")
+
+func notMocked(service, method string) error {
+	return fmt.Errorf("%w: %s.%s", ErrNotMocked, service, method)
+}
+
+// Call is a call to a mock.
+type Call struct {
+	// Service and Method are the names of the interface and of the method called, such as
+	// "ChatService" and "Create".
+	Service string
+	Method  string
+
+	// Ctx is the context of the call.
+	Ctx context.Context
+
+	// Args are the other arguments of the call, the options included as a []coze.CallOption.
+	Args []interface{}
+}
+
+// callLog records the calls of several mocks in order.
+type callLog struct {
+	mu    sync.Mutex
+	calls []*Call
+}
+
+func (l *callLog) add(call *Call) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, call)
+}
+
+func (l *callLog) list(method string) []*Call {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var res []*Call
+	for _, call := range l.calls {
+		if method == "" || call.Method == method {
+			res = append(res, call)
+		}
+	}
+	return res
+}
+
+func (l *callLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = nil
+}
+
+// mock records the calls of a mock, and forwards them to the log of its API, if any.
+type mock struct {
+	calls callLog
+	api   *callLog
+}
+
+func (m *mock) record(service, method string, ctx context.Context, args ...interface{}) {
+	call := &Call{Service: service, Method: method, Ctx: ctx, Args: args}
+	m.calls.add(call)
+	if m.api != nil {
+		m.api.add(call)
+	}
+}
+
+// Calls returns the calls to the mock, in order.
+func (m *mock) Calls() []*Call {
+	return m.calls.list("")
+}
+
+// CallsTo returns the calls to a method of the mock, in order.
+func (m *mock) CallsTo(method string) []*Call {
+	return m.calls.list(method)
+}
+
+// Reset forgets the calls to the mock.
+func (m *mock) Reset() {
+	m.calls.reset()
+}
+
+// API is a mock of every service of the Coze API.
+type API struct {
+	Audio struct {
+		Rooms  *AudioRoomsService
+		Speech *AudioSpeechService
+		Voices *AudioVoicesService
+	}
+	Bots                  *BotsService
+	Chat                  *ChatService
+	ChatMessages          *ChatMessagesService
+	Conversations         *ConversationsService
+	ConversationMessages  *ConversationMessagesService
+	Datasets              *DatasetsService
+	DatasetDocuments      *DatasetDocumentsService
+	DatasetImages         *DatasetImagesService
+	Files                 *FilesService
+	Templates             *TemplatesService
+	Users                 *UsersService
+	WorkflowRuns          *WorkflowRunsService
+	WorkflowRunsHistories *WorkflowRunsHistoriesService
+	WorkflowsChat         *WorkflowsChatService
+	Workspaces            *WorkspacesService
+
+	calls callLog
+}
+
+// New returns the mocks of all the services, which record their calls in the log of the API too.
+func New() *API {
+	a := &API{
+		Bots:                  &BotsService{},
+		Chat:                  &ChatService{},
+		ChatMessages:          &ChatMessagesService{},
+		Conversations:         &ConversationsService{},
+		ConversationMessages:  &ConversationMessagesService{},
+		Datasets:              &DatasetsService{},
+		DatasetDocuments:      &DatasetDocumentsService{},
+		DatasetImages:         &DatasetImagesService{},
+		Files:                 &FilesService{},
+		Templates:             &TemplatesService{},
+		Users:                 &UsersService{},
+		WorkflowRuns:          &WorkflowRunsService{},
+		WorkflowRunsHistories: &WorkflowRunsHistoriesService{},
+		WorkflowsChat:         &WorkflowsChatService{},
+		Workspaces:            &WorkspacesService{},
+	}
+	a.Audio.Rooms, a.Audio.Speech, a.Audio.Voices = &AudioRoomsService{}, &AudioSpeechService{}, &AudioVoicesService{}
+	for _, m := range []*mock{
+		&a.Audio.Rooms.mock, &a.Audio.Speech.mock, &a.Audio.Voices.mock, &a.Bots.mock, &a.Chat.mock,
+		&a.ChatMessages.mock, &a.Conversations.mock, &a.ConversationMessages.mock, &a.Datasets.mock,
+		&a.DatasetDocuments.mock, &a.DatasetImages.mock, &a.Files.mock, &a.Templates.mock, &a.Users.mock,
+		&a.WorkflowRuns.mock, &a.WorkflowRunsHistories.mock, &a.WorkflowsChat.mock, &a.Workspaces.mock,
+	} {
+		m.api = &a.calls
+	}
+	return a
+}
+
+// CozeAPI returns a client calling the mocks.
+func (a *API) CozeAPI() coze.CozeAPI {
+	return coze.CozeAPI{
+		Audio:         &coze.AudioAPI{Rooms: a.Audio.Rooms, Speech: a.Audio.Speech, Voices: a.Audio.Voices},
+		Bots:          a.Bots,
+		Chat:          &coze.ChatAPI{ChatService: a.Chat, Messages: a.ChatMessages},
+		Conversations: &coze.ConversationsAPI{ConversationsService: a.Conversations, Messages: a.ConversationMessages},
+		Workflows: &coze.WorkflowsAPI{
+			Runs: &coze.WorkflowRunsAPI{WorkflowRunsService: a.WorkflowRuns, Histories: a.WorkflowRunsHistories},
+			Chat: a.WorkflowsChat,
+		},
+		Workspaces: a.Workspaces,
+		Datasets:   &coze.DatasetsAPI{DatasetsService: a.Datasets, Documents: a.DatasetDocuments, Images: a.DatasetImages},
+		Files:      a.Files,
+		Templates:  a.Templates,
+		Users:      a.Users,
+	}
+}
+
+// Calls returns the calls to all the mocks, in order.
+func (a *API) Calls() []*Call {
+	return a.calls.list("")
+}
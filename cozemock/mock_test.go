@@ -0,0 +1,98 @@
+package cozemock
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+type ctxKey struct{}
+
+func TestAPI(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	t.Run("programmed", func(t *testing.T) {
+		mocks := New()
+		mocks.Chat.CreateFunc = func(ctx context.Context, req *coze.CreateChatsReq, opts ...coze.CallOption) (*coze.CreateChatsResp, error) {
+			return &coze.CreateChatsResp{Chat: coze.Chat{ID: "chat_1", BotID: req.BotID}}, nil
+		}
+		mocks.ChatMessages.ListFunc = func(ctx context.Context, req *coze.ListChatsMessagesReq, opts ...coze.CallOption) (*coze.ListChatsMessagesResp, error) {
+			return &coze.ListChatsMessagesResp{Messages: []*coze.Message{{Content: "hi"}}}, nil
+		}
+		api := mocks.CozeAPI()
+
+		chat, err := api.Chat.Create(ctx, &coze.CreateChatsReq{BotID: "bot"})
+		require.NoError(t, err)
+		assert.Equal(t, "chat_1", chat.Chat.ID)
+		assert.Equal(t, "bot", chat.Chat.BotID)
+		messages, err := api.Chat.Messages.List(ctx, &coze.ListChatsMessagesReq{ChatID: chat.Chat.ID})
+		require.NoError(t, err)
+		assert.Equal(t, "hi", messages.Messages[0].Content)
+
+		calls := mocks.Chat.CallsTo("Create")
+		require.Len(t, calls, 1)
+		assert.Equal(t, "ChatService", calls[0].Service)
+		assert.Equal(t, "value", calls[0].Ctx.Value(ctxKey{}))
+		require.Len(t, calls[0].Args, 2)
+		assert.Equal(t, "bot", calls[0].Args[0].(*coze.CreateChatsReq).BotID)
+		assert.Empty(t, calls[0].Args[1])
+		assert.Empty(t, mocks.Chat.CallsTo("Retrieve"))
+
+		all := mocks.Calls()
+		require.Len(t, all, 2)
+		assert.Equal(t, "Create", all[0].Method)
+		assert.Equal(t, "ChatMessagesService", all[1].Service)
+
+		mocks.Chat.Reset()
+		assert.Empty(t, mocks.Chat.Calls())
+		assert.Len(t, mocks.Calls(), 2)
+	})
+
+	t.Run("not mocked", func(t *testing.T) {
+		mocks := New()
+		api := mocks.CozeAPI()
+
+		_, err := api.Workflows.Runs.Histories.Retrieve(ctx, &coze.RetrieveWorkflowsRunsHistoriesReq{})
+		require.ErrorIs(t, err, ErrNotMocked)
+		assert.Contains(t, err.Error(), "WorkflowRunsHistoriesService.Retrieve")
+		_, err = api.Audio.Voices.List(ctx, &coze.ListAudioVoicesReq{})
+		require.ErrorIs(t, err, ErrNotMocked)
+		assert.Len(t, mocks.Audio.Voices.Calls(), 1)
+	})
+
+	t.Run("chat session", func(t *testing.T) {
+		mocks := New()
+		mocks.Conversations.CreateFunc = func(ctx context.Context, req *coze.CreateConversationsReq, opts ...coze.CallOption) (*coze.CreateConversationsResp, error) {
+			return &coze.CreateConversationsResp{Conversation: coze.Conversation{ID: "conv_1"}}, nil
+		}
+		mocks.Chat.CreateAndPollFunc = func(ctx context.Context, req *coze.CreateChatsReq, pollOpts *coze.ChatPollOptions, opts ...coze.CallOption) (*coze.ChatPoll, error) {
+			return &coze.ChatPoll{Chat: &coze.Chat{ConversationID: req.ConversationID}}, nil
+		}
+		session := coze.NewChatSession(mocks.CozeAPI(), coze.NewChatSessionParam{BotID: "bot", UserID: "user"})
+
+		poll, err := session.Send(ctx, "hi", coze.WithCallHeader("X-Test", "1"))
+		require.NoError(t, err)
+		assert.Equal(t, "conv_1", poll.Chat.ConversationID)
+		calls := mocks.Conversations.CallsTo("Create")
+		require.Len(t, calls, 1)
+		assert.Len(t, calls[0].Args[1], 1, "the options of the chat apply to the creation of the conversation")
+	})
+
+	t.Run("single service of a client", func(t *testing.T) {
+		mocks := New()
+		mocks.Chat.CreateFunc = func(ctx context.Context, req *coze.CreateChatsReq, opts ...coze.CallOption) (*coze.CreateChatsResp, error) {
+			return &coze.CreateChatsResp{Chat: coze.Chat{ID: "chat_1"}}, nil
+		}
+		api := coze.NewCozeAPI(coze.NewTokenAuth("token"))
+		api.Chat.ChatService = mocks.Chat
+
+		chat, err := api.Chat.Create(ctx, &coze.CreateChatsReq{BotID: "bot"})
+		require.NoError(t, err)
+		assert.Equal(t, "chat_1", chat.Chat.ID)
+		assert.Len(t, mocks.Chat.CallsTo("Create"), 1)
+	})
+}
@@ -0,0 +1,101 @@
+package cozemock
+
+import (
+	"io"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// LogID is the log ID of the responses of the streams and pages built by the helpers.
+const LogID = "cozemock_log_id"
+
+type response struct{}
+
+func (response) LogID() string { return LogID }
+
+// sliceStream is a stream returning the events of a slice, then its error.
+type sliceStream[T any] struct {
+	events []*T
+	err    error
+	closed bool
+}
+
+func (s *sliceStream[T]) Response() coze.HTTPResponse {
+	return response{}
+}
+
+func (s *sliceStream[T]) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *sliceStream[T]) Recv() (*T, error) {
+	if s.closed {
+		return nil, io.EOF
+	}
+	if len(s.events) == 0 {
+		return nil, s.err
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+// NewChatStream returns a stream of the chat events, for the results of the Stream and
+// StreamSubmitToolOutputs functions of ChatService. Once the events are received, Recv returns
+// err, or io.EOF if it is nil.
+func NewChatStream(events []*coze.ChatEvent, err error) coze.Stream[coze.ChatEvent] {
+	return newSliceStream(events, err)
+}
+
+// NewWorkflowStream returns a stream of the workflow events, for the results of the Stream and
+// Resume functions of WorkflowRunsService. Once the events are received, Recv returns err, or
+// io.EOF if it is nil.
+func NewWorkflowStream(events []*coze.WorkflowEvent, err error) coze.Stream[coze.WorkflowEvent] {
+	return newSliceStream(events, err)
+}
+
+func newSliceStream[T any](events []*T, err error) *sliceStream[T] {
+	if err == nil {
+		err = io.EOF
+	}
+	return &sliceStream[T]{events: events, err: err}
+}
+
+// slicePaged is a single page holding the items of a slice.
+type slicePaged[T any] struct {
+	items []*T
+	index int
+	cur   *T
+}
+
+func (p *slicePaged[T]) Err() error    { return nil }
+func (p *slicePaged[T]) Items() []*T   { return p.items }
+func (p *slicePaged[T]) Current() *T   { return p.cur }
+func (p *slicePaged[T]) HasMore() bool { return false }
+func (p *slicePaged[T]) Total() int    { return len(p.items) }
+
+func (p *slicePaged[T]) GetLastID() string {
+	return ""
+}
+
+func (p *slicePaged[T]) Next() bool {
+	if p.index >= len(p.items) {
+		return false
+	}
+	p.cur = p.items[p.index]
+	p.index++
+	return true
+}
+
+// NewNumberPaged returns a single page of the items, for the results of the List functions
+// returning a coze.NumberPaged.
+func NewNumberPaged[T any](items []*T) coze.NumberPaged[T] {
+	return &slicePaged[T]{items: items}
+}
+
+// NewLastIDPaged returns a single page of the items, for the results of the List functions
+// returning a coze.LastIDPaged.
+func NewLastIDPaged[T any](items []*T) coze.LastIDPaged[T] {
+	return &slicePaged[T]{items: items}
+}
@@ -0,0 +1,69 @@
+package cozemock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+func TestNewChatStream(t *testing.T) {
+	ctx := context.Background()
+	mocks := New()
+	mocks.Chat.StreamFunc = func(ctx context.Context, req *coze.CreateChatsReq, opts ...coze.CallOption) (coze.Stream[coze.ChatEvent], error) {
+		return NewChatStream([]*coze.ChatEvent{
+			{Event: coze.ChatEventConversationMessageDelta, Message: &coze.Message{ID: "m1", Content: "Hel"}},
+			{Event: coze.ChatEventConversationMessageDelta, Message: &coze.Message{ID: "m1", Content: "lo"}},
+			{Event: coze.ChatEventDone},
+		}, nil), nil
+	}
+	api := mocks.CozeAPI()
+
+	stream, err := api.Chat.Stream(ctx, &coze.CreateChatsReq{})
+	require.NoError(t, err)
+	assert.Equal(t, LogID, stream.Response().LogID())
+	accumulator := coze.NewChatStreamAccumulator()
+	_, err = accumulator.Consume(ctx, stream)
+	require.NoError(t, err)
+	require.Len(t, accumulator.Messages(), 1)
+	assert.Equal(t, "Hello", accumulator.Messages()[0].Content)
+	assert.True(t, accumulator.Done())
+}
+
+func TestNewWorkflowStream(t *testing.T) {
+	failure := errors.New("broken")
+	stream := NewWorkflowStream([]*coze.WorkflowEvent{{ID: 1}}, failure)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, 1, event.ID)
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, failure)
+
+	stream = NewWorkflowStream([]*coze.WorkflowEvent{{ID: 1}}, nil)
+	require.NoError(t, stream.Close())
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestNewPaged(t *testing.T) {
+	numbers := NewNumberPaged([]*coze.Voice{{Name: "a"}, {Name: "b"}})
+	var names []string
+	for numbers.Next() {
+		names = append(names, numbers.Current().Name)
+	}
+	require.NoError(t, numbers.Err())
+	assert.Equal(t, []string{"a", "b"}, names)
+	assert.Equal(t, 2, numbers.Total())
+	assert.False(t, numbers.HasMore())
+
+	lastIDs := NewLastIDPaged([]*coze.Workspace{})
+	assert.False(t, lastIDs.Next())
+	assert.Nil(t, lastIDs.Current())
+	assert.Empty(t, lastIDs.GetLastID())
+}
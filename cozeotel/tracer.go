@@ -0,0 +1,120 @@
+// Package cozeotel adapts OpenTelemetry to the tracing interface of the Coze SDK.
+//
+//	api := coze.NewCozeAPI(auth, coze.WithTracer(cozeotel.NewTracer()))
+package cozeotel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/coze-dev/coze-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/coze-dev/coze-go/cozeotel"
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// Option configures the tracer.
+type Option func(*options)
+
+// WithTracerProvider sets the tracer provider, defaults to otel.GetTracerProvider()
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(opt *options) {
+		opt.tracerProvider = provider
+	}
+}
+
+// WithPropagator sets the propagator writing the trace context into the request headers, defaults
+// to the W3C trace context propagator
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(opt *options) {
+		opt.propagator = propagator
+	}
+}
+
+type tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer returns a coze.Tracer creating OpenTelemetry client spans.
+func NewTracer(opts ...Option) coze.Tracer {
+	opt := &options{}
+	for _, o := range opts {
+		o(opt)
+	}
+	if opt.tracerProvider == nil {
+		opt.tracerProvider = otel.GetTracerProvider()
+	}
+	if opt.propagator == nil {
+		opt.propagator = propagation.TraceContext{}
+	}
+	return &tracer{
+		tracer:     opt.tracerProvider.Tracer(instrumentationName),
+		propagator: opt.propagator,
+	}
+}
+
+// Start ...
+func (t *tracer) Start(ctx context.Context, name string, attrs ...coze.Attribute) (context.Context, coze.Span) {
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(toAttributes(attrs)...),
+	)
+	return ctx, &otelSpan{span: span}
+}
+
+// Inject ...
+func (t *tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+// SetAttributes ...
+func (s *otelSpan) SetAttributes(attrs ...coze.Attribute) {
+	s.span.SetAttributes(toAttributes(attrs)...)
+}
+
+// RecordError ...
+func (s *otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End ...
+func (s *otelSpan) End() {
+	s.span.End()
+}
+
+func toAttributes(attrs []coze.Attribute) []attribute.KeyValue {
+	res := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			res = append(res, attribute.String(attr.Key, v))
+		case bool:
+			res = append(res, attribute.Bool(attr.Key, v))
+		case int:
+			res = append(res, attribute.Int(attr.Key, v))
+		case int64:
+			res = append(res, attribute.Int64(attr.Key, v))
+		case float64:
+			res = append(res, attribute.Float64(attr.Key, v))
+		default:
+			res = append(res, attribute.String(attr.Key, fmt.Sprint(v)))
+		}
+	}
+	return res
+}
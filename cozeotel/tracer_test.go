@@ -0,0 +1,96 @@
+package cozeotel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/coze-dev/coze-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Tt-Logid", "test_log_id")
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(data)),
+	}, nil
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	res := map[attribute.Key]attribute.Value{}
+	for _, attr := range span.Attributes() {
+		res[attr.Key] = attr.Value
+	}
+	return res
+}
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	var traceparent string
+	api := coze.NewCozeAPI(coze.NewTokenAuth("token"),
+		coze.WithTracer(NewTracer(WithTracerProvider(provider))),
+		coze.WithHttpClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			traceparent = req.Header.Get("traceparent")
+			if req.URL.Path == "/v1/users/me" {
+				return jsonResponse(map[string]interface{}{"code": 4000, "msg": "invalid param"})
+			}
+			return jsonResponse(map[string]interface{}{"code": 0, "data": map[string]string{"id": "chat1"}})
+		})}),
+	)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	_, err := api.Chat.Retrieve(ctx, &coze.RetrieveChatsReq{ConversationID: "conv1", ChatID: "chat1"})
+	require.NoError(t, err)
+	_, err = api.Users.Me(ctx)
+	require.Error(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	retrieve := spans[0]
+	assert.Equal(t, "chat.retrieve", retrieve.Name())
+	assert.Equal(t, trace.SpanKindClient, retrieve.SpanKind())
+	assert.Equal(t, parent.SpanContext().TraceID(), retrieve.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanContext().SpanID(), retrieve.Parent().SpanID())
+	attrs := attributes(retrieve)
+	assert.Equal(t, "GET", attrs[coze.AttrHTTPMethod].AsString())
+	assert.Equal(t, "/v3/chat/retrieve", attrs[coze.AttrURLPath].AsString())
+	assert.Equal(t, int64(http.StatusOK), attrs[coze.AttrHTTPStatusCode].AsInt64())
+	assert.Equal(t, "test_log_id", attrs[coze.AttrLogID].AsString())
+	assert.Equal(t, codes.Unset, retrieve.Status().Code)
+
+	me := spans[1]
+	assert.Equal(t, "users.me", me.Name())
+	assert.Equal(t, int64(4000), attributes(me)[coze.AttrCode].AsInt64())
+	assert.Equal(t, codes.Error, me.Status().Code)
+	require.Len(t, me.Events(), 1)
+
+	// the trace context of the last call is propagated in the W3C format
+	assert.Equal(t, "00-"+me.SpanContext().TraceID().String()+"-"+me.SpanContext().SpanID().String()+"-01", traceparent)
+}
@@ -0,0 +1,298 @@
+package cozetest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Cassette is the content of a cassette file: the interactions recorded in the order of the
+// requests.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  *RecordedRequest  `json:"request"`
+	Response *RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded request, secrets redacted.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   *Body       `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded response, secrets redacted. The body of a server-sent events
+// stream is recorded as Events, the other ones as Body. Error is the error the transport or the
+// body failed with, if any.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       *Body       `json:"body,omitempty"`
+	Events     []*SSEChunk `json:"events,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Body is a recorded body, as text if it is valid UTF-8, in base64 otherwise.
+type Body struct {
+	Text   string `json:"text,omitempty"`
+	Base64 []byte `json:"base64,omitempty"`
+}
+
+// SSEChunk is an event of a server-sent events stream, with its blank line.
+type SSEChunk struct {
+	// Delay is the time elapsed since the previous event, or since the response for the first one.
+	Delay time.Duration `json:"delay"`
+	Data  string        `json:"data"`
+}
+
+func newBody(data []byte) *Body {
+	if len(data) == 0 {
+		return nil
+	}
+	if utf8.Valid(data) {
+		return &Body{Text: string(data)}
+	}
+	return &Body{Base64: data}
+}
+
+// Bytes returns the content of the body.
+func (b *Body) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	if b.Base64 != nil {
+		return b.Base64
+	}
+	return []byte(b.Text)
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{}
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, fmt.Errorf("cozetest: invalid cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Save writes the cassette file, creating its directory if needed.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// sseEventEnd returns the length of the first complete event of a stream, blank line included, or
+// -1 if the data has no blank line yet.
+func sseEventEnd(data []byte) int {
+	end := -1
+	for _, sep := range []string{"\r\n\r\n", "\n\n", "\r\r"} {
+		if i := bytes.Index(data, []byte(sep)); i >= 0 && (end < 0 || i+len(sep) < end) {
+			end = i + len(sep)
+		}
+	}
+	return end
+}
+
+func isEventStream(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+}
+
+// requestKey is what the requests are matched on: method, path, sorted query and normalized body.
+type requestKey struct {
+	method string
+	path   string
+	query  string
+	body   string
+}
+
+func (k requestKey) String() string {
+	s := k.method + " " + k.path
+	if k.query != "" {
+		s += "?" + k.query
+	}
+	return s
+}
+
+func newRequestKey(method, path, query string, header http.Header, body []byte) requestKey {
+	return requestKey{
+		method: method,
+		path:   path,
+		query:  normalizeQuery(query),
+		body:   normalizeBody(header.Get("Content-Type"), body),
+	}
+}
+
+func normalizeQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+	// url.Values.Encode sorts by key
+	return values.Encode()
+}
+
+// normalizeBody returns a form of the body independent of the formatting of the JSON bodies and of
+// the boundaries of the multipart bodies.
+func normalizeBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" {
+		if normalized, err := normalizeMultipart(body, params["boundary"]); err == nil {
+			return normalized
+		}
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err == nil {
+		// maps are marshalled with sorted keys
+		normalized, _ := json.Marshal(value)
+		return string(normalized)
+	}
+	return string(body)
+}
+
+func normalizeMultipart(body []byte, boundary string) (string, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	var parts []string
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		parts = append(parts, part.FormName()+"|"+part.FileName()+"|"+hex.EncodeToString(sum[:]))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "\n"), nil
+}
+
+// redactor replaces the secrets of the recorded headers and JSON bodies.
+type redactor struct {
+	headers map[string]bool
+	fields  map[string]bool
+}
+
+// Redacted replaces the secrets in the cassettes.
+const Redacted = "REDACTED"
+
+var (
+	defaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+	defaultRedactedFields  = []string{"access_token", "refresh_token", "client_secret", "private_key", "code_verifier"}
+)
+
+func newRedactor(headers, fields []string) *redactor {
+	r := &redactor{headers: map[string]bool{}, fields: map[string]bool{}}
+	for _, header := range append(defaultRedactedHeaders, headers...) {
+		r.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	for _, field := range append(defaultRedactedFields, fields...) {
+		r.fields[strings.ToLower(field)] = true
+	}
+	return r
+}
+
+func (r *redactor) header(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	res := header.Clone()
+	for key, values := range res {
+		if r.headers[http.CanonicalHeaderKey(key)] {
+			for i := range values {
+				values[i] = Redacted
+			}
+		}
+	}
+	return res
+}
+
+// body redacts the string fields of a JSON body, other bodies are returned as is.
+func (r *redactor) body(data []byte) []byte {
+	var value interface{}
+	if len(data) == 0 || json.Unmarshal(data, &value) != nil {
+		return data
+	}
+	if !r.walk(value) {
+		return data
+	}
+	res, err := json.Marshal(value)
+	if err != nil {
+		return data
+	}
+	return res
+}
+
+// walk redacts the value in place, and reports whether it redacted something.
+func (r *redactor) walk(value interface{}) bool {
+	redacted := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if _, ok := child.(string); ok && r.fields[strings.ToLower(key)] {
+				v[key] = Redacted
+				redacted = true
+				continue
+			}
+			redacted = r.walk(child) || redacted
+		}
+	case []interface{}:
+		for _, child := range v {
+			redacted = r.walk(child) || redacted
+		}
+	}
+	return redacted
+}
+
+// sseEvents redacts the data of every event of a stream.
+func (r *redactor) sseEvents(data []byte) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		content := strings.TrimRight(line, "\r\n")
+		if !strings.HasPrefix(content, "data:") {
+			continue
+		}
+		value := strings.TrimPrefix(strings.TrimPrefix(content, "data:"), " ")
+		if redacted := r.body([]byte(value)); string(redacted) != value {
+			lines[i] = "data: " + string(redacted) + line[len(content):]
+		}
+	}
+	return []byte(strings.Join(lines, ""))
+}
@@ -0,0 +1,426 @@
+// Package cozetest records the HTTP interactions of a Coze client to cassette files, and replays
+// them in tests without a network or credentials.
+//
+// A test records its cassette once against the real API:
+//
+//	rec, err := cozetest.NewRecorder("testdata/chat.json", cozetest.WithMode(cozetest.ModeRecord))
+//	api := coze.NewCozeAPI(coze.NewTokenAuth(token), coze.WithTransport(rec))
+//	// ... calls ...
+//	err = rec.Stop()
+//
+// and then replays it, the default mode, with any token. The secrets, such as the Authorization
+// header and the tokens of the OAuth responses, are redacted from the cassettes.
+//
+// For the tests which need no recording, Server fakes the Coze API in memory, with scriptable bot
+// replies, workflow runs, failures and latency:
+//
+//	server := cozetest.NewServer()
+//	defer server.Close()
+//	server.AddReplies("bot", &cozetest.Reply{Content: "Hello"})
+//	api := server.NewAPI()
+package cozetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode is the mode of a Recorder.
+type Mode int
+
+const (
+	// ModeReplay serves the responses of the cassette, the requests never reach the network.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests and records the interactions to the cassette.
+	ModeRecord
+)
+
+// RecorderOption configures a Recorder.
+type RecorderOption func(*Recorder)
+
+// WithMode sets the mode of the recorder, ModeReplay by default.
+func WithMode(mode Mode) RecorderOption {
+	return func(r *Recorder) {
+		r.mode = mode
+	}
+}
+
+// WithTransport sets the transport the requests are sent with in record mode,
+// http.DefaultTransport by default.
+func WithTransport(transport http.RoundTripper) RecorderOption {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// WithRedactHeaders redacts the given headers, in addition to Authorization, Cookie, Set-Cookie and
+// Proxy-Authorization.
+func WithRedactHeaders(headers ...string) RecorderOption {
+	return func(r *Recorder) {
+		r.redactHeaders = append(r.redactHeaders, headers...)
+	}
+}
+
+// WithRedactFields redacts the string fields with the given names of the JSON bodies, in addition
+// to access_token, refresh_token, client_secret, private_key and code_verifier.
+func WithRedactFields(fields ...string) RecorderOption {
+	return func(r *Recorder) {
+		r.redactFields = append(r.redactFields, fields...)
+	}
+}
+
+// WithRealtime replays the events of the streams with their recorded delays, instead of all at
+// once.
+func WithRealtime() RecorderOption {
+	return func(r *Recorder) {
+		r.realtime = true
+	}
+}
+
+// Recorder is an http.RoundTripper recording the interactions to a cassette, or replaying them,
+// depending on its mode. In replay mode, a request is matched on its method, path, query and
+// body, JSON bodies being compared regardless of their formatting and multipart bodies regardless
+// of their boundary. Each recorded interaction is served once, in the order of the cassette.
+type Recorder struct {
+	path          string
+	mode          Mode
+	transport     http.RoundTripper
+	redactHeaders []string
+	redactFields  []string
+	realtime      bool
+	redactor      *redactor
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewRecorder creates a recorder of the cassette file at path. In replay mode, the cassette must
+// exist.
+func NewRecorder(path string, opts ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{path: path, transport: http.DefaultTransport}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.redactor = newRedactor(r.redactHeaders, r.redactFields)
+	if r.mode == ModeRecord {
+		r.cassette = &Cassette{}
+		return r, nil
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	r.cassette = cassette
+	r.used = make([]bool, len(cassette.Interactions))
+	return r, nil
+}
+
+// Mode returns the mode of the recorder.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Cassette returns the interactions recorded or loaded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]*Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Stop writes the cassette in record mode. The streams must be closed before, the events of a
+// stream still open would be missing. In replay mode, it does nothing.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
+
+// RoundTrip records or replays the request, see Recorder.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	if r.mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cozetest: read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	interaction := &Interaction{
+		Request: &RecordedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Query:  req.URL.RawQuery,
+			Header: r.redactor.header(req.Header),
+			Body:   newBody(r.redactor.body(body)),
+		},
+		Response: &RecordedResponse{},
+	}
+	// the interaction is added before the response, to keep the order of the requests
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	start := time.Now()
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		r.mu.Lock()
+		interaction.Response.Error = err.Error()
+		r.mu.Unlock()
+		return nil, err
+	}
+
+	r.mu.Lock()
+	interaction.Response.StatusCode = resp.StatusCode
+	interaction.Response.Header = r.redactor.header(resp.Header)
+	r.mu.Unlock()
+	if isEventStream(resp.Header) {
+		resp.Body = &recordingStreamBody{body: resp.Body, recorder: r, response: interaction.Response, last: start}
+		return resp, nil
+	}
+
+	data, readErr := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	r.mu.Lock()
+	interaction.Response.Body = newBody(r.redactor.body(data))
+	if readErr != nil {
+		interaction.Response.Error = readErr.Error()
+	}
+	r.mu.Unlock()
+	resp.Body = &replayBody{reader: bytes.NewReader(data), err: readErr}
+	return resp, nil
+}
+
+// recordingStreamBody records the events of a stream as they are read, with their timing.
+type recordingStreamBody struct {
+	body     io.ReadCloser
+	recorder *Recorder
+	response *RecordedResponse
+	// mu guards the fields below, Close being called concurrently with Read on timeouts
+	mu   sync.Mutex
+	buf  []byte
+	last time.Time
+	done bool
+}
+
+func (b *recordingStreamBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return n, err
+	}
+	b.buf = append(b.buf, p[:n]...)
+	for {
+		end := sseEventEnd(b.buf)
+		if end < 0 {
+			break
+		}
+		b.addEvent(b.buf[:end])
+		b.buf = b.buf[end:]
+	}
+	if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *recordingStreamBody) Close() error {
+	b.mu.Lock()
+	b.finish(nil)
+	b.mu.Unlock()
+	return b.body.Close()
+}
+
+func (b *recordingStreamBody) addEvent(data []byte) {
+	now := time.Now()
+	event := &SSEChunk{Delay: now.Sub(b.last), Data: string(b.recorder.redactor.sseEvents(data))}
+	b.last = now
+	b.recorder.mu.Lock()
+	b.response.Events = append(b.response.Events, event)
+	b.recorder.mu.Unlock()
+}
+
+// finish records the end of the stream which did not end with a blank line, and the error the
+// stream failed with, if any.
+func (b *recordingStreamBody) finish(err error) {
+	if b.done {
+		return
+	}
+	b.done = true
+	if len(b.buf) > 0 {
+		b.addEvent(b.buf)
+		b.buf = nil
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.recorder.mu.Lock()
+		b.response.Error = err.Error()
+		b.recorder.mu.Unlock()
+	}
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	key := newRequestKey(req.Method, req.URL.Path, req.URL.RawQuery, req.Header, r.redactor.body(body))
+
+	r.mu.Lock()
+	var interaction *Interaction
+	samePath := 0
+	for i, candidate := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		recorded := candidate.Request
+		candidateKey := newRequestKey(recorded.Method, recorded.Path, recorded.Query, recorded.Header, recorded.Body.Bytes())
+		if candidateKey == key {
+			r.used[i] = true
+			interaction = candidate
+			break
+		}
+		if candidateKey.method == key.method && candidateKey.path == key.path {
+			samePath++
+		}
+	}
+	r.mu.Unlock()
+
+	if interaction == nil {
+		if samePath > 0 {
+			return nil, fmt.Errorf("cozetest: no recorded interaction for %s in %s, %d unused with another query or body",
+				key, r.path, samePath)
+		}
+		return nil, fmt.Errorf("cozetest: no recorded interaction for %s in %s", key, r.path)
+	}
+
+	recorded := interaction.Response
+	replayErr := replayError(recorded.Error)
+	if recorded.StatusCode == 0 && replayErr != nil {
+		return nil, replayErr
+	}
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode: recorded.StatusCode,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     recorded.Header.Clone(),
+		Request:    req,
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if recorded.Events != nil {
+		resp.ContentLength = -1
+		resp.Body = &replayStreamBody{
+			req: req, events: recorded.Events, realtime: r.realtime, err: replayErr, closed: make(chan struct{}),
+		}
+		return resp, nil
+	}
+	data := recorded.Body.Bytes()
+	resp.ContentLength = int64(len(data))
+	resp.Body = &replayBody{reader: bytes.NewReader(data), err: replayErr}
+	return resp, nil
+}
+
+var errBodyClosed = errors.New("cozetest: read on closed body")
+
+// replayError returns the recorded error, as the io error it was when it is one.
+func replayError(msg string) error {
+	switch msg {
+	case "":
+		return nil
+	case io.ErrUnexpectedEOF.Error():
+		return io.ErrUnexpectedEOF
+	}
+	return errors.New(msg)
+}
+
+// replayBody serves a recorded body, then fails with its recorded error if any.
+type replayBody struct {
+	reader *bytes.Reader
+	err    error
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if errors.Is(err, io.EOF) && b.err != nil {
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *replayBody) Close() error {
+	return nil
+}
+
+// replayStreamBody serves the recorded events of a stream, with their delays in realtime mode.
+type replayStreamBody struct {
+	req      *http.Request
+	events   []*SSEChunk
+	realtime bool
+	err      error
+	current  strings.Reader
+	closed   chan struct{}
+	close    sync.Once
+}
+
+func (b *replayStreamBody) Read(p []byte) (int, error) {
+	for b.current.Len() == 0 {
+		select {
+		case <-b.closed:
+			return 0, errBodyClosed
+		default:
+		}
+		if len(b.events) == 0 {
+			if b.err != nil {
+				return 0, b.err
+			}
+			return 0, io.EOF
+		}
+		event := b.events[0]
+		b.events = b.events[1:]
+		if b.realtime && event.Delay > 0 {
+			timer := time.NewTimer(event.Delay)
+			select {
+			case <-timer.C:
+			case <-b.req.Context().Done():
+				timer.Stop()
+				return 0, b.req.Context().Err()
+			case <-b.closed:
+				timer.Stop()
+				return 0, errBodyClosed
+			}
+		}
+		b.current.Reset(event.Data)
+	}
+	return b.current.Read(p)
+}
+
+// Close interrupts a pending Read, as closing a response body does.
+func (b *replayStreamBody) Close() error {
+	b.close.Do(func() { close(b.closed) })
+	return nil
+}
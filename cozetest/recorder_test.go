@@ -0,0 +1,261 @@
+package cozetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+const testToken = "pat_secret_token"
+
+var testAudio = []byte{0xff, 0xf3, 0x00, 0x01, 0x80, 0xfe}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	writeEvents := func(w http.ResponseWriter, events ...string) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			_, _ = io.WriteString(w, event)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer "+testToken, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/v3/chat":
+			writeEvents(w,
+				"event: conversation.chat.created\ndata: {\"id\":\"chat1\",\"conversation_id\":\"conv1\"}\n\n",
+				"event: conversation.message.delta\ndata: {\"id\":\"msg1\",\"content\":\"Hel\"}\n\n",
+				"event: conversation.message.delta\ndata: {\"id\":\"msg1\",\"content\":\"lo\"}\n\n",
+				"event: done\ndata: \"[DONE]\"\n\n",
+			)
+		case "/v1/workflow/stream_run":
+			writeEvents(w,
+				"id: 0\nevent: Message\ndata: {\"content\":\"result\",\"node_title\":\"End\",\"node_is_finish\":true}\n\n",
+				"id: 1\nevent: Done\ndata: {\"debug_url\":\"https://www.coze.com/work_flow?execute_id=1\"}\n\n",
+			)
+		case "/v1/audio/speech":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			_, _ = w.Write(testAudio)
+		case "/v1/files/upload":
+			file, header, err := r.FormFile("file")
+			require.NoError(t, err)
+			content, _ := io.ReadAll(file)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 0,
+				"data": map[string]interface{}{"id": "file1", "file_name": header.Filename, "bytes": len(content)},
+			})
+		case "/api/permission/oauth2/token":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"access_token":"at_secret","expires_in":900,"refresh_token":"rt_secret"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+type testResults struct {
+	messages []string
+	workflow []string
+	audio    []byte
+	file     *coze.UploadFilesResp
+}
+
+func runTestCalls(t *testing.T, api coze.CozeAPI) *testResults {
+	ctx := context.Background()
+	res := &testResults{}
+
+	chat, err := api.Chat.Stream(ctx, &coze.CreateChatsReq{
+		BotID: "bot1", UserID: "user1", Messages: []*coze.Message{coze.BuildUserQuestionText("Hi", nil)},
+	})
+	require.NoError(t, err)
+	require.NoError(t, (&coze.ChatEventHandler{
+		OnMessageDelta: func(event *coze.ChatEvent) error {
+			res.messages = append(res.messages, event.Message.Content)
+			return nil
+		},
+	}).Handle(ctx, chat))
+
+	workflow, err := api.Workflows.Runs.Stream(ctx, &coze.RunWorkflowsReq{WorkflowID: "wf1"})
+	require.NoError(t, err)
+	require.NoError(t, (&coze.WorkflowEventHandler{
+		OnEvent: func(event *coze.WorkflowEvent) error {
+			res.workflow = append(res.workflow, string(event.Event))
+			return nil
+		},
+	}).Handle(ctx, workflow))
+
+	speech, err := api.Audio.Speech.Create(ctx, &coze.CreateAudioSpeechReq{Input: "Hi", VoiceID: "voice1"})
+	require.NoError(t, err)
+	res.audio, err = io.ReadAll(speech.Data)
+	require.NoError(t, err)
+	require.NoError(t, speech.Data.Close())
+
+	res.file, err = api.Files.Upload(ctx, &coze.UploadFilesReq{File: coze.NewUploadFile(strings.NewReader("file content"), "a.txt")})
+	require.NoError(t, err)
+	return res
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "calls.json")
+	server := newTestServer(t)
+
+	rec, err := NewRecorder(path, WithMode(ModeRecord))
+	require.NoError(t, err)
+	recorded := runTestCalls(t, coze.NewCozeAPI(coze.NewTokenAuth(testToken), coze.WithBaseURL(server.URL), coze.WithTransport(rec)))
+	require.NoError(t, rec.Stop())
+	server.Close()
+
+	assert.Equal(t, []string{"Hel", "lo"}, recorded.messages)
+	assert.Equal(t, []string{"Message", "Done"}, recorded.workflow)
+	assert.Equal(t, testAudio, recorded.audio)
+	assert.Equal(t, "file1", recorded.file.ID)
+	assert.Equal(t, 12, recorded.file.Bytes)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), testToken)
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 4)
+	chat := cassette.Interactions[0]
+	assert.Equal(t, []string{Redacted}, chat.Request.Header["Authorization"])
+	require.Len(t, chat.Response.Events, 4)
+	assert.Greater(t, chat.Response.Events[1].Delay, time.Duration(0))
+	assert.Equal(t, testAudio, cassette.Interactions[2].Response.Body.Base64)
+
+	t.Run("replay", func(t *testing.T) {
+		rec, err := NewRecorder(path)
+		require.NoError(t, err)
+		api := coze.NewCozeAPI(coze.NewTokenAuth("another token"), coze.WithBaseURL(server.URL), coze.WithTransport(rec))
+		replayed := runTestCalls(t, api)
+		assert.Equal(t, recorded, replayed)
+		require.NoError(t, rec.Stop())
+
+		// every interaction is served once
+		_, err = api.Audio.Speech.Create(context.Background(), &coze.CreateAudioSpeechReq{Input: "Hi", VoiceID: "voice1"})
+		assert.ErrorContains(t, err, "cozetest: no recorded interaction for POST /v1/audio/speech")
+	})
+
+	t.Run("replay matches the body", func(t *testing.T) {
+		rec, err := NewRecorder(path)
+		require.NoError(t, err)
+		api := coze.NewCozeAPI(coze.NewTokenAuth(testToken), coze.WithBaseURL(server.URL), coze.WithTransport(rec))
+		_, err = api.Files.Upload(context.Background(), &coze.UploadFilesReq{File: coze.NewUploadFile(strings.NewReader("other"), "a.txt")})
+		assert.ErrorContains(t, err, "1 unused with another query or body")
+		_, err = api.Files.Upload(context.Background(), &coze.UploadFilesReq{File: coze.NewUploadFile(strings.NewReader("file content"), "a.txt")})
+		assert.NoError(t, err)
+	})
+}
+
+func TestRecorderRedaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	server := newTestServer(t)
+	defer server.Close()
+
+	rec, err := NewRecorder(path, WithMode(ModeRecord), WithRedactFields("expires_in"), WithRedactHeaders("X-Api-Key"))
+	require.NoError(t, err)
+	client := &http.Client{Transport: rec}
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/permission/oauth2/token",
+		strings.NewReader(`{"grant_type":"refresh_token","refresh_token":"rt_old","client_secret":"cs_secret"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("X-Api-Key", "key")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Contains(t, string(body), "at_secret")
+	require.NoError(t, rec.Stop())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for _, secret := range []string{testToken, "rt_old", "cs_secret", "at_secret", "rt_secret", `"key"`} {
+		assert.NotContains(t, string(data), secret)
+	}
+	// expires_in is not a string
+	assert.Contains(t, string(data), "900")
+
+	rec, err = NewRecorder(path)
+	require.NoError(t, err)
+	req, err = http.NewRequest(http.MethodPost, server.URL+"/api/permission/oauth2/token",
+		strings.NewReader(`{"client_secret": "other", "refresh_token": "rt_new", "grant_type": "refresh_token"}`))
+	require.NoError(t, err)
+	resp, err = (&http.Client{Transport: rec}).Do(req)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	assert.JSONEq(t, `{"access_token":"REDACTED","expires_in":900,"refresh_token":"REDACTED"}`, string(body))
+}
+
+func TestRecorderReplayStream(t *testing.T) {
+	cassette := &Cassette{Interactions: []*Interaction{{
+		Request: &RecordedRequest{Method: http.MethodGet, Path: "/stream", Query: "b=2&a=1"},
+		Response: &RecordedResponse{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/event-stream"}},
+			Events: []*SSEChunk{
+				{Delay: 0, Data: "data: a\n\n"},
+				{Delay: 50 * time.Millisecond, Data: "data: b\n\n"},
+			},
+			Error: io.ErrUnexpectedEOF.Error(),
+		},
+	}}}
+	path := filepath.Join(t.TempDir(), "stream.json")
+	require.NoError(t, cassette.Save(path))
+
+	t.Run("realtime", func(t *testing.T) {
+		rec, err := NewRecorder(path, WithRealtime())
+		require.NoError(t, err)
+		start := time.Now()
+		resp, err := (&http.Client{Transport: rec}).Get("http://localhost/stream?a=1&b=2")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, "data: a\n\ndata: b\n\n", string(body))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("close interrupts a pending read", func(t *testing.T) {
+		rec, err := NewRecorder(path, WithRealtime())
+		require.NoError(t, err)
+		resp, err := (&http.Client{Transport: rec}).Get("http://localhost/stream?a=1&b=2")
+		require.NoError(t, err)
+		buf := make([]byte, 64)
+		_, err = resp.Body.Read(buf)
+		require.NoError(t, err)
+		time.AfterFunc(10*time.Millisecond, func() { _ = resp.Body.Close() })
+		_, err = io.ReadAll(resp.Body)
+		assert.True(t, errors.Is(err, errBodyClosed))
+	})
+}
+
+func TestNormalizeBody(t *testing.T) {
+	assert.Equal(t, normalizeBody("application/json", []byte(`{"b": 1, "a": [1, 2]}`)),
+		normalizeBody("application/json", []byte(`{"a":[1,2],"b":1}`)))
+	assert.Equal(t, "not json", normalizeBody("text/plain", []byte("not json")))
+
+	multipart := func(boundary string) (string, []byte) {
+		body := "--" + boundary + "\r\nContent-Disposition: form-data; name=\"file\"; filename=\"a.txt\"\r\n\r\ncontent\r\n--" + boundary + "--\r\n"
+		return "multipart/form-data; boundary=" + boundary, []byte(body)
+	}
+	contentType1, body1 := multipart("boundary1")
+	contentType2, body2 := multipart("boundary2")
+	assert.Equal(t, normalizeBody(contentType1, body1), normalizeBody(contentType2, body2))
+	assert.False(t, bytes.Contains([]byte(normalizeBody(contentType1, body1)), []byte("boundary1")))
+}
@@ -0,0 +1,473 @@
+package cozetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// Server is an in-memory fake of the Coze Open API, serving the endpoints called by the SDK from
+// the state of its resources: a CozeAPI created with WithBaseURL(server.URL), or with
+// server.NewAPI, runs offline against it.
+//
+// Its behavior is scriptable: the replies of the bots, including tool calls and failures, with
+// AddReplies, the runs of the workflows, including interrupts, with SetWorkflow, the failures and
+// the latency of any endpoint with Fail and SetLatency, and the handler of any endpoint with
+// Handle.
+type Server struct {
+	*httptest.Server
+
+	routes []*route
+
+	mu          sync.Mutex
+	nextID      int64
+	requests    []*ServerRequest
+	failures    []*scriptedFailure
+	latencies   map[string]time.Duration
+	handlers    map[string]http.HandlerFunc
+	tokens      map[string]bool
+	requireAuth bool
+
+	user          *coze.User
+	workspaces    []*coze.Workspace
+	bots          map[string]*fakeBot
+	botOrder      []string
+	conversations map[string]*fakeConversation
+	convOrder     []string
+	chats         map[string]*fakeChat
+	replies       map[string][]*Reply
+	replyFunc     ReplyFunc
+	workflows     map[string]*WorkflowScript
+	runs          map[string]*fakeRun
+	interrupts    map[string]*fakeRun
+	datasets      map[string]*fakeDataset
+	datasetOrder  []string
+	documents     map[string]*fakeDocument
+	files         map[string]*fakeFile
+	voices        []*coze.Voice
+	devices       map[string]*fakeDevice
+	refreshTokens map[string]bool
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithAccessToken makes the server accept only the given access tokens, and the ones it issued
+// on the OAuth endpoints. By default, any token is accepted.
+func WithAccessToken(tokens ...string) ServerOption {
+	return func(s *Server) {
+		s.requireAuth = true
+		for _, token := range tokens {
+			s.tokens[token] = true
+		}
+	}
+}
+
+// ServerRequest is a request received by the Server.
+type ServerRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Failure is a scripted failure of an endpoint, see Server.Fail.
+type Failure struct {
+	// StatusCode is the HTTP status of the response. It defaults to 200 when Code is set, as the
+	// Coze API reports its errors, and to 500 otherwise.
+	StatusCode int
+
+	// Code and Msg are the Coze error of the response.
+	Code int
+	Msg  string
+
+	// AuthCode is the OAuth error code of the response, such as "invalid_grant".
+	AuthCode string
+
+	// RetryAfter sets the Retry-After header of the response.
+	RetryAfter time.Duration
+
+	// DropAfterEvents, if positive, makes a stream drop its connection after this number of events,
+	// instead of failing the request.
+	DropAfterEvents int
+
+	// Times is the number of requests failing, 1 by default. A negative value fails all of them.
+	Times int
+}
+
+type scriptedFailure struct {
+	method  string
+	path    string
+	failure Failure
+	left    int
+}
+
+// DefaultAccessToken is the token of the client returned by Server.NewAPI when the server accepts
+// any token.
+const DefaultAccessToken = "fake_access_token"
+
+// NewServer starts a fake Coze server, with a personal workspace and a user. It must be closed
+// with Close.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		nextID:        7000000000000000000,
+		latencies:     map[string]time.Duration{},
+		handlers:      map[string]http.HandlerFunc{},
+		tokens:        map[string]bool{},
+		bots:          map[string]*fakeBot{},
+		conversations: map[string]*fakeConversation{},
+		chats:         map[string]*fakeChat{},
+		replies:       map[string][]*Reply{},
+		replyFunc:     EchoReply,
+		workflows:     map[string]*WorkflowScript{},
+		runs:          map[string]*fakeRun{},
+		interrupts:    map[string]*fakeRun{},
+		datasets:      map[string]*fakeDataset{},
+		documents:     map[string]*fakeDocument{},
+		files:         map[string]*fakeFile{},
+		devices:       map[string]*fakeDevice{},
+		refreshTokens: map[string]bool{},
+	}
+	s.user = &coze.User{UserID: s.newID(), UserName: "fake_user", NickName: "Fake User"}
+	s.workspaces = []*coze.Workspace{{
+		ID: s.newID(), Name: "Personal", RoleType: coze.WorkspaceRoleTypeOwner, WorkspaceType: coze.WorkspaceTypePersonal,
+	}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.registerChatRoutes()
+	s.registerWorkflowRoutes()
+	s.registerResourceRoutes()
+	s.registerAuthRoutes()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// NewAPI returns a client of the server, authenticated with the first token given to
+// WithAccessToken, or DefaultAccessToken.
+func (s *Server) NewAPI(opts ...coze.CozeAPIOption) coze.CozeAPI {
+	token := DefaultAccessToken
+	s.mu.Lock()
+	for t := range s.tokens {
+		token = t
+		break
+	}
+	s.mu.Unlock()
+	return coze.NewCozeAPI(coze.NewTokenAuth(token), append([]coze.CozeAPIOption{coze.WithBaseURL(s.URL)}, opts...)...)
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []*ServerRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*ServerRequest(nil), s.requests...)
+}
+
+// Fail makes the next requests to the endpoint fail, see Failure. An empty method or path matches
+// any. The failures are consumed in the order they were added.
+func (s *Server) Fail(method, path string, failure Failure) {
+	left := failure.Times
+	if left == 0 {
+		left = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &scriptedFailure{method: method, path: path, failure: failure, left: left})
+}
+
+// SetLatency delays the responses of the endpoint with the given path, or of all of them for an
+// empty path. The delay ends early if the request is canceled.
+func (s *Server) SetLatency(path string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies[path] = latency
+}
+
+// Handle replaces the handler of an endpoint, for the responses the fake does not script.
+func (s *Server) Handle(method, path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method+" "+path] = handler
+}
+
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.FormatInt(s.nextID, 10)
+}
+
+// route is an endpoint, its pattern matching the segments between braces as parameters.
+type route struct {
+	method   string
+	segments []string
+	handler  func(*serverCall)
+}
+
+func (s *Server) route(method, pattern string, handler func(*serverCall)) {
+	s.routes = append(s.routes, &route{method: method, segments: strings.Split(pattern, "/"), handler: handler})
+}
+
+func (r *route) match(method, path string) (map[string]string, bool) {
+	segments := strings.Split(path, "/")
+	if method != r.method || len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range r.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	s.mu.Lock()
+	s.requests = append(s.requests, &ServerRequest{
+		Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: body,
+	})
+	logID := "fake_log_" + s.newID()
+	latency, ok := s.latencies[r.URL.Path]
+	if !ok {
+		latency = s.latencies[""]
+	}
+	failure := s.nextFailure(r.Method, r.URL.Path)
+	handler := s.handlers[r.Method+" "+r.URL.Path]
+	s.mu.Unlock()
+
+	w.Header().Set("X-Tt-Logid", logID)
+	if latency > 0 && sleepRequest(r.Context(), latency) != nil {
+		return
+	}
+	call := &serverCall{server: s, w: w, r: r, body: body}
+	if failure != nil && failure.DropAfterEvents <= 0 {
+		call.fail(failure)
+		return
+	}
+	if failure != nil {
+		call.dropAfter = failure.DropAfterEvents
+	}
+	if handler != nil {
+		handler(w, r)
+		return
+	}
+	if !isAuthPath(r.URL.Path) && !s.authorized(r) {
+		call.json(http.StatusUnauthorized, map[string]interface{}{
+			"code": 4100, "msg": "authentication is invalid",
+		})
+		return
+	}
+	for _, route := range s.routes {
+		if params, ok := route.match(r.Method, r.URL.Path); ok {
+			call.params = params
+			route.handler(call)
+			return
+		}
+	}
+	call.json(http.StatusNotFound, map[string]interface{}{"code": 4004, "msg": "not found: " + r.Method + " " + r.URL.Path})
+}
+
+func (s *Server) nextFailure(method, path string) *Failure {
+	for i, f := range s.failures {
+		if (f.method != "" && f.method != method) || (f.path != "" && f.path != path) {
+			continue
+		}
+		if f.left > 0 {
+			f.left--
+			if f.left == 0 {
+				s.failures = append(s.failures[:i:i], s.failures[i+1:]...)
+			}
+		}
+		failure := f.failure
+		return &failure
+	}
+	return nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if !s.requireAuth {
+		return true
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[token]
+}
+
+func sleepRequest(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serverCall is a request being handled by the Server.
+type serverCall struct {
+	server    *Server
+	w         http.ResponseWriter
+	r         *http.Request
+	body      []byte
+	params    map[string]string
+	dropAfter int
+}
+
+func (c *serverCall) decode(v interface{}) bool {
+	if len(c.body) == 0 {
+		return true
+	}
+	if err := json.Unmarshal(c.body, v); err != nil {
+		c.error(4000, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func (c *serverCall) query(name string) string {
+	return c.r.URL.Query().Get(name)
+}
+
+func (c *serverCall) queryInt(name string, fallback int) int {
+	if v, err := strconv.Atoi(c.query(name)); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func (c *serverCall) json(status int, v interface{}) {
+	c.w.Header().Set("Content-Type", "application/json")
+	c.w.WriteHeader(status)
+	_ = json.NewEncoder(c.w).Encode(v)
+}
+
+// data writes a successful response with the data in the envelope of the Coze API.
+func (c *serverCall) data(data interface{}) {
+	c.json(http.StatusOK, map[string]interface{}{"code": 0, "msg": "", "data": data})
+}
+
+// ok writes a successful response with the fields of v at the top level, as some endpoints do.
+func (c *serverCall) ok(v interface{}) {
+	res := map[string]interface{}{}
+	if v != nil {
+		raw, _ := json.Marshal(v)
+		_ = json.Unmarshal(raw, &res)
+	}
+	res["code"], res["msg"] = 0, ""
+	c.json(http.StatusOK, res)
+}
+
+// error writes a Coze error, with the status 200 as the Coze API does.
+func (c *serverCall) error(code int, msg string) {
+	c.json(http.StatusOK, map[string]interface{}{"code": code, "msg": msg})
+}
+
+func (c *serverCall) notFound(kind, id string) {
+	c.error(4200, fmt.Sprintf("%s %s not found", kind, id))
+}
+
+func (c *serverCall) fail(f *Failure) {
+	status := f.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+		if f.Code != 0 {
+			status = http.StatusOK
+		}
+	}
+	if f.RetryAfter > 0 {
+		c.w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+	}
+	body := map[string]interface{}{"code": f.Code, "msg": f.Msg}
+	if f.AuthCode != "" {
+		body["error_code"], body["error_message"] = f.AuthCode, f.Msg
+	}
+	c.json(status, body)
+}
+
+// sseWriter writes the events of a stream.
+type sseWriter struct {
+	call   *serverCall
+	events int
+}
+
+func (c *serverCall) stream() *sseWriter {
+	c.w.Header().Set("Content-Type", "text/event-stream")
+	c.w.Header().Set("Cache-Control", "no-cache")
+	c.w.WriteHeader(http.StatusOK)
+	return &sseWriter{call: c}
+}
+
+// send writes an event after the delay, its data being written as is if it is a string, in JSON
+// otherwise. It fails once the request is canceled, and drops the connection as scripted by the
+// failure of the request.
+func (w *sseWriter) send(delay time.Duration, id, event string, data interface{}) error {
+	if err := sleepRequest(w.call.r.Context(), delay); err != nil {
+		return err
+	}
+	if w.call.dropAfter > 0 && w.events >= w.call.dropAfter {
+		panic(http.ErrAbortHandler)
+	}
+	raw, ok := data.(string)
+	if !ok {
+		encoded, _ := json.Marshal(data)
+		raw = string(encoded)
+	}
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	b.WriteString("event: " + event + "\n")
+	for _, line := range strings.Split(raw, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(w.call.w, b.String()); err != nil {
+		return err
+	}
+	w.call.w.(http.Flusher).Flush()
+	w.events++
+	return nil
+}
+
+// page returns the items of a page numbered from 1, and whether more pages follow.
+func page[T any](items []T, pageNum, pageSize int) ([]T, bool) {
+	if pageNum < 1 {
+		pageNum = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	start := (pageNum - 1) * pageSize
+	if start >= len(items) {
+		return []T{}, false
+	}
+	end := start + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end], end < len(items)
+}
+
+func now() int64 {
+	return time.Now().Unix()
+}
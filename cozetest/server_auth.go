@@ -0,0 +1,136 @@
+package cozetest
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// TokenTTL is the lifetime of the access tokens issued by the Server.
+const TokenTTL = 15 * time.Minute
+
+// ApproveDevice approves the device authorization with the given user code: the next request of
+// its token succeeds. It reports whether the code is known.
+func (s *Server) ApproveDevice(userCode string) bool {
+	return s.setDevice(userCode, "")
+}
+
+// DenyDevice denies the device authorization with the given user code: the requests of its token
+// fail with access_denied. It reports whether the code is known.
+func (s *Server) DenyDevice(userCode string) bool {
+	return s.setDevice(userCode, coze.AccessDenied)
+}
+
+func (s *Server) setDevice(userCode string, status coze.AuthErrorCode) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, device := range s.devices {
+		if device.userCode == userCode {
+			device.status = status
+			return true
+		}
+	}
+	return false
+}
+
+// The OAuth error codes the SDK does not define.
+const (
+	invalidRequest       coze.AuthErrorCode = "invalid_request"
+	invalidGrant         coze.AuthErrorCode = "invalid_grant"
+	unsupportedGrantType coze.AuthErrorCode = "unsupported_grant_type"
+)
+
+type fakeDevice struct {
+	userCode string
+	status   coze.AuthErrorCode
+}
+
+// oauthRequest is the body of the token requests, see getAccessTokenReq of the SDK.
+type oauthRequest struct {
+	ClientID     string `json:"client_id"`
+	GrantType    string `json:"grant_type"`
+	Code         string `json:"code"`
+	RefreshToken string `json:"refresh_token"`
+	DeviceCode   string `json:"device_code"`
+}
+
+func isAuthPath(path string) bool {
+	return strings.HasPrefix(path, "/api/permission/")
+}
+
+func (s *Server) registerAuthRoutes() {
+	s.route(http.MethodPost, "/api/permission/oauth2/token", s.issueToken)
+	s.route(http.MethodPost, "/api/permission/oauth2/account/{account_id}/token", s.issueToken)
+	s.route(http.MethodPost, "/api/permission/oauth2/device/code", s.deviceCode)
+	s.route(http.MethodPost, "/api/permission/oauth2/workspace_id/{workspace_id}/device/code", s.deviceCode)
+}
+
+// authError writes an OAuth error, with the status 400 as the Coze API does.
+func (c *serverCall) authError(code coze.AuthErrorCode, msg string) {
+	c.json(http.StatusBadRequest, map[string]interface{}{"error_code": string(code), "error_message": msg})
+}
+
+func (s *Server) issueToken(c *serverCall) {
+	req := &oauthRequest{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch coze.GrantType(req.GrantType) {
+	case coze.GrantTypeAuthorizationCode:
+		if req.Code == "" {
+			c.authError(invalidRequest, "code is required")
+			return
+		}
+	case coze.GrantTypeJWTCode:
+		if !strings.HasPrefix(c.r.Header.Get("Authorization"), "Bearer ") {
+			c.authError(invalidRequest, "jwt is required")
+			return
+		}
+	case coze.GrantTypeRefreshToken:
+		if !s.refreshTokens[req.RefreshToken] {
+			c.authError(invalidGrant, "refresh token is invalid")
+			return
+		}
+		delete(s.refreshTokens, req.RefreshToken)
+	case coze.GrantTypeDeviceCode:
+		device, ok := s.devices[req.DeviceCode]
+		switch {
+		case !ok:
+			c.authError(invalidGrant, "device code is invalid")
+			return
+		case device.status != "":
+			c.authError(device.status, string(device.status))
+			return
+		}
+		delete(s.devices, req.DeviceCode)
+	default:
+		c.authError(unsupportedGrantType, "unsupported grant type "+req.GrantType)
+		return
+	}
+
+	token := &coze.OAuthToken{
+		AccessToken: "fake_access_token_" + s.newID(),
+		ExpiresIn:   time.Now().Add(TokenTTL).Unix(),
+	}
+	s.tokens[token.AccessToken] = true
+	if coze.GrantType(req.GrantType) != coze.GrantTypeJWTCode {
+		token.RefreshToken = "fake_refresh_token_" + s.newID()
+		s.refreshTokens[token.RefreshToken] = true
+	}
+	c.json(http.StatusOK, token)
+}
+
+func (s *Server) deviceCode(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deviceCode, userCode := "fake_device_code_"+s.newID(), s.newID()[12:]
+	s.devices[deviceCode] = &fakeDevice{userCode: userCode, status: coze.AuthorizationPending}
+	c.json(http.StatusOK, &coze.GetDeviceAuthResp{
+		DeviceCode: deviceCode, UserCode: userCode, VerificationURI: s.URL + "/device",
+		ExpiresIn: int(TokenTTL / time.Second), Interval: 5,
+	})
+}
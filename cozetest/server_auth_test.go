@@ -0,0 +1,79 @@
+package cozetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+func TestServerOAuth(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("web oauth", func(t *testing.T) {
+		server := NewServer(WithAccessToken())
+		defer server.Close()
+		client, err := coze.NewWebOAuthClient("client", "secret", coze.WithAuthBaseURL(server.URL))
+		require.NoError(t, err)
+
+		token, err := client.GetAccessToken(ctx, &coze.GetWebOAuthAccessTokenReq{Code: "code", RedirectURI: "http://localhost"})
+		require.NoError(t, err)
+		assert.Greater(t, token.ExpiresIn, time.Now().Unix())
+
+		api := coze.NewCozeAPI(coze.NewTokenAuth(token.AccessToken), coze.WithBaseURL(server.URL))
+		_, err = api.Users.Me(ctx)
+		require.NoError(t, err)
+
+		refreshed, err := client.RefreshToken(ctx, token.RefreshToken)
+		require.NoError(t, err)
+		assert.NotEqual(t, token.AccessToken, refreshed.AccessToken)
+
+		_, err = client.RefreshToken(ctx, token.RefreshToken)
+		authErr, ok := coze.AsAuthError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, invalidGrant, authErr.Code)
+	})
+
+	t.Run("device oauth", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		client, err := coze.NewDeviceOAuthClient("client", coze.WithAuthBaseURL(server.URL))
+		require.NoError(t, err)
+
+		code, err := client.GetDeviceCode(ctx, &coze.GetDeviceOAuthCodeReq{})
+		require.NoError(t, err)
+		assert.Contains(t, code.VerificationURL, code.UserCode)
+
+		_, err = client.GetAccessToken(ctx, &coze.GetDeviceOAuthAccessTokenReq{DeviceCode: code.DeviceCode})
+		authErr, ok := coze.AsAuthError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, coze.AuthorizationPending, authErr.Code)
+
+		require.True(t, server.ApproveDevice(code.UserCode))
+		token, err := client.GetAccessToken(ctx, &coze.GetDeviceOAuthAccessTokenReq{DeviceCode: code.DeviceCode})
+		require.NoError(t, err)
+		assert.NotEmpty(t, token.AccessToken)
+	})
+
+	t.Run("device oauth denied", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		client, err := coze.NewDeviceOAuthClient("client", coze.WithAuthBaseURL(server.URL))
+		require.NoError(t, err)
+
+		workspaceID := server.Workspaces()[0].ID
+		code, err := client.GetDeviceCode(ctx, &coze.GetDeviceOAuthCodeReq{WorkspaceID: &workspaceID})
+		require.NoError(t, err)
+		require.True(t, server.DenyDevice(code.UserCode))
+		assert.False(t, server.DenyDevice("unknown"))
+
+		_, err = client.GetAccessToken(ctx, &coze.GetDeviceOAuthAccessTokenReq{DeviceCode: code.DeviceCode, Poll: true})
+		authErr, ok := coze.AsAuthError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, coze.AccessDenied, authErr.Code)
+	})
+}
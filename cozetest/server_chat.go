@@ -0,0 +1,657 @@
+package cozetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// Reply is a scripted reply of a bot to a chat, see Server.AddReplies.
+type Reply struct {
+	// Content is the answer of the bot. A stream sends it in Deltas when they are set, in one
+	// delta otherwise.
+	Content string
+	Deltas  []string
+
+	// ReasoningContent is the reasoning of the bot, sent with the first delta.
+	ReasoningContent string
+
+	// ToolCalls interrupts the chat, which then requires the outputs of the tools. The next reply of
+	// the bot answers the outputs submitted.
+	ToolCalls []*coze.ChatToolCall
+
+	// Error fails the chat with this error.
+	Error *coze.ChatError
+
+	// Usage is the token usage of the chat, counted from the words of the messages by default.
+	Usage *coze.ChatUsage
+
+	// Delay is waited before each event of a stream, and before the end of a chat created without
+	// stream.
+	Delay time.Duration
+}
+
+// ReplyFunc returns the reply of the bot to the messages of a conversation, the last one being the
+// question or the output of the tools.
+type ReplyFunc func(botID string, messages []*coze.Message) *Reply
+
+// EchoReply is the default ReplyFunc, the bot answering the content of the last message.
+func EchoReply(botID string, messages []*coze.Message) *Reply {
+	if len(messages) == 0 {
+		return &Reply{}
+	}
+	return &Reply{Content: messages[len(messages)-1].Content}
+}
+
+// AddReplies queues replies of the bot, served in order to its next chats and tool outputs. Once
+// they are all served, the replies are returned by the ReplyFunc.
+func (s *Server) AddReplies(botID string, replies ...*Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[botID] = append(s.replies[botID], replies...)
+}
+
+// SetReplyFunc sets the function replying to the chats once the queued replies are served,
+// EchoReply by default.
+func (s *Server) SetReplyFunc(fn ReplyFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replyFunc = fn
+}
+
+// AddConversation adds a conversation with its messages.
+func (s *Server) AddConversation(botID string, messages ...*coze.Message) *coze.Conversation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conv := s.createConversation(botID, nil)
+	for _, message := range messages {
+		s.addMessage(conv, message, "")
+	}
+	res := conv.conversation
+	return &res
+}
+
+// Chat returns the chat with the given id, in its current state.
+func (s *Server) Chat(chatID string) (*coze.Chat, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chat, ok := s.chats[chatID]
+	if !ok {
+		return nil, false
+	}
+	s.settleChat(chat)
+	res := chat.chat
+	return &res, true
+}
+
+// ToolOutputs returns the tool outputs submitted to the chat with the given id.
+func (s *Server) ToolOutputs(chatID string) []*coze.ToolOutput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if chat, ok := s.chats[chatID]; ok {
+		return append([]*coze.ToolOutput(nil), chat.toolOutputs...)
+	}
+	return nil
+}
+
+type fakeConversation struct {
+	conversation coze.Conversation
+	botID        string
+	messages     []*coze.Message
+}
+
+type fakeChat struct {
+	chat        coze.Chat
+	question    string
+	reply       *Reply
+	readyAt     time.Time
+	messages    []*coze.Message
+	toolOutputs []*coze.ToolOutput
+	saveHistory bool
+}
+
+func (s *Server) registerChatRoutes() {
+	s.route(http.MethodPost, "/v3/chat", s.createChat)
+	s.route(http.MethodGet, "/v3/chat/retrieve", s.retrieveChat)
+	s.route(http.MethodPost, "/v3/chat/cancel", s.cancelChat)
+	s.route(http.MethodPost, "/v3/chat/submit_tool_outputs", s.submitToolOutputs)
+	s.route(http.MethodGet, "/v3/chat/message/list", s.listChatMessages)
+
+	s.route(http.MethodGet, "/v1/conversations", s.listConversations)
+	s.route(http.MethodPost, "/v1/conversation/create", s.createConversationHandler)
+	s.route(http.MethodGet, "/v1/conversation/retrieve", s.retrieveConversation)
+	s.route(http.MethodPost, "/v1/conversations/{conversation_id}/clear", s.clearConversation)
+	s.route(http.MethodPost, "/v1/conversation/message/create", s.createMessage)
+	s.route(http.MethodPost, "/v1/conversation/message/list", s.listMessages)
+	s.route(http.MethodGet, "/v1/conversation/message/retrieve", s.retrieveMessage)
+	s.route(http.MethodPost, "/v1/conversation/message/modify", s.modifyMessage)
+	s.route(http.MethodPost, "/v1/conversation/message/delete", s.deleteMessage)
+}
+
+func (s *Server) createConversation(botID string, metaData map[string]string) *fakeConversation {
+	conv := &fakeConversation{
+		conversation: coze.Conversation{ID: s.newID(), CreatedAt: int(now()), MetaData: metaData, LastSectionID: s.newID()},
+		botID:        botID,
+	}
+	s.conversations[conv.conversation.ID] = conv
+	s.convOrder = append(s.convOrder, conv.conversation.ID)
+	return conv
+}
+
+func (s *Server) addMessage(conv *fakeConversation, message *coze.Message, chatID string) *coze.Message {
+	res := *message
+	res.ID = s.newID()
+	res.ConversationID = conv.conversation.ID
+	res.SectionID = conv.conversation.LastSectionID
+	res.ChatID = chatID
+	if res.BotID == "" && res.Role == coze.MessageRoleAssistant {
+		res.BotID = conv.botID
+	}
+	if res.ContentType == "" {
+		res.ContentType = coze.MessageContentTypeText
+	}
+	res.CreatedAt, res.UpdatedAt = now(), now()
+	conv.messages = append(conv.messages, &res)
+	return &res
+}
+
+// nextReply pops the next reply of the bot, or asks the ReplyFunc.
+func (s *Server) nextReply(botID string, conv *fakeConversation) *Reply {
+	if queue := s.replies[botID]; len(queue) > 0 {
+		s.replies[botID] = queue[1:]
+		return queue[0]
+	}
+	var history []*coze.Message
+	for _, message := range conv.messages {
+		if message.SectionID == conv.conversation.LastSectionID {
+			history = append(history, message)
+		}
+	}
+	return s.replyFunc(botID, history)
+}
+
+func (s *Server) createChat(c *serverCall) {
+	req := &coze.CreateChatsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	var conv *fakeConversation
+	if id := c.query("conversation_id"); id != "" {
+		var ok bool
+		if conv, ok = s.conversations[id]; !ok {
+			s.mu.Unlock()
+			c.notFound("conversation", id)
+			return
+		}
+	} else {
+		conv = s.createConversation(req.BotID, nil)
+	}
+	chat := &fakeChat{
+		chat: coze.Chat{
+			ID: s.newID(), ConversationID: conv.conversation.ID, BotID: req.BotID,
+			CreatedAt: int(now()), MetaData: req.MetaData, Status: coze.ChatStatusCreated,
+		},
+		saveHistory: req.AutoSaveHistory == nil || *req.AutoSaveHistory,
+	}
+	for _, message := range req.Messages {
+		saved := s.addMessage(conv, message, chat.chat.ID)
+		chat.question = saved.Content
+	}
+	reply := s.nextReply(req.BotID, conv)
+	s.chats[chat.chat.ID] = chat
+	stream := req.Stream != nil && *req.Stream
+	if !stream {
+		chat.chat.Status = coze.ChatStatusInProgress
+		chat.reply, chat.readyAt = reply, time.Now().Add(reply.Delay)
+	}
+	res := chat.chat
+	s.mu.Unlock()
+
+	if stream {
+		s.streamChat(c, chat, reply, false)
+		return
+	}
+	c.data(res)
+}
+
+// settleChat ends a chat created without stream once its reply is ready.
+func (s *Server) settleChat(chat *fakeChat) {
+	if chat.reply == nil || time.Now().Before(chat.readyAt) {
+		return
+	}
+	reply := chat.reply
+	chat.reply = nil
+	for _, message := range s.replyMessages(chat, reply) {
+		s.saveChatMessage(chat, message)
+	}
+	s.endChat(chat, reply)
+}
+
+// replyMessages returns the messages of the reply, not saved yet.
+func (s *Server) replyMessages(chat *fakeChat, reply *Reply) []*coze.Message {
+	newMessage := func(messageType coze.MessageType, content string) *coze.Message {
+		return &coze.Message{
+			ID: s.newID(), ConversationID: chat.chat.ConversationID, BotID: chat.chat.BotID, ChatID: chat.chat.ID,
+			Role: coze.MessageRoleAssistant, Type: messageType, Content: content, ContentType: coze.MessageContentTypeText,
+			CreatedAt: now(), UpdatedAt: now(),
+		}
+	}
+	if reply.Error != nil {
+		return nil
+	}
+	var messages []*coze.Message
+	if len(reply.ToolCalls) > 0 {
+		for _, call := range reply.ToolCalls {
+			content, _ := json.Marshal(call)
+			messages = append(messages, newMessage(coze.MessageTypeFunctionCall, string(content)))
+		}
+		return messages
+	}
+	answer := newMessage(coze.MessageTypeAnswer, reply.Content)
+	if reply.Content == "" && len(reply.Deltas) > 0 {
+		answer.Content = strings.Join(reply.Deltas, "")
+	}
+	answer.ReasoningContent = reply.ReasoningContent
+	return append(messages, answer)
+}
+
+func (s *Server) saveChatMessage(chat *fakeChat, message *coze.Message) {
+	chat.messages = append(chat.messages, message)
+	if conv, ok := s.conversations[chat.chat.ConversationID]; ok && chat.saveHistory {
+		message.SectionID = conv.conversation.LastSectionID
+		conv.messages = append(conv.messages, message)
+	}
+}
+
+// endChat sets the status of the chat at the end of the reply, unless it was canceled.
+func (s *Server) endChat(chat *fakeChat, reply *Reply) {
+	if chat.chat.Status == coze.ChatStatusCancelled {
+		return
+	}
+	switch {
+	case reply.Error != nil:
+		chat.chat.Status, chat.chat.FailedAt, chat.chat.LastError = coze.ChatStatusFailed, int(now()), reply.Error
+	case len(reply.ToolCalls) > 0:
+		chat.chat.Status = coze.ChatStatusRequiresAction
+		chat.chat.RequiredAction = &coze.ChatRequiredAction{
+			Type:              "submit_tool_outputs",
+			SubmitToolOutputs: &coze.ChatSubmitToolOutputs{ToolCalls: reply.ToolCalls},
+		}
+	default:
+		chat.chat.Status, chat.chat.CompletedAt, chat.chat.RequiredAction = coze.ChatStatusCompleted, int(now()), nil
+	}
+	chat.chat.Usage = reply.Usage
+	if chat.chat.Usage == nil {
+		usage := &coze.ChatUsage{InputCount: len(strings.Fields(chat.question))}
+		for _, message := range chat.messages {
+			usage.OutputCount += len(strings.Fields(message.Content))
+		}
+		usage.TokenCount = usage.InputCount + usage.OutputCount
+		chat.chat.Usage = usage
+	}
+}
+
+// streamChat sends the events of the reply, and then ends the chat. A resumed chat, answering tool
+// outputs, is not created again.
+func (s *Server) streamChat(c *serverCall, chat *fakeChat, reply *Reply, resumed bool) {
+	s.mu.Lock()
+	messages := s.replyMessages(chat, reply)
+	created := chat.chat
+	chat.chat.Status = coze.ChatStatusInProgress
+	inProgress := chat.chat
+	s.mu.Unlock()
+
+	w := c.stream()
+	if !resumed && w.send(reply.Delay, "", string(coze.ChatEventConversationChatCreated), created) != nil {
+		return
+	}
+	if w.send(reply.Delay, "", string(coze.ChatEventConversationChatInProgress), inProgress) != nil {
+		return
+	}
+	for _, message := range messages {
+		if message.Type == coze.MessageTypeAnswer {
+			deltas := reply.Deltas
+			if len(deltas) == 0 {
+				deltas = []string{message.Content}
+			}
+			for i, content := range deltas {
+				delta := *message
+				delta.Content, delta.ReasoningContent = content, ""
+				if i == 0 {
+					delta.ReasoningContent = message.ReasoningContent
+				}
+				if w.send(reply.Delay, "", string(coze.ChatEventConversationMessageDelta), delta) != nil {
+					return
+				}
+			}
+		}
+		if w.send(reply.Delay, "", string(coze.ChatEventConversationMessageCompleted), message) != nil {
+			return
+		}
+		s.mu.Lock()
+		s.saveChatMessage(chat, message)
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.endChat(chat, reply)
+	end := chat.chat
+	s.mu.Unlock()
+	event := coze.ChatEventConversationChatCompleted
+	switch end.Status {
+	case coze.ChatStatusFailed:
+		event = coze.ChatEventConversationChatFailed
+	case coze.ChatStatusRequiresAction:
+		event = coze.ChatEventConversationChatRequiresAction
+	}
+	if w.send(reply.Delay, "", string(event), end) != nil {
+		return
+	}
+	_ = w.send(0, "", string(coze.ChatEventDone), "[DONE]")
+}
+
+// chatOf returns the chat of the query, settled, or writes the error.
+func (s *Server) chatOf(c *serverCall, conversationID, chatID string) *fakeChat {
+	chat, ok := s.chats[chatID]
+	if !ok || chat.chat.ConversationID != conversationID {
+		c.notFound("chat", chatID)
+		return nil
+	}
+	s.settleChat(chat)
+	return chat
+}
+
+func (s *Server) retrieveChat(c *serverCall) {
+	s.mu.Lock()
+	chat := s.chatOf(c, c.query("conversation_id"), c.query("chat_id"))
+	if chat == nil {
+		s.mu.Unlock()
+		return
+	}
+	res := chat.chat
+	s.mu.Unlock()
+	c.data(res)
+}
+
+func (s *Server) cancelChat(c *serverCall) {
+	req := &coze.CancelChatsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	chat := s.chatOf(c, req.ConversationID, req.ChatID)
+	if chat == nil {
+		s.mu.Unlock()
+		return
+	}
+	switch chat.chat.Status {
+	case coze.ChatStatusCompleted, coze.ChatStatusFailed:
+		s.mu.Unlock()
+		c.error(4000, "chat "+req.ChatID+" already ended")
+		return
+	}
+	chat.chat.Status, chat.chat.RequiredAction, chat.reply = coze.ChatStatusCancelled, nil, nil
+	res := chat.chat
+	s.mu.Unlock()
+	c.data(res)
+}
+
+func (s *Server) submitToolOutputs(c *serverCall) {
+	req := &coze.SubmitToolOutputsChatReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	chat := s.chatOf(c, c.query("conversation_id"), c.query("chat_id"))
+	if chat == nil {
+		s.mu.Unlock()
+		return
+	}
+	if chat.chat.Status != coze.ChatStatusRequiresAction {
+		s.mu.Unlock()
+		c.error(4000, "chat "+chat.chat.ID+" does not require action")
+		return
+	}
+	chat.toolOutputs = append(chat.toolOutputs, req.ToolOutputs...)
+	conv := s.conversations[chat.chat.ConversationID]
+	for _, output := range req.ToolOutputs {
+		s.saveChatMessage(chat, &coze.Message{
+			ID: s.newID(), ConversationID: conv.conversation.ID, ChatID: chat.chat.ID, Role: coze.MessageRoleUser,
+			Type: coze.MessageTypeToolOutput, Content: output.Output, ContentType: coze.MessageContentTypeText,
+			CreatedAt: now(), UpdatedAt: now(),
+		})
+	}
+	reply := s.nextReply(chat.chat.BotID, conv)
+	chat.chat.Status, chat.chat.RequiredAction = coze.ChatStatusInProgress, nil
+	stream := req.Stream != nil && *req.Stream
+	if !stream {
+		chat.reply, chat.readyAt = reply, time.Now().Add(reply.Delay)
+	}
+	res := chat.chat
+	s.mu.Unlock()
+
+	if stream {
+		s.streamChat(c, chat, reply, true)
+		return
+	}
+	c.data(res)
+}
+
+func (s *Server) listChatMessages(c *serverCall) {
+	s.mu.Lock()
+	chat := s.chatOf(c, c.query("conversation_id"), c.query("chat_id"))
+	if chat == nil {
+		s.mu.Unlock()
+		return
+	}
+	messages := append([]*coze.Message{}, chat.messages...)
+	s.mu.Unlock()
+	c.data(messages)
+}
+
+func (s *Server) listConversations(c *serverCall) {
+	botID := c.query("bot_id")
+	s.mu.Lock()
+	var convs []*coze.Conversation
+	for _, id := range s.convOrder {
+		if conv := s.conversations[id]; conv.botID == botID {
+			res := conv.conversation
+			convs = append(convs, &res)
+		}
+	}
+	s.mu.Unlock()
+	items, hasMore := page(convs, c.queryInt("page_num", 1), c.queryInt("page_size", 20))
+	c.data(map[string]interface{}{"conversations": items, "has_more": hasMore})
+}
+
+func (s *Server) createConversationHandler(c *serverCall) {
+	req := &coze.CreateConversationsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	conv := s.createConversation(req.BotID, req.MetaData)
+	for _, message := range req.Messages {
+		s.addMessage(conv, message, "")
+	}
+	res := conv.conversation
+	s.mu.Unlock()
+	c.data(res)
+}
+
+// conversationOf returns the conversation with the given id, or writes the error.
+func (s *Server) conversationOf(c *serverCall, id string) *fakeConversation {
+	conv, ok := s.conversations[id]
+	if !ok {
+		c.notFound("conversation", id)
+	}
+	return conv
+}
+
+func (s *Server) retrieveConversation(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conv := s.conversationOf(c, c.query("conversation_id")); conv != nil {
+		c.data(conv.conversation)
+	}
+}
+
+func (s *Server) clearConversation(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conv := s.conversationOf(c, c.params["conversation_id"])
+	if conv == nil {
+		return
+	}
+	conv.conversation.LastSectionID = s.newID()
+	c.data(map[string]interface{}{"id": conv.conversation.LastSectionID, "conversation_id": conv.conversation.ID})
+}
+
+func (s *Server) createMessage(c *serverCall) {
+	req := &coze.CreateMessageReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conv := s.conversationOf(c, c.query("conversation_id"))
+	if conv == nil {
+		return
+	}
+	messageType := coze.MessageTypeQuestion
+	if req.Role == coze.MessageRoleAssistant {
+		messageType = coze.MessageTypeAnswer
+	}
+	c.data(s.addMessage(conv, &coze.Message{
+		Role: req.Role, Type: messageType, Content: req.Content, ContentType: req.ContentType, MetaData: req.MetaData,
+	}, ""))
+}
+
+// listMessages lists the messages of a conversation, the newest first unless the order is "asc".
+// The page follows after_id, or precedes before_id, in this order.
+func (s *Server) listMessages(c *serverCall) {
+	req := &coze.ListConversationsMessagesReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	conv := s.conversationOf(c, c.query("conversation_id"))
+	if conv == nil {
+		s.mu.Unlock()
+		return
+	}
+	var messages []*coze.Message
+	for _, message := range conv.messages {
+		if req.ChatID == nil || *req.ChatID == message.ChatID {
+			messages = append(messages, message)
+		}
+	}
+	s.mu.Unlock()
+	if req.Order == nil || *req.Order != "asc" {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	start, end := 0, len(messages)
+	if req.AfterID != nil && *req.AfterID != "" {
+		start = indexOfMessage(messages, *req.AfterID) + 1
+	}
+	if req.BeforeID != nil && *req.BeforeID != "" {
+		if i := indexOfMessage(messages, *req.BeforeID); i >= 0 {
+			end = i
+		}
+		if end-start > limit {
+			start = end - limit
+		}
+	}
+	if end-start > limit {
+		end = start + limit
+	}
+	if start > end {
+		start = end
+	}
+	items := append([]*coze.Message{}, messages[start:end]...)
+	res := map[string]interface{}{"data": items, "has_more": end < len(messages), "first_id": "", "last_id": ""}
+	if len(items) > 0 {
+		res["first_id"], res["last_id"] = items[0].ID, items[len(items)-1].ID
+	}
+	c.ok(res)
+}
+
+func indexOfMessage(messages []*coze.Message, id string) int {
+	for i, message := range messages {
+		if message.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// messageOf returns the message of the query, or writes the error.
+func (s *Server) messageOf(c *serverCall) (*fakeConversation, int) {
+	conv := s.conversationOf(c, c.query("conversation_id"))
+	if conv == nil {
+		return nil, -1
+	}
+	id := c.query("message_id")
+	i := indexOfMessage(conv.messages, id)
+	if i < 0 {
+		c.notFound("message", id)
+		return nil, -1
+	}
+	return conv, i
+}
+
+func (s *Server) retrieveMessage(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conv, i := s.messageOf(c); conv != nil {
+		c.data(conv.messages[i])
+	}
+}
+
+func (s *Server) modifyMessage(c *serverCall) {
+	req := &coze.UpdateConversationMessagesReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conv, i := s.messageOf(c)
+	if conv == nil {
+		return
+	}
+	message := conv.messages[i]
+	if req.Content != "" {
+		message.Content = req.Content
+	}
+	if req.ContentType != "" {
+		message.ContentType = req.ContentType
+	}
+	if req.MetaData != nil {
+		message.MetaData = req.MetaData
+	}
+	message.UpdatedAt = now()
+	c.json(http.StatusOK, map[string]interface{}{"code": 0, "msg": "", "message": message})
+}
+
+func (s *Server) deleteMessage(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conv, i := s.messageOf(c)
+	if conv == nil {
+		return
+	}
+	message := conv.messages[i]
+	conv.messages = append(conv.messages[:i:i], conv.messages[i+1:]...)
+	c.data(message)
+}
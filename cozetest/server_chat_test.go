@@ -0,0 +1,268 @@
+package cozetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+var weatherCall = &coze.ChatToolCall{
+	ID: "call_1", Type: "function",
+	Function: &coze.ChatToolCallFunction{Name: "get_weather", Arguments: `{"city":"Paris"}`},
+}
+
+func TestServerChat(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create and poll", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+
+		poll, err := api.Chat.CreateAndPoll(ctx, &coze.CreateChatsReq{
+			BotID: "bot", UserID: "user", Messages: []*coze.Message{coze.BuildUserQuestionText("hello there", nil)},
+		}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, coze.ChatStatusCompleted, poll.Chat.Status)
+		assert.Equal(t, 4, poll.Chat.Usage.TokenCount)
+		require.Len(t, poll.Messages, 1)
+		assert.Equal(t, coze.MessageTypeAnswer, poll.Messages[0].Type)
+		assert.Equal(t, "hello there", poll.Messages[0].Content)
+
+		chat, ok := server.Chat(poll.Chat.ID)
+		require.True(t, ok)
+		assert.Equal(t, coze.ChatStatusCompleted, chat.Status)
+	})
+
+	t.Run("tool calls without stream", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+		server.AddReplies("bot", &Reply{ToolCalls: []*coze.ChatToolCall{weatherCall}})
+
+		chat, err := api.Chat.Create(ctx, &coze.CreateChatsReq{
+			BotID: "bot", UserID: "user", Messages: []*coze.Message{coze.BuildUserQuestionText("weather?", nil)},
+		})
+		require.NoError(t, err)
+		retrieved, err := api.Chat.Retrieve(ctx, &coze.RetrieveChatsReq{ConversationID: chat.ConversationID, ChatID: chat.ID})
+		require.NoError(t, err)
+		require.Equal(t, coze.ChatStatusRequiresAction, retrieved.Status)
+		assert.Equal(t, "get_weather", retrieved.RequiredAction.SubmitToolOutputs.ToolCalls[0].Function.Name)
+
+		_, err = api.Chat.SubmitToolOutputs(ctx, &coze.SubmitToolOutputsChatReq{
+			ConversationID: chat.ConversationID, ChatID: chat.ID,
+			ToolOutputs: []*coze.ToolOutput{{ToolCallID: "call_1", Output: "sunny"}},
+		})
+		require.NoError(t, err)
+		retrieved, err = api.Chat.Retrieve(ctx, &coze.RetrieveChatsReq{ConversationID: chat.ConversationID, ChatID: chat.ID})
+		require.NoError(t, err)
+		assert.Equal(t, coze.ChatStatusCompleted, retrieved.Status)
+		assert.Equal(t, []*coze.ToolOutput{{ToolCallID: "call_1", Output: "sunny"}}, server.ToolOutputs(chat.ID))
+
+		_, err = api.Chat.SubmitToolOutputs(ctx, &coze.SubmitToolOutputsChatReq{
+			ConversationID: chat.ConversationID, ChatID: chat.ID,
+			ToolOutputs: []*coze.ToolOutput{{ToolCallID: "call_1", Output: "sunny"}},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("run with tools", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+		tools := coze.NewToolRegistry()
+		coze.RegisterTool(tools, "get_weather", func(ctx context.Context, args struct{ City string }) (string, error) {
+			return "sunny in " + args.City, nil
+		})
+		req := func() *coze.CreateChatsReq {
+			return &coze.CreateChatsReq{BotID: "bot", UserID: "user", Messages: []*coze.Message{coze.BuildUserQuestionText("weather?", nil)}}
+		}
+		opts := &coze.ToolRunOptions{PollOptions: coze.PollOptions{InitialInterval: 10 * time.Millisecond, BackoffFactor: 1}}
+
+		server.AddReplies("bot", &Reply{ToolCalls: []*coze.ChatToolCall{weatherCall}}, &Reply{Content: "It is sunny"})
+		poll, err := api.Chat.RunWithTools(ctx, req(), tools, opts)
+		require.NoError(t, err)
+		assert.Equal(t, coze.ChatStatusCompleted, poll.Chat.Status)
+		assert.Equal(t, "It is sunny", poll.Messages[len(poll.Messages)-1].Content)
+		assert.Equal(t, []*coze.ToolOutput{{ToolCallID: "call_1", Output: "sunny in Paris"}}, server.ToolOutputs(poll.Chat.ID))
+
+		server.AddReplies("bot", &Reply{ToolCalls: []*coze.ChatToolCall{weatherCall}}, &Reply{Content: "It is sunny"})
+		stream, err := api.Chat.StreamWithTools(ctx, req(), tools, opts)
+		require.NoError(t, err)
+		poll, err = coze.NewChatStreamAccumulator().Consume(ctx, stream)
+		require.NoError(t, err)
+		assert.Equal(t, coze.ChatStatusCompleted, poll.Chat.Status)
+		assert.Equal(t, "It is sunny", poll.Messages[len(poll.Messages)-1].Content)
+		assert.Equal(t, []*coze.ToolOutput{{ToolCallID: "call_1", Output: "sunny in Paris"}}, server.ToolOutputs(poll.Chat.ID))
+	})
+
+	t.Run("tool calls with stream", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+		server.AddReplies("bot",
+			&Reply{ToolCalls: []*coze.ChatToolCall{weatherCall}},
+			&Reply{Deltas: []string{"It is ", "sunny"}, ReasoningContent: "the tool said so"},
+		)
+
+		stream, err := api.Chat.Stream(ctx, &coze.CreateChatsReq{
+			BotID: "bot", UserID: "user", Messages: []*coze.Message{coze.BuildUserQuestionText("weather?", nil)},
+		})
+		require.NoError(t, err)
+		acc := coze.NewChatStreamAccumulator()
+		poll, err := acc.Consume(ctx, stream)
+		require.NoError(t, err)
+		require.Equal(t, coze.ChatStatusRequiresAction, poll.Chat.Status)
+		assert.Equal(t, "call_1", poll.Chat.RequiredAction.SubmitToolOutputs.ToolCalls[0].ID)
+
+		stream, err = api.Chat.StreamSubmitToolOutputs(ctx, &coze.SubmitToolOutputsChatReq{
+			ConversationID: poll.Chat.ConversationID, ChatID: poll.Chat.ID,
+			ToolOutputs: []*coze.ToolOutput{{ToolCallID: "call_1", Output: "sunny"}},
+		})
+		require.NoError(t, err)
+		var deltas []string
+		err = (&coze.ChatEventHandler{
+			OnMessageDelta: func(event *coze.ChatEvent) error {
+				deltas = append(deltas, event.Message.Content)
+				return nil
+			},
+			OnChatCompleted: func(event *coze.ChatEvent) error {
+				assert.Equal(t, poll.Chat.ID, event.Chat.ID)
+				return nil
+			},
+		}).Handle(ctx, stream)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"It is ", "sunny"}, deltas)
+
+		messages, err := api.Chat.Messages.List(ctx, &coze.ListChatsMessagesReq{
+			ConversationID: poll.Chat.ConversationID, ChatID: poll.Chat.ID,
+		})
+		require.NoError(t, err)
+		last := messages.Messages[len(messages.Messages)-1]
+		assert.Equal(t, "It is sunny", last.Content)
+		assert.Equal(t, "the tool said so", last.ReasoningContent)
+	})
+
+	t.Run("failed chat", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.AddReplies("bot", &Reply{Error: &coze.ChatError{Code: 5000, Msg: "model overloaded"}})
+
+		stream, err := server.NewAPI().Chat.Stream(ctx, &coze.CreateChatsReq{BotID: "bot", UserID: "user"})
+		require.NoError(t, err)
+		poll, err := coze.NewChatStreamAccumulator().Consume(ctx, stream)
+		require.NoError(t, err)
+		assert.Equal(t, coze.ChatStatusFailed, poll.Chat.Status)
+		assert.Equal(t, "model overloaded", poll.Chat.LastError.Msg)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+		server.AddReplies("bot", &Reply{Content: "slow", Delay: time.Hour})
+
+		chat, err := api.Chat.Create(ctx, &coze.CreateChatsReq{BotID: "bot", UserID: "user"})
+		require.NoError(t, err)
+		canceled, err := api.Chat.Cancel(ctx, &coze.CancelChatsReq{ConversationID: chat.ConversationID, ChatID: chat.ID})
+		require.NoError(t, err)
+		assert.Equal(t, coze.ChatStatusCancelled, canceled.Status)
+	})
+
+	t.Run("reply func", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.SetReplyFunc(func(botID string, messages []*coze.Message) *Reply {
+			return &Reply{Content: botID + " saw " + messages[len(messages)-1].Content}
+		})
+
+		stream, err := server.NewAPI().Chat.Stream(ctx, &coze.CreateChatsReq{
+			BotID: "bot", UserID: "user", Messages: []*coze.Message{coze.BuildUserQuestionText("hi", nil)},
+		})
+		require.NoError(t, err)
+		acc := coze.NewChatStreamAccumulator()
+		_, err = acc.Consume(ctx, stream)
+		require.NoError(t, err)
+		require.Len(t, acc.CompletedMessages(), 1)
+		assert.Equal(t, "bot saw hi", acc.CompletedMessages()[0].Content)
+	})
+}
+
+func TestServerConversations(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Close()
+	api := server.NewAPI()
+
+	conv, err := api.Conversations.Create(ctx, &coze.CreateConversationsReq{BotID: "bot"})
+	require.NoError(t, err)
+	var ids []string
+	for _, content := range []string{"one", "two", "three", "four", "five"} {
+		message, err := api.Conversations.Messages.Create(ctx, &coze.CreateMessageReq{
+			ConversationID: conv.ID, Role: coze.MessageRoleUser, Content: content,
+			ContentType: coze.MessageContentTypeText,
+		})
+		require.NoError(t, err)
+		ids = append(ids, message.ID)
+	}
+
+	t.Run("list messages by pages", func(t *testing.T) {
+		messages, err := api.Conversations.Messages.List(ctx, &coze.ListConversationsMessagesReq{
+			ConversationID: conv.ID, Limit: 2,
+		})
+		require.NoError(t, err)
+		var contents []string
+		for messages.Next() {
+			contents = append(contents, messages.Current().Content)
+		}
+		require.NoError(t, messages.Err())
+		assert.Equal(t, []string{"five", "four", "three", "two", "one"}, contents)
+	})
+
+	t.Run("modify and delete", func(t *testing.T) {
+		_, err := api.Conversations.Messages.Update(ctx, &coze.UpdateConversationMessagesReq{
+			ConversationID: conv.ID, MessageID: ids[0], Content: "uno",
+		})
+		require.NoError(t, err)
+		message, err := api.Conversations.Messages.Retrieve(ctx, &coze.RetrieveConversationsMessagesReq{
+			ConversationID: conv.ID, MessageID: ids[0],
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "uno", message.Content)
+
+		_, err = api.Conversations.Messages.Delete(ctx, &coze.DeleteConversationsMessagesReq{
+			ConversationID: conv.ID, MessageID: ids[0],
+		})
+		require.NoError(t, err)
+		_, err = api.Conversations.Messages.Retrieve(ctx, &coze.RetrieveConversationsMessagesReq{
+			ConversationID: conv.ID, MessageID: ids[0],
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		cleared, err := api.Conversations.Clear(ctx, &coze.ClearConversationsReq{ConversationID: conv.ID})
+		require.NoError(t, err)
+		assert.Equal(t, conv.ID, cleared.ConversationID)
+		retrieved, err := api.Conversations.Retrieve(ctx, &coze.RetrieveConversationsReq{ConversationID: conv.ID})
+		require.NoError(t, err)
+		assert.NotEqual(t, conv.LastSectionID, retrieved.LastSectionID)
+	})
+
+	t.Run("list conversations", func(t *testing.T) {
+		server.AddConversation("bot", coze.BuildUserQuestionText("seeded", nil))
+		convs, err := api.Conversations.List(ctx, &coze.ListConversationsReq{BotID: "bot", PageSize: 1})
+		require.NoError(t, err)
+		var count int
+		for convs.Next() {
+			count++
+		}
+		require.NoError(t, convs.Err())
+		assert.Equal(t, 2, count)
+	})
+}
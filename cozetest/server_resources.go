@@ -0,0 +1,656 @@
+package cozetest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// AddBot adds a bot to the workspace, published if publish is true.
+func (s *Server) AddBot(spaceID string, bot *coze.Bot, publish bool) *coze.Bot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := *bot
+	if res.BotID == "" {
+		res.BotID = s.newID()
+	}
+	s.addBot(&fakeBot{bot: res, spaceID: spaceID, published: publish})
+	return &res
+}
+
+// AddWorkspace adds a workspace.
+func (s *Server) AddWorkspace(workspace *coze.Workspace) *coze.Workspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := *workspace
+	if res.ID == "" {
+		res.ID = s.newID()
+	}
+	s.workspaces = append(s.workspaces, &res)
+	return &res
+}
+
+// Workspaces returns the workspaces, the personal one first.
+func (s *Server) Workspaces() []*coze.Workspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*coze.Workspace(nil), s.workspaces...)
+}
+
+// AddVoice adds a voice.
+func (s *Server) AddVoice(voice *coze.Voice) *coze.Voice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := *voice
+	if res.VoiceID == "" {
+		res.VoiceID = s.newID()
+	}
+	s.voices = append(s.voices, &res)
+	return &res
+}
+
+// SetUser sets the user returned by Users.Me.
+func (s *Server) SetUser(user *coze.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.user = user
+}
+
+// File returns the content of an uploaded file.
+func (s *Server) File(fileID string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[fileID]
+	if !ok {
+		return nil, false
+	}
+	return file.content, true
+}
+
+type fakeBot struct {
+	bot       coze.Bot
+	spaceID   string
+	published bool
+}
+
+type fakeDataset struct {
+	dataset   coze.Dataset
+	documents []string
+}
+
+type fakeDocument struct {
+	datasetID string
+	document  coze.Document
+	caption   string
+}
+
+type fakeFile struct {
+	info    coze.FileInfo
+	content []byte
+}
+
+func (s *Server) registerResourceRoutes() {
+	s.route(http.MethodPost, "/v1/bot/create", s.createBot)
+	s.route(http.MethodPost, "/v1/bot/update", s.updateBot)
+	s.route(http.MethodPost, "/v1/bot/publish", s.publishBot)
+	s.route(http.MethodGet, "/v1/bot/get_online_info", s.retrieveBot)
+	s.route(http.MethodGet, "/v1/space/published_bots_list", s.listBots)
+
+	s.route(http.MethodPost, "/v1/datasets", s.createDataset)
+	s.route(http.MethodGet, "/v1/datasets", s.listDatasets)
+	s.route(http.MethodPut, "/v1/datasets/{dataset_id}", s.updateDataset)
+	s.route(http.MethodDelete, "/v1/datasets/{dataset_id}", s.deleteDataset)
+	s.route(http.MethodPost, "/v1/datasets/{dataset_id}/process", s.processDocuments)
+	s.route(http.MethodPost, "/open_api/knowledge/document/create", s.createDocuments)
+	s.route(http.MethodPost, "/open_api/knowledge/document/update", s.updateDocument)
+	s.route(http.MethodPost, "/open_api/knowledge/document/delete", s.deleteDocuments)
+	s.route(http.MethodPost, "/open_api/knowledge/document/list", s.listDocuments)
+	s.route(http.MethodPut, "/v1/datasets/{dataset_id}/images/{document_id}", s.updateImage)
+	s.route(http.MethodGet, "/v1/datasets/{dataset_id}/images", s.listImages)
+
+	s.route(http.MethodPost, "/v1/files/upload", s.uploadFile)
+	s.route(http.MethodPost, "/v1/files/retrieve", s.retrieveFile)
+
+	s.route(http.MethodPost, "/v1/audio/speech", s.createSpeech)
+	s.route(http.MethodGet, "/v1/audio/voices", s.listVoices)
+	s.route(http.MethodPost, "/v1/audio/voices/clone", s.cloneVoice)
+	s.route(http.MethodPost, "/v1/audio/rooms", s.createRoom)
+
+	s.route(http.MethodGet, "/v1/workspaces", s.listWorkspaces)
+	s.route(http.MethodGet, "/v1/users/me", s.me)
+}
+
+func (s *Server) addBot(bot *fakeBot) {
+	s.bots[bot.bot.BotID] = bot
+	s.botOrder = append(s.botOrder, bot.bot.BotID)
+}
+
+func (s *Server) botOf(c *serverCall, id string) *fakeBot {
+	bot, ok := s.bots[id]
+	if !ok {
+		c.notFound("bot", id)
+	}
+	return bot
+}
+
+func (s *Server) createBot(c *serverCall) {
+	req := &coze.CreateBotsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bot := &fakeBot{spaceID: req.SpaceID, bot: coze.Bot{
+		BotID: s.newID(), Name: req.Name, Description: req.Description, CreateTime: now(), UpdateTime: now(),
+		PromptInfo: req.PromptInfo, OnboardingInfo: req.OnboardingInfo, BotMode: coze.BotModeSingleAgentWorkflow,
+	}}
+	if req.ModelInfoConfig != nil {
+		bot.bot.ModelInfo = &coze.BotModelInfo{ModelID: req.ModelInfoConfig.ModelID}
+	}
+	s.addBot(bot)
+	c.data(map[string]string{"bot_id": bot.bot.BotID})
+}
+
+func (s *Server) updateBot(c *serverCall) {
+	req := &coze.UpdateBotsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bot := s.botOf(c, req.BotID)
+	if bot == nil {
+		return
+	}
+	if req.Name != "" {
+		bot.bot.Name = req.Name
+	}
+	if req.Description != "" {
+		bot.bot.Description = req.Description
+	}
+	if req.PromptInfo != nil {
+		bot.bot.PromptInfo = req.PromptInfo
+	}
+	if req.OnboardingInfo != nil {
+		bot.bot.OnboardingInfo = req.OnboardingInfo
+	}
+	if req.ModelInfoConfig != nil {
+		bot.bot.ModelInfo = &coze.BotModelInfo{ModelID: req.ModelInfoConfig.ModelID}
+	}
+	bot.bot.UpdateTime = now()
+	c.data(nil)
+}
+
+func (s *Server) publishBot(c *serverCall) {
+	req := &coze.PublishBotsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bot := s.botOf(c, req.BotID)
+	if bot == nil {
+		return
+	}
+	bot.published = true
+	bot.bot.Version = strconv.FormatInt(now(), 10)
+	c.data(map[string]string{"bot_id": bot.bot.BotID, "version": bot.bot.Version})
+}
+
+func (s *Server) retrieveBot(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bot := s.botOf(c, c.query("bot_id"))
+	if bot == nil {
+		return
+	}
+	if !bot.published {
+		c.error(4015, "bot "+bot.bot.BotID+" is not published")
+		return
+	}
+	c.data(bot.bot)
+}
+
+func (s *Server) listBots(c *serverCall) {
+	spaceID := c.query("space_id")
+	s.mu.Lock()
+	var bots []*coze.SimpleBot
+	for _, id := range s.botOrder {
+		if bot := s.bots[id]; bot.spaceID == spaceID && bot.published {
+			bots = append(bots, &coze.SimpleBot{
+				BotID: bot.bot.BotID, BotName: bot.bot.Name, Description: bot.bot.Description,
+				IconURL: bot.bot.IconURL, PublishTime: strconv.FormatInt(bot.bot.UpdateTime, 10),
+			})
+		}
+	}
+	s.mu.Unlock()
+	items, _ := page(bots, c.queryInt("page_index", 1), c.queryInt("page_size", 20))
+	c.data(map[string]interface{}{"space_bots": items, "total": len(bots)})
+}
+
+func (s *Server) datasetOf(c *serverCall, id string) *fakeDataset {
+	dataset, ok := s.datasets[id]
+	if !ok {
+		c.notFound("dataset", id)
+	}
+	return dataset
+}
+
+func (s *Server) createDataset(c *serverCall) {
+	req := &coze.CreateDatasetsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dataset := &fakeDataset{dataset: coze.Dataset{
+		ID: s.newID(), Name: req.Name, Description: req.Description, SpaceID: req.SpaceID,
+		Status: coze.DatasetStatusEnabled, FormatType: req.FormatType, CanEdit: true,
+		CreatorID: s.user.UserID, CreatorName: s.user.UserName, CreateTime: int(now()), UpdateTime: int(now()),
+	}}
+	s.datasets[dataset.dataset.ID] = dataset
+	s.datasetOrder = append(s.datasetOrder, dataset.dataset.ID)
+	c.data(map[string]string{"dataset_id": dataset.dataset.ID})
+}
+
+func (s *Server) listDatasets(c *serverCall) {
+	spaceID, name, formatType := c.query("space_id"), c.query("name"), c.query("format_type")
+	s.mu.Lock()
+	var datasets []*coze.Dataset
+	for _, id := range s.datasetOrder {
+		dataset := s.datasets[id]
+		if dataset.dataset.SpaceID != spaceID || !strings.Contains(dataset.dataset.Name, name) ||
+			(formatType != "" && formatType != strconv.Itoa(int(dataset.dataset.FormatType))) {
+			continue
+		}
+		res := dataset.dataset
+		res.DocCount = len(dataset.documents)
+		datasets = append(datasets, &res)
+	}
+	s.mu.Unlock()
+	items, _ := page(datasets, c.queryInt("page_num", 1), c.queryInt("page_size", 10))
+	c.data(map[string]interface{}{"dataset_list": items, "total_count": len(datasets)})
+}
+
+func (s *Server) updateDataset(c *serverCall) {
+	req := &coze.UpdateDatasetsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dataset := s.datasetOf(c, c.params["dataset_id"])
+	if dataset == nil {
+		return
+	}
+	dataset.dataset.Name, dataset.dataset.Description = req.Name, req.Description
+	dataset.dataset.UpdateTime = int(now())
+	c.data(nil)
+}
+
+func (s *Server) deleteDataset(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dataset := s.datasetOf(c, c.params["dataset_id"])
+	if dataset == nil {
+		return
+	}
+	for _, id := range dataset.documents {
+		delete(s.documents, id)
+	}
+	delete(s.datasets, dataset.dataset.ID)
+	for i, id := range s.datasetOrder {
+		if id == dataset.dataset.ID {
+			s.datasetOrder = append(s.datasetOrder[:i:i], s.datasetOrder[i+1:]...)
+			break
+		}
+	}
+	c.data(nil)
+}
+
+func (s *Server) processDocuments(c *serverCall) {
+	req := &coze.ProcessDocumentsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.datasetOf(c, c.params["dataset_id"]) == nil {
+		return
+	}
+	progress := []*coze.DocumentProgress{}
+	for _, id := range req.DocumentIDs {
+		document, ok := s.documents[id]
+		if !ok {
+			c.notFound("document", id)
+			return
+		}
+		progress = append(progress, &coze.DocumentProgress{
+			DocumentID: id, DocumentName: document.document.Name, Size: document.document.Size,
+			Type: document.document.Type, Status: document.document.Status, Progress: 100,
+		})
+	}
+	c.data(map[string]interface{}{"data": progress})
+}
+
+func (s *Server) createDocuments(c *serverCall) {
+	req := &coze.CreateDatasetsDocumentsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dataset := s.datasetOf(c, strconv.FormatInt(req.DatasetID, 10))
+	if dataset == nil {
+		return
+	}
+	documents := []*coze.Document{}
+	for _, base := range req.DocumentBases {
+		document := &fakeDocument{datasetID: dataset.dataset.ID, document: coze.Document{
+			DocumentID: s.newID(), Name: base.Name, ChunkStrategy: req.ChunkStrategy, FormatType: dataset.dataset.FormatType,
+			Status: coze.DocumentStatusCompleted, CreateTime: int(now()), UpdateTime: int(now()),
+		}}
+		if info := base.SourceInfo; info != nil {
+			if info.FileType != nil {
+				document.document.Type = *info.FileType
+			}
+			if info.FileBase64 != nil {
+				document.document.Size = len(*info.FileBase64) * 3 / 4
+			}
+			if info.WebUrl != nil {
+				document.document.SourceType = coze.DocumentSourceTypeOnlineWeb
+			}
+		}
+		if rule := base.UpdateRule; rule != nil {
+			document.document.UpdateType, document.document.UpdateInterval = rule.UpdateType, rule.UpdateInterval
+		}
+		s.documents[document.document.DocumentID] = document
+		dataset.documents = append(dataset.documents, document.document.DocumentID)
+		res := document.document
+		documents = append(documents, &res)
+	}
+	c.ok(map[string]interface{}{"document_infos": documents})
+}
+
+func (s *Server) documentOf(c *serverCall, id string) *fakeDocument {
+	document, ok := s.documents[id]
+	if !ok {
+		c.notFound("document", id)
+	}
+	return document
+}
+
+func (s *Server) updateDocument(c *serverCall) {
+	req := &coze.UpdateDatasetsDocumentsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	document := s.documentOf(c, strconv.FormatInt(req.DocumentID, 10))
+	if document == nil {
+		return
+	}
+	if req.DocumentName != "" {
+		document.document.Name = req.DocumentName
+	}
+	if rule := req.UpdateRule; rule != nil {
+		document.document.UpdateType, document.document.UpdateInterval = rule.UpdateType, rule.UpdateInterval
+	}
+	document.document.UpdateTime = int(now())
+	c.ok(nil)
+}
+
+func (s *Server) deleteDocuments(c *serverCall) {
+	req := &coze.DeleteDatasetsDocumentsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range req.DocumentIDs {
+		document := s.documentOf(c, strconv.FormatInt(id, 10))
+		if document == nil {
+			return
+		}
+		delete(s.documents, document.document.DocumentID)
+		if dataset, ok := s.datasets[document.datasetID]; ok {
+			for i, docID := range dataset.documents {
+				if docID == document.document.DocumentID {
+					dataset.documents = append(dataset.documents[:i:i], dataset.documents[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	c.ok(nil)
+}
+
+func (s *Server) listDocuments(c *serverCall) {
+	req := &coze.ListDatasetsDocumentsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	dataset := s.datasetOf(c, strconv.FormatInt(req.DatasetID, 10))
+	if dataset == nil {
+		s.mu.Unlock()
+		return
+	}
+	var documents []*coze.Document
+	for _, id := range dataset.documents {
+		res := s.documents[id].document
+		documents = append(documents, &res)
+	}
+	s.mu.Unlock()
+	items, _ := page(documents, req.Page, req.Size)
+	c.ok(map[string]interface{}{"document_infos": items, "total": len(documents)})
+}
+
+// imageOf returns the image document of the dataset, or writes the error.
+func (s *Server) imageOf(c *serverCall) *fakeDocument {
+	dataset := s.datasetOf(c, c.params["dataset_id"])
+	if dataset == nil {
+		return nil
+	}
+	document, ok := s.documents[c.params["document_id"]]
+	if !ok || document.datasetID != dataset.dataset.ID || document.document.FormatType != coze.DocumentFormatTypeImage {
+		c.notFound("image", c.params["document_id"])
+		return nil
+	}
+	return document
+}
+
+func (s *Server) updateImage(c *serverCall) {
+	req := &coze.UpdateDatasetImageReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	document := s.imageOf(c)
+	if document == nil {
+		return
+	}
+	if req.Caption != nil {
+		document.caption = *req.Caption
+	}
+	document.document.UpdateTime = int(now())
+	c.data(nil)
+}
+
+func (s *Server) listImages(c *serverCall) {
+	keyword, hasCaption := c.query("keyword"), c.query("has_caption")
+	s.mu.Lock()
+	dataset := s.datasetOf(c, c.params["dataset_id"])
+	if dataset == nil {
+		s.mu.Unlock()
+		return
+	}
+	var images []*coze.Image
+	for _, id := range dataset.documents {
+		document := s.documents[id]
+		if document.document.FormatType != coze.DocumentFormatTypeImage ||
+			(keyword != "" && !strings.Contains(document.caption, keyword)) ||
+			(hasCaption != "" && hasCaption != strconv.FormatBool(document.caption != "")) {
+			continue
+		}
+		images = append(images, &coze.Image{
+			DocumentID: id, Name: document.document.Name, Size: document.document.Size, Caption: document.caption,
+			FormatType: coze.DocumentFormatTypeImage, Status: coze.ImageStatusCompleted, CreatorID: s.user.UserID,
+			CreateTime: document.document.CreateTime, UpdateTime: document.document.UpdateTime,
+		})
+	}
+	s.mu.Unlock()
+	items, _ := page(images, c.queryInt("page_num", 1), c.queryInt("page_size", 10))
+	c.data(map[string]interface{}{"photo_infos": items, "total_count": len(images)})
+}
+
+// formFile reads the file of a multipart request, or writes the error.
+func (c *serverCall) formFile() (string, []byte, bool) {
+	file, header, err := c.r.FormFile("file")
+	if err != nil {
+		c.error(4000, "invalid file: "+err.Error())
+		return "", nil, false
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.error(4000, "invalid file: "+err.Error())
+		return "", nil, false
+	}
+	return header.Filename, content, true
+}
+
+func (s *Server) uploadFile(c *serverCall) {
+	name, content, ok := c.formFile()
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file := &fakeFile{
+		info:    coze.FileInfo{ID: s.newID(), Bytes: len(content), CreatedAt: int(now()), FileName: name},
+		content: content,
+	}
+	s.files[file.info.ID] = file
+	c.data(file.info)
+}
+
+func (s *Server) retrieveFile(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, ok := s.files[c.query("file_id")]
+	if !ok {
+		c.notFound("file", c.query("file_id"))
+		return
+	}
+	c.data(file.info)
+}
+
+// speechContentTypes are the content types of the audio formats.
+var speechContentTypes = map[coze.AudioFormat]string{
+	coze.AudioFormatMP3:     "audio/mpeg",
+	coze.AudioFormatWAV:     "audio/wav",
+	coze.AudioFormatPCM:     "audio/pcm",
+	coze.AudioFormatOGGOPUS: "audio/ogg",
+	coze.AudioFormatM4A:     "audio/mp4",
+	coze.AudioFormatAAC:     "audio/aac",
+}
+
+// createSpeech returns a fake audio, the input prefixed with the voice and the format.
+func (s *Server) createSpeech(c *serverCall) {
+	req := &coze.CreateAudioSpeechReq{}
+	if !c.decode(req) {
+		return
+	}
+	format := coze.AudioFormatMP3
+	if req.ResponseFormat != nil {
+		format = *req.ResponseFormat
+	}
+	contentType, ok := speechContentTypes[format]
+	if !ok {
+		c.error(4000, "unsupported response format "+string(format))
+		return
+	}
+	c.w.Header().Set("Content-Type", contentType)
+	c.w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(c.w, "fake %s audio of voice %s: %s", format, req.VoiceID, req.Input)
+}
+
+func (s *Server) listVoices(c *serverCall) {
+	filterSystem := c.query("filter_system_voice") == "true"
+	s.mu.Lock()
+	var voices []*coze.Voice
+	for _, voice := range s.voices {
+		if !filterSystem || !voice.IsSystemVoice {
+			voices = append(voices, voice)
+		}
+	}
+	s.mu.Unlock()
+	items, _ := page(voices, c.queryInt("page_num", 1), c.queryInt("page_size", 20))
+	c.data(map[string]interface{}{"voice_list": items})
+}
+
+func (s *Server) cloneVoice(c *serverCall) {
+	if _, _, ok := c.formFile(); !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	voice := &coze.Voice{
+		VoiceID: c.r.FormValue("voice_id"), Name: c.r.FormValue("voice_name"), LanguageCode: c.r.FormValue("language"),
+		PreviewText: c.r.FormValue("preview_text"), CreateTime: int(now()), UpdateTime: int(now()),
+	}
+	if voice.VoiceID == "" {
+		voice.VoiceID = s.newID()
+		s.voices = append(s.voices, voice)
+	} else {
+		found := false
+		for i, existing := range s.voices {
+			if existing.VoiceID == voice.VoiceID {
+				s.voices[i], found = voice, true
+			}
+		}
+		if !found {
+			c.notFound("voice", voice.VoiceID)
+			return
+		}
+	}
+	c.data(map[string]string{"voice_id": voice.VoiceID})
+}
+
+func (s *Server) createRoom(c *serverCall) {
+	req := &coze.CreateAudioRoomsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.botOf(c, req.BotID) == nil {
+		return
+	}
+	uid := req.UID
+	if uid == "" {
+		uid = s.newID()
+	}
+	c.data(map[string]string{"room_id": s.newID(), "app_id": "fake_app", "token": "fake_room_token_" + s.newID(), "uid": uid})
+}
+
+func (s *Server) listWorkspaces(c *serverCall) {
+	s.mu.Lock()
+	workspaces := append([]*coze.Workspace(nil), s.workspaces...)
+	s.mu.Unlock()
+	items, _ := page(workspaces, c.queryInt("page_num", 1), c.queryInt("page_size", 20))
+	c.data(map[string]interface{}{"workspaces": items, "total_count": len(workspaces)})
+}
+
+func (s *Server) me(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.data(s.user)
+}
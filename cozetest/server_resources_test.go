@@ -0,0 +1,207 @@
+package cozetest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+func TestServerBots(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Close()
+	api := server.NewAPI()
+	spaceID := server.Workspaces()[0].ID
+
+	created, err := api.Bots.Create(ctx, &coze.CreateBotsReq{SpaceID: spaceID, Name: "helper"})
+	require.NoError(t, err)
+	_, err = api.Bots.Retrieve(ctx, &coze.RetrieveBotsReq{BotID: created.BotID})
+	require.Error(t, err, "the bot is not published")
+
+	_, err = api.Bots.Update(ctx, &coze.UpdateBotsReq{BotID: created.BotID, Description: "helps"})
+	require.NoError(t, err)
+	published, err := api.Bots.Publish(ctx, &coze.PublishBotsReq{BotID: created.BotID, ConnectorIDs: []string{"1024"}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, published.BotVersion)
+
+	bot, err := api.Bots.Retrieve(ctx, &coze.RetrieveBotsReq{BotID: created.BotID})
+	require.NoError(t, err)
+	assert.Equal(t, "helper", bot.Name)
+	assert.Equal(t, "helps", bot.Description)
+
+	server.AddBot(spaceID, &coze.Bot{Name: "seeded"}, true)
+	server.AddBot(spaceID, &coze.Bot{Name: "draft"}, false)
+	bots, err := api.Bots.List(ctx, &coze.ListBotsReq{SpaceID: spaceID, PageSize: 1})
+	require.NoError(t, err)
+	var names []string
+	for bots.Next() {
+		names = append(names, bots.Current().BotName)
+	}
+	require.NoError(t, bots.Err())
+	assert.Equal(t, []string{"helper", "seeded"}, names)
+}
+
+func TestServerDatasets(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Close()
+	api := server.NewAPI()
+	spaceID := server.Workspaces()[0].ID
+
+	created, err := api.Datasets.Create(ctx, &coze.CreateDatasetsReq{Name: "docs", SpaceID: spaceID})
+	require.NoError(t, err)
+	datasetID, err := strconv.ParseInt(created.DatasetID, 10, 64)
+	require.NoError(t, err)
+
+	documents, err := api.Datasets.Documents.Create(ctx, &coze.CreateDatasetsDocumentsReq{
+		DatasetID: datasetID,
+		DocumentBases: []*coze.DocumentBase{
+			coze.DocumentBaseBuildLocalFile("a.txt", "first", "txt"),
+			coze.DocumentBaseBuildLocalFile("b.txt", "second", "txt"),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, documents.DocumentInfos, 2)
+	firstID, _ := strconv.ParseInt(documents.DocumentInfos[0].DocumentID, 10, 64)
+
+	_, err = api.Datasets.Documents.Update(ctx, &coze.UpdateDatasetsDocumentsReq{DocumentID: firstID, DocumentName: "renamed.txt"})
+	require.NoError(t, err)
+	progress, err := api.Datasets.Process(ctx, &coze.ProcessDocumentsReq{
+		DatasetID: created.DatasetID, DocumentIDs: []string{documents.DocumentInfos[0].DocumentID},
+	})
+	require.NoError(t, err)
+	require.Len(t, progress.Data, 1)
+	assert.Equal(t, "renamed.txt", progress.Data[0].DocumentName)
+	assert.Equal(t, 100, progress.Data[0].Progress)
+
+	_, err = api.Datasets.Documents.Delete(ctx, &coze.DeleteDatasetsDocumentsReq{DocumentIDs: []int64{firstID}})
+	require.NoError(t, err)
+	list, err := api.Datasets.Documents.List(ctx, &coze.ListDatasetsDocumentsReq{DatasetID: datasetID, Size: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.Total())
+
+	datasets, err := api.Datasets.List(ctx, coze.NewListDatasetsReq(spaceID))
+	require.NoError(t, err)
+	require.True(t, datasets.Next())
+	assert.Equal(t, "docs", datasets.Current().Name)
+	assert.Equal(t, 1, datasets.Current().DocCount)
+
+	_, err = api.Datasets.Delete(ctx, &coze.DeleteDatasetsReq{DatasetID: created.DatasetID})
+	require.NoError(t, err)
+	_, err = api.Datasets.Documents.List(ctx, &coze.ListDatasetsDocumentsReq{DatasetID: datasetID})
+	require.Error(t, err)
+}
+
+func TestServerImages(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Close()
+	api := server.NewAPI()
+
+	created, err := api.Datasets.Create(ctx, &coze.CreateDatasetsReq{
+		Name: "photos", SpaceID: server.Workspaces()[0].ID, FormatType: coze.DocumentFormatTypeImage,
+	})
+	require.NoError(t, err)
+	datasetID, _ := strconv.ParseInt(created.DatasetID, 10, 64)
+	documents, err := api.Datasets.Documents.Create(ctx, &coze.CreateDatasetsDocumentsReq{
+		DatasetID: datasetID, FormatType: coze.DocumentFormatTypeImage,
+		DocumentBases: []*coze.DocumentBase{coze.DocumentBaseBuildImage("cat.png", 1), coze.DocumentBaseBuildImage("dog.png", 2)},
+	})
+	require.NoError(t, err)
+
+	caption := "a sleeping cat"
+	_, err = api.Datasets.Images.Update(ctx, &coze.UpdateDatasetImageReq{
+		DatasetID: created.DatasetID, DocumentID: documents.DocumentInfos[0].DocumentID, Caption: &caption,
+	})
+	require.NoError(t, err)
+
+	keyword := "cat"
+	images, err := api.Datasets.Images.List(ctx, &coze.ListDatasetsImagesReq{DatasetID: created.DatasetID, Keyword: &keyword})
+	require.NoError(t, err)
+	assert.Equal(t, 1, images.Total())
+	require.True(t, images.Next())
+	assert.Equal(t, caption, images.Current().Caption)
+}
+
+func TestServerFiles(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Close()
+	api := server.NewAPI()
+
+	uploaded, err := api.Files.Upload(ctx, &coze.UploadFilesReq{File: coze.NewUploadFile(bytes.NewReader([]byte("hello")), "hello.txt")})
+	require.NoError(t, err)
+	assert.Equal(t, "hello.txt", uploaded.FileName)
+	assert.Equal(t, 5, uploaded.Bytes)
+
+	retrieved, err := api.Files.Retrieve(ctx, &coze.RetrieveFilesReq{FileID: uploaded.ID})
+	require.NoError(t, err)
+	assert.Equal(t, uploaded.ID, retrieved.ID)
+	content, ok := server.File(uploaded.ID)
+	require.True(t, ok)
+	assert.Equal(t, "hello", string(content))
+
+	_, err = api.Files.Retrieve(ctx, &coze.RetrieveFilesReq{FileID: "missing"})
+	require.Error(t, err)
+}
+
+func TestServerAudio(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Close()
+	api := server.NewAPI()
+
+	format := coze.AudioFormatWAV
+	speech, err := api.Audio.Speech.Create(ctx, &coze.CreateAudioSpeechReq{Input: "hi", VoiceID: "v1", ResponseFormat: &format})
+	require.NoError(t, err)
+	audio, err := io.ReadAll(speech.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "fake wav audio of voice v1: hi", string(audio))
+
+	server.AddVoice(&coze.Voice{Name: "system", IsSystemVoice: true})
+	cloned, err := api.Audio.Voices.Clone(ctx, &coze.CloneAudioVoicesReq{
+		VoiceName: "mine", File: bytes.NewReader([]byte("sample")), AudioFormat: coze.AudioFormatMP3,
+	})
+	require.NoError(t, err)
+	voices, err := api.Audio.Voices.List(ctx, &coze.ListAudioVoicesReq{FilterSystemVoice: true})
+	require.NoError(t, err)
+	require.True(t, voices.Next())
+	assert.Equal(t, cloned.VoiceID, voices.Current().VoiceID)
+	assert.Equal(t, "mine", voices.Current().Name)
+	assert.False(t, voices.Next())
+
+	bot := server.AddBot(server.Workspaces()[0].ID, &coze.Bot{Name: "voice"}, true)
+	room, err := api.Audio.Rooms.Create(ctx, &coze.CreateAudioRoomsReq{BotID: bot.BotID, UID: "user"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, room.RoomID)
+	assert.Equal(t, "user", room.UID)
+}
+
+func TestServerWorkspacesAndUsers(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer()
+	defer server.Close()
+	api := server.NewAPI()
+
+	server.AddWorkspace(&coze.Workspace{Name: "Team", WorkspaceType: coze.WorkspaceTypeTeam})
+	workspaces, err := api.Workspaces.List(ctx, coze.NewListWorkspaceReq())
+	require.NoError(t, err)
+	var names []string
+	for workspaces.Next() {
+		names = append(names, workspaces.Current().Name)
+	}
+	require.NoError(t, workspaces.Err())
+	assert.Equal(t, []string{"Personal", "Team"}, names)
+
+	server.SetUser(&coze.User{UserID: "42", UserName: "alice"})
+	user, err := api.Users.Me(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.UserName)
+}
@@ -0,0 +1,137 @@
+package cozetest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("records requests", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+
+		user, err := api.Users.Me(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "fake_user", user.UserName)
+		assert.NotEmpty(t, user.LogID())
+
+		requests := server.Requests()
+		require.Len(t, requests, 1)
+		assert.Equal(t, http.MethodGet, requests[0].Method)
+		assert.Equal(t, "/v1/users/me", requests[0].Path)
+		assert.Equal(t, "Bearer "+DefaultAccessToken, requests[0].Header.Get("Authorization"))
+	})
+
+	t.Run("access token", func(t *testing.T) {
+		server := NewServer(WithAccessToken("pat_good"))
+		defer server.Close()
+
+		_, err := server.NewAPI().Users.Me(ctx)
+		require.NoError(t, err)
+
+		api := coze.NewCozeAPI(coze.NewTokenAuth("pat_bad"), coze.WithBaseURL(server.URL))
+		_, err = api.Users.Me(ctx)
+		require.Error(t, err)
+	})
+
+	t.Run("unknown endpoint", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+
+		_, err := server.NewAPI().Workflows.Chat.Stream(ctx, &coze.WorkflowsChatStreamReq{WorkflowID: "1"})
+		require.Error(t, err)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+
+		server.Fail(http.MethodGet, "/v1/users/me", Failure{Code: 4013, Msg: "rate limited", Times: 2})
+		for i := 0; i < 2; i++ {
+			_, err := api.Users.Me(ctx)
+			cozeErr, ok := coze.AsCozeError(err)
+			require.True(t, ok, "%v", err)
+			assert.Equal(t, 4013, cozeErr.Code)
+			assert.Equal(t, "rate limited", cozeErr.Message)
+		}
+		_, err := api.Users.Me(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("fail with retry", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI(coze.WithRetryPolicy(&coze.RetryPolicy{
+			MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond,
+			RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+		}))
+
+		server.Fail("", "", Failure{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Millisecond, Times: 2})
+		_, err := api.Users.Me(ctx)
+		require.NoError(t, err)
+		requests := server.Requests()
+		require.Len(t, requests, 3)
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+
+		server.SetLatency("/v1/users/me", 50*time.Millisecond)
+		start := time.Now()
+		_, err := api.Users.Me(ctx)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = api.Users.Me(timeoutCtx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		start = time.Now()
+		_, err = api.Workspaces.List(ctx, &coze.ListWorkspaceReq{})
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("handle", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+
+		server.Handle(http.MethodGet, "/v1/users/me", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"code":0,"data":{"user_id":"custom"}}`))
+		})
+		user, err := server.NewAPI().Users.Me(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "custom", user.UserID)
+	})
+}
+
+func TestPage(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	res, more := page(items, 1, 2)
+	assert.Equal(t, []int{1, 2}, res)
+	assert.True(t, more)
+
+	res, more = page(items, 3, 2)
+	assert.Equal(t, []int{5}, res)
+	assert.False(t, more)
+
+	res, more = page(items, 4, 2)
+	assert.Empty(t, res)
+	assert.False(t, more)
+}
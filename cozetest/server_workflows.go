@@ -0,0 +1,268 @@
+package cozetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// WorkflowScript is a scripted run of a workflow, see Server.SetWorkflow.
+type WorkflowScript struct {
+	// Messages are the outputs of the nodes, sent in order. The output of a run without stream is
+	// the content of the last one.
+	Messages []*coze.WorkflowEventMessage
+
+	// Interrupt interrupts the run after the messages. Its event id, generated when empty, resumes
+	// the run with the Resume script.
+	Interrupt *coze.WorkflowEventInterrupt
+	Resume    *WorkflowScript
+
+	// Error fails the run after the messages.
+	Error *coze.WorkflowEventError
+
+	// Delay is waited before each event of a stream, and before the end of a run without stream.
+	Delay time.Duration
+}
+
+// SetWorkflow scripts the runs of the workflow. Without script, a run outputs its parameters in
+// JSON from an "End" node, and a resumed run outputs its resume data.
+func (s *Server) SetWorkflow(workflowID string, script *WorkflowScript) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workflows[workflowID] = script
+}
+
+// WorkflowRun returns the history of the run with the given execute id.
+func (s *Server) WorkflowRun(executeID string) (*coze.WorkflowRunHistory, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[executeID]
+	if !ok {
+		return nil, false
+	}
+	s.settleRun(run)
+	res := run.history
+	return &res, true
+}
+
+type fakeRun struct {
+	workflowID string
+	history    coze.WorkflowRunHistory
+	script     *WorkflowScript
+	readyAt    time.Time
+	pending    bool
+}
+
+func (s *Server) registerWorkflowRoutes() {
+	s.route(http.MethodPost, "/v1/workflow/run", s.runWorkflow)
+	s.route(http.MethodPost, "/v1/workflow/stream_run", s.streamRunWorkflow)
+	s.route(http.MethodPost, "/v1/workflow/stream_resume", s.streamResumeWorkflow)
+	s.route(http.MethodGet, "/v1/workflows/{workflow_id}/run_histories/{execute_id}", s.retrieveRunHistory)
+}
+
+func (s *Server) workflowScript(req *coze.RunWorkflowsReq) *WorkflowScript {
+	if script, ok := s.workflows[req.WorkflowID]; ok {
+		return script
+	}
+	output, _ := json.Marshal(req.Parameters)
+	return &WorkflowScript{Messages: []*coze.WorkflowEventMessage{{
+		Content: string(output), NodeTitle: "End", NodeSeqID: "0", NodeIsFinish: true,
+	}}}
+}
+
+func (s *Server) newRun(workflowID, botID string, mode coze.WorkflowRunMode, script *WorkflowScript) *fakeRun {
+	executeID := s.newID()
+	run := &fakeRun{
+		workflowID: workflowID,
+		script:     script,
+		history: coze.WorkflowRunHistory{
+			ExecuteID: executeID, ExecuteStatus: coze.WorkflowExecuteStatusRunning, BotID: botID, RunMode: mode,
+			CreateTime: int(now()), UpdateTime: int(now()), DebugURL: s.debugURL(workflowID, executeID),
+		},
+	}
+	s.runs[executeID] = run
+	return run
+}
+
+func (s *Server) debugURL(workflowID, executeID string) string {
+	return fmt.Sprintf("https://www.coze.com/work_flow?execute_id=%s&workflow_id=%s", executeID, workflowID)
+}
+
+// settleRun ends a run without stream once it is ready.
+func (s *Server) settleRun(run *fakeRun) {
+	if !run.pending || time.Now().Before(run.readyAt) {
+		return
+	}
+	run.pending = false
+	s.endRun(run, run.script)
+}
+
+// endRun records the end of the script in the history of the run.
+func (s *Server) endRun(run *fakeRun, script *WorkflowScript) {
+	run.history.UpdateTime = int(now())
+	switch {
+	case script.Error != nil:
+		run.history.ExecuteStatus = coze.WorkflowExecuteStatusFail
+		run.history.ErrorCode = strconv.Itoa(script.Error.ErrorCode)
+		run.history.ErrorMessage = script.Error.ErrorMessage
+	case script.Interrupt != nil:
+		// an interrupted run is running until it is resumed
+		run.history.ExecuteStatus = coze.WorkflowExecuteStatusRunning
+	default:
+		run.history.ExecuteStatus = coze.WorkflowExecuteStatusSuccess
+		run.history.Output = scriptOutput(script)
+	}
+}
+
+func scriptOutput(script *WorkflowScript) string {
+	if len(script.Messages) == 0 {
+		return ""
+	}
+	return script.Messages[len(script.Messages)-1].Content
+}
+
+func (s *Server) runWorkflow(c *serverCall) {
+	req := &coze.RunWorkflowsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	script := s.workflowScript(req)
+	mode := coze.WorkflowRunModeSynchronous
+	if req.IsAsync {
+		mode = coze.WorkflowRunModeAsynchronous
+	}
+	run := s.newRun(req.WorkflowID, req.BotID, mode, script)
+	if req.IsAsync {
+		run.pending, run.readyAt = true, time.Now().Add(script.Delay)
+		res := &coze.RunWorkflowsResp{ExecuteID: run.history.ExecuteID, DebugURL: run.history.DebugURL}
+		s.mu.Unlock()
+		c.ok(res)
+		return
+	}
+	s.mu.Unlock()
+
+	if sleepRequest(c.r.Context(), script.Delay) != nil {
+		return
+	}
+	s.mu.Lock()
+	s.endRun(run, script)
+	s.mu.Unlock()
+	if script.Error != nil {
+		c.error(script.Error.ErrorCode, script.Error.ErrorMessage)
+		return
+	}
+	c.ok(&coze.RunWorkflowsResp{
+		ExecuteID: run.history.ExecuteID, Data: scriptOutput(script), DebugURL: run.history.DebugURL,
+	})
+}
+
+func (s *Server) streamRunWorkflow(c *serverCall) {
+	req := &coze.RunWorkflowsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	script := s.workflowScript(req)
+	run := s.newRun(req.WorkflowID, req.BotID, coze.WorkflowRunModeStreaming, script)
+	s.mu.Unlock()
+	s.streamWorkflow(c, run, script)
+}
+
+func (s *Server) streamResumeWorkflow(c *serverCall) {
+	req := &coze.ResumeRunWorkflowsReq{}
+	if !c.decode(req) {
+		return
+	}
+	s.mu.Lock()
+	run, ok := s.interrupts[req.EventID]
+	if !ok || run.workflowID != req.WorkflowID {
+		s.mu.Unlock()
+		c.notFound("interrupt event", req.EventID)
+		return
+	}
+	delete(s.interrupts, req.EventID)
+	script := run.script.Resume
+	if script == nil {
+		script = &WorkflowScript{Messages: []*coze.WorkflowEventMessage{{
+			Content: req.ResumeData, NodeTitle: "End", NodeSeqID: "0", NodeIsFinish: true,
+		}}}
+	}
+	run.script = script
+	s.mu.Unlock()
+	s.streamWorkflow(c, run, script)
+}
+
+// streamWorkflow sends the events of the script, and records the end of the run. The messages
+// report the execute id of the run in their ext, and the run still ends when the stream drops.
+func (s *Server) streamWorkflow(c *serverCall, run *fakeRun, script *WorkflowScript) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.mu.Lock()
+			s.endRun(run, script)
+			s.mu.Unlock()
+			panic(r)
+		}
+	}()
+	w := c.stream()
+	id := 0
+	send := func(event coze.WorkflowEventType, data interface{}) error {
+		err := w.send(script.Delay, strconv.Itoa(id), string(event), data)
+		id++
+		return err
+	}
+	for _, message := range script.Messages {
+		res := *message
+		res.Ext = map[string]any{"execute_id": run.history.ExecuteID}
+		for k, v := range message.Ext {
+			res.Ext[k] = v
+		}
+		if send(coze.WorkflowEventTypeMessage, &res) != nil {
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.endRun(run, script)
+	var interrupt *coze.WorkflowEventInterrupt
+	if script.Interrupt != nil {
+		res := *script.Interrupt
+		data := coze.WorkflowEventInterruptData{}
+		if res.InterruptData != nil {
+			data = *res.InterruptData
+		}
+		if data.EventID == "" {
+			data.EventID = s.newID()
+		}
+		res.InterruptData = &data
+		interrupt = &res
+		s.interrupts[data.EventID] = run
+	}
+	debugURL := run.history.DebugURL
+	s.mu.Unlock()
+
+	switch {
+	case script.Error != nil:
+		_ = send(coze.WorkflowEventTypeError, script.Error)
+	case interrupt != nil:
+		_ = send(coze.WorkflowEventTypeInterrupt, interrupt)
+	default:
+		_ = send(coze.WorkflowEventTypeDone, map[string]string{"debug_url": debugURL})
+	}
+}
+
+func (s *Server) retrieveRunHistory(c *serverCall) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[c.params["execute_id"]]
+	if !ok || run.workflowID != c.params["workflow_id"] {
+		c.notFound("workflow run", c.params["execute_id"])
+		return
+	}
+	s.settleRun(run)
+	c.data([]coze.WorkflowRunHistory{run.history})
+}
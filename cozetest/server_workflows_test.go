@@ -0,0 +1,136 @@
+package cozetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/coze-dev/coze-go"
+)
+
+func TestServerWorkflows(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("run", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+
+		resp, err := server.NewAPI().Workflows.Runs.Create(ctx, &coze.RunWorkflowsReq{
+			WorkflowID: "wf", Parameters: map[string]any{"city": "Paris"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `{"city":"Paris"}`, resp.Data)
+		assert.Contains(t, resp.DebugURL, resp.ExecuteID)
+
+		history, ok := server.WorkflowRun(resp.ExecuteID)
+		require.True(t, ok)
+		assert.Equal(t, coze.WorkflowExecuteStatusSuccess, history.ExecuteStatus)
+	})
+
+	t.Run("run failing", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.SetWorkflow("wf", &WorkflowScript{Error: &coze.WorkflowEventError{ErrorCode: 5000, ErrorMessage: "node failed"}})
+
+		_, err := server.NewAPI().Workflows.Runs.Create(ctx, &coze.RunWorkflowsReq{WorkflowID: "wf"})
+		cozeErr, ok := coze.AsCozeError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, 5000, cozeErr.Code)
+	})
+
+	t.Run("async run", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+		server.SetWorkflow("wf", &WorkflowScript{
+			Messages: []*coze.WorkflowEventMessage{{Content: "done", NodeTitle: "End"}},
+			Delay:    50 * time.Millisecond,
+		})
+
+		resp, err := api.Workflows.Runs.Create(ctx, &coze.RunWorkflowsReq{WorkflowID: "wf", IsAsync: true})
+		require.NoError(t, err)
+		retrieve := func() *coze.WorkflowRunHistory {
+			histories, err := api.Workflows.Runs.Histories.Retrieve(ctx, &coze.RetrieveWorkflowsRunsHistoriesReq{
+				WorkflowID: "wf", ExecuteID: resp.ExecuteID,
+			})
+			require.NoError(t, err)
+			require.Len(t, histories.Histories, 1)
+			return histories.Histories[0]
+		}
+		assert.Equal(t, coze.WorkflowExecuteStatusRunning, retrieve().ExecuteStatus)
+		time.Sleep(60 * time.Millisecond)
+		history := retrieve()
+		assert.Equal(t, coze.WorkflowExecuteStatusSuccess, history.ExecuteStatus)
+		assert.Equal(t, "done", history.Output)
+	})
+
+	t.Run("interrupt and resume", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		api := server.NewAPI()
+		server.SetWorkflow("wf", &WorkflowScript{
+			Messages:  []*coze.WorkflowEventMessage{{Content: "which city?", NodeTitle: "Question"}},
+			Interrupt: &coze.WorkflowEventInterrupt{NodeTitle: "Question", InterruptData: &coze.WorkflowEventInterruptData{Type: 2}},
+			Resume: &WorkflowScript{
+				Messages: []*coze.WorkflowEventMessage{{Content: "sunny", NodeTitle: "End", NodeIsFinish: true}},
+			},
+		})
+
+		stream, err := api.Workflows.Runs.Stream(ctx, &coze.RunWorkflowsReq{WorkflowID: "wf"})
+		require.NoError(t, err)
+		acc := coze.NewWorkflowStreamAccumulator()
+		require.NoError(t, acc.Consume(ctx, stream))
+		interrupt := acc.Interrupt()
+		require.NotNil(t, interrupt)
+		assert.Equal(t, 2, interrupt.InterruptData.Type)
+		require.NotEmpty(t, interrupt.InterruptData.EventID)
+
+		stream, err = api.Workflows.Runs.Resume(ctx, &coze.ResumeRunWorkflowsReq{
+			WorkflowID: "wf", EventID: interrupt.InterruptData.EventID, ResumeData: "Paris", InterruptType: 2,
+		})
+		require.NoError(t, err)
+		acc = coze.NewWorkflowStreamAccumulator()
+		require.NoError(t, acc.Consume(ctx, stream))
+		assert.True(t, acc.Done())
+		assert.Equal(t, "sunny", acc.Node("End").Content)
+
+		_, err = api.Workflows.Runs.Resume(ctx, &coze.ResumeRunWorkflowsReq{
+			WorkflowID: "wf", EventID: interrupt.InterruptData.EventID, ResumeData: "Paris", InterruptType: 2,
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("stream error", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.SetWorkflow("wf", &WorkflowScript{Error: &coze.WorkflowEventError{ErrorCode: 5000, ErrorMessage: "node failed"}})
+
+		stream, err := server.NewAPI().Workflows.Runs.Stream(ctx, &coze.RunWorkflowsReq{WorkflowID: "wf"})
+		require.NoError(t, err)
+		acc := coze.NewWorkflowStreamAccumulator()
+		require.NoError(t, acc.Consume(ctx, stream))
+		require.NotNil(t, acc.Error())
+		assert.Equal(t, "node failed", acc.Error().ErrorMessage)
+	})
+
+	t.Run("dropped stream", func(t *testing.T) {
+		server := NewServer()
+		defer server.Close()
+		server.SetWorkflow("wf", &WorkflowScript{Messages: []*coze.WorkflowEventMessage{
+			{Content: "partial", NodeTitle: "Start"},
+			{Content: "result", NodeTitle: "End", NodeIsFinish: true},
+		}})
+		server.Fail("", "/v1/workflow/stream_run", Failure{DropAfterEvents: 1})
+
+		stream, err := server.NewAPI().Workflows.Runs.Stream(ctx, &coze.RunWorkflowsReq{WorkflowID: "wf"},
+			coze.WithCallStreamReconnect(&coze.StreamReconnectPolicy{PollInterval: 10 * time.Millisecond}))
+		require.NoError(t, err)
+		acc := coze.NewWorkflowStreamAccumulator()
+		require.NoError(t, acc.Consume(ctx, stream))
+		assert.Equal(t, "partial", acc.Node("Start").Content)
+		assert.True(t, acc.Done())
+	})
+}
@@ -7,6 +7,29 @@ import (
 	"strconv"
 )
 
+// DatasetsService is the dataset api, see CozeAPI.Datasets.
+type DatasetsService interface {
+	Create(ctx context.Context, req *CreateDatasetsReq, opts ...CallOption) (*CreateDatasetResp, error)
+	List(ctx context.Context, req *ListDatasetsReq, opts ...CallOption) (NumberPaged[Dataset], error)
+	Update(ctx context.Context, req *UpdateDatasetsReq, opts ...CallOption) (*UpdateDatasetsResp, error)
+	Delete(ctx context.Context, req *DeleteDatasetsReq, opts ...CallOption) (*DeleteDatasetsResp, error)
+	Process(ctx context.Context, req *ProcessDocumentsReq, opts ...CallOption) (*ProcessDocumentsResp, error)
+}
+
+// DatasetsAPI is the dataset api and its sub-services.
+type DatasetsAPI struct {
+	DatasetsService
+	Documents DatasetDocumentsService
+	Images    DatasetImagesService
+}
+
+func newDatasetsAPI(core *core) *DatasetsAPI {
+	datasets := newDatasets(core)
+	return &DatasetsAPI{DatasetsService: datasets, Documents: datasets.Documents, Images: datasets.Images}
+}
+
+var _ DatasetsService = (*datasets)(nil)
+
 type datasets struct {
 	client    *core
 	Documents *datasetsDocuments
@@ -21,7 +44,8 @@ func newDatasets(core *core) *datasets {
 	}
 }
 
-func (r *datasets) Create(ctx context.Context, req *CreateDatasetsReq) (*CreateDatasetResp, error) {
+func (r *datasets) Create(ctx context.Context, req *CreateDatasetsReq, opts ...CallOption) (*CreateDatasetResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/datasets"
 	resp := &createDatasetResp{}
@@ -33,7 +57,8 @@ func (r *datasets) Create(ctx context.Context, req *CreateDatasetsReq) (*CreateD
 	return resp.Data, nil
 }
 
-func (r *datasets) List(ctx context.Context, req *ListDatasetsReq) (NumberPaged[Dataset], error) {
+func (r *datasets) List(ctx context.Context, req *ListDatasetsReq, opts ...CallOption) (NumberPaged[Dataset], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.PageSize == 0 {
 		req.PageSize = 10 // 设置默认值为10
 	}
@@ -71,7 +96,8 @@ func (r *datasets) List(ctx context.Context, req *ListDatasetsReq) (NumberPaged[
 		}, req.PageSize, req.PageNum)
 }
 
-func (r *datasets) Update(ctx context.Context, req *UpdateDatasetsReq) (*UpdateDatasetsResp, error) {
+func (r *datasets) Update(ctx context.Context, req *UpdateDatasetsReq, opts ...CallOption) (*UpdateDatasetsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPut
 	uri := fmt.Sprintf("/v1/datasets/%s", req.DatasetID)
 	resp := &updateDatasetResp{}
@@ -84,7 +110,8 @@ func (r *datasets) Update(ctx context.Context, req *UpdateDatasetsReq) (*UpdateD
 	return result, nil
 }
 
-func (r *datasets) Delete(ctx context.Context, req *DeleteDatasetsReq) (*DeleteDatasetsResp, error) {
+func (r *datasets) Delete(ctx context.Context, req *DeleteDatasetsReq, opts ...CallOption) (*DeleteDatasetsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodDelete
 	uri := fmt.Sprintf("/v1/datasets/%s", req.DatasetID)
 	resp := &deleteDatasetResp{}
@@ -97,7 +124,8 @@ func (r *datasets) Delete(ctx context.Context, req *DeleteDatasetsReq) (*DeleteD
 	return result, nil
 }
 
-func (r *datasets) Process(ctx context.Context, req *ProcessDocumentsReq) (*ProcessDocumentsResp, error) {
+func (r *datasets) Process(ctx context.Context, req *ProcessDocumentsReq, opts ...CallOption) (*ProcessDocumentsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := fmt.Sprintf("/v1/datasets/%s/process", req.DatasetID)
 	resp := &processDocumentsResp{}
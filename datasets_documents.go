@@ -6,7 +6,8 @@ import (
 	"net/http"
 )
 
-func (r *datasetsDocuments) Create(ctx context.Context, req *CreateDatasetsDocumentsReq) (*CreateDatasetsDocumentsResp, error) {
+func (r *datasetsDocuments) Create(ctx context.Context, req *CreateDatasetsDocumentsReq, opts ...CallOption) (*CreateDatasetsDocumentsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/open_api/knowledge/document/create"
 	resp := &createDatasetsDocumentsResp{}
@@ -18,7 +19,8 @@ func (r *datasetsDocuments) Create(ctx context.Context, req *CreateDatasetsDocum
 	return resp.CreateDatasetsDocumentsResp, nil
 }
 
-func (r *datasetsDocuments) Update(ctx context.Context, req *UpdateDatasetsDocumentsReq) (*UpdateDatasetsDocumentsResp, error) {
+func (r *datasetsDocuments) Update(ctx context.Context, req *UpdateDatasetsDocumentsReq, opts ...CallOption) (*UpdateDatasetsDocumentsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/open_api/knowledge/document/update"
 	resp := &updateDatasetsDocumentsResp{}
@@ -31,7 +33,8 @@ func (r *datasetsDocuments) Update(ctx context.Context, req *UpdateDatasetsDocum
 	return result, nil
 }
 
-func (r *datasetsDocuments) Delete(ctx context.Context, req *DeleteDatasetsDocumentsReq) (*DeleteDatasetsDocumentsResp, error) {
+func (r *datasetsDocuments) Delete(ctx context.Context, req *DeleteDatasetsDocumentsReq, opts ...CallOption) (*DeleteDatasetsDocumentsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/open_api/knowledge/document/delete"
 	resp := &deleteDatasetsDocumentsResp{}
@@ -44,7 +47,8 @@ func (r *datasetsDocuments) Delete(ctx context.Context, req *DeleteDatasetsDocum
 	return result, nil
 }
 
-func (r *datasetsDocuments) List(ctx context.Context, req *ListDatasetsDocumentsReq) (NumberPaged[Document], error) {
+func (r *datasetsDocuments) List(ctx context.Context, req *ListDatasetsDocumentsReq, opts ...CallOption) (NumberPaged[Document], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.Page == 0 {
 		req.Page = 1
 	}
@@ -73,6 +77,16 @@ func (r *datasetsDocuments) List(ctx context.Context, req *ListDatasetsDocuments
 		}, req.Size, req.Page)
 }
 
+// DatasetDocumentsService is the api of the documents of a dataset, see DatasetsAPI.Documents.
+type DatasetDocumentsService interface {
+	Create(ctx context.Context, req *CreateDatasetsDocumentsReq, opts ...CallOption) (*CreateDatasetsDocumentsResp, error)
+	Update(ctx context.Context, req *UpdateDatasetsDocumentsReq, opts ...CallOption) (*UpdateDatasetsDocumentsResp, error)
+	Delete(ctx context.Context, req *DeleteDatasetsDocumentsReq, opts ...CallOption) (*DeleteDatasetsDocumentsResp, error)
+	List(ctx context.Context, req *ListDatasetsDocumentsReq, opts ...CallOption) (NumberPaged[Document], error)
+}
+
+var _ DatasetDocumentsService = (*datasetsDocuments)(nil)
+
 type datasetsDocuments struct {
 	client          *core
 	commonHeaderOpt []RequestOption
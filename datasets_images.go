@@ -7,6 +7,14 @@ import (
 	"strconv"
 )
 
+// DatasetImagesService is the api of the images of a dataset, see DatasetsAPI.Images.
+type DatasetImagesService interface {
+	Update(ctx context.Context, req *UpdateDatasetImageReq, opts ...CallOption) (*UpdateDatasetImageResp, error)
+	List(ctx context.Context, req *ListDatasetsImagesReq, opts ...CallOption) (NumberPaged[Image], error)
+}
+
+var _ DatasetImagesService = (*datasetsImages)(nil)
+
 type datasetsImages struct {
 	client *core
 }
@@ -17,7 +25,8 @@ func newDatasetsImages(core *core) *datasetsImages {
 	}
 }
 
-func (r *datasetsImages) Update(ctx context.Context, req *UpdateDatasetImageReq) (*UpdateDatasetImageResp, error) {
+func (r *datasetsImages) Update(ctx context.Context, req *UpdateDatasetImageReq, opts ...CallOption) (*UpdateDatasetImageResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPut
 	uri := fmt.Sprintf("/v1/datasets/%s/images/%s", req.DatasetID, req.DocumentID)
 	resp := &updateImageResp{}
@@ -30,7 +39,8 @@ func (r *datasetsImages) Update(ctx context.Context, req *UpdateDatasetImageReq)
 	return result, nil
 }
 
-func (r *datasetsImages) List(ctx context.Context, req *ListDatasetsImagesReq) (NumberPaged[Image], error) {
+func (r *datasetsImages) List(ctx context.Context, req *ListDatasetsImagesReq, opts ...CallOption) (NumberPaged[Image], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.PageSize == 0 {
 		req.PageSize = 10
 	}
@@ -1,14 +1,47 @@
 package coze
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
 )
 
+// The kinds of the failures of the API. The typed errors of the SDK, Error, AuthError,
+// StreamError, ChatFailedError, StreamTimeoutError and StreamProtocolError, match their kind with
+// errors.Is:
+//
+//	if errors.Is(err, coze.ErrRateLimited) {
+//		// back off
+//	}
+var (
+	ErrRateLimited      = errors.New("rate limited")
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrNotFound         = errors.New("not found")
+	ErrInvalidParameter = errors.New("invalid parameter")
+	ErrServer           = errors.New("server error")
+	ErrTimeout          = errors.New("timeout")
+	ErrStreamProtocol   = errors.New("stream protocol error")
+)
+
+// Error is a failure reported by the API, either by the code of a response body, or by the HTTP
+// status of a response which is not an OAuth error, see AuthError.
 type Error struct {
 	Code    int
 	Message string
 	LogID   string
+
+	// HTTPStatusCode is the status of the response, 200 for the failures reported by the code of a
+	// response body or of a stream event. It is 0 for the errors created by NewError.
+	HTTPStatusCode int
+
+	// RawBody is the body of the response, or the data of the stream event.
+	RawBody string
 }
 
 func NewError(code int, msg, logID string) *Error {
@@ -27,6 +60,16 @@ func (e *Error) Error() string {
 		e.LogID)
 }
 
+// Is matches the kind of the error, from its code when it is known and has a kind, see
+// LookupErrorCode, or else from its HTTP status.
+func (e *Error) Is(target error) bool {
+	kind := errorKindOfStatus(e.HTTPStatusCode)
+	if info, ok := LookupErrorCode(e.Code); ok && info.Kind != nil {
+		kind = info.Kind
+	}
+	return kind != nil && kind == target
+}
+
 // AsCozeError checks if the error is of type Error
 func AsCozeError(err error) (*Error, bool) {
 	var cozeErr *Error
@@ -76,7 +119,9 @@ type AuthError struct {
 	ErrorMessage string
 	Param        string
 	LogID        string
-	parent       error
+	// RawBody is the body of the response.
+	RawBody string
+	parent  error
 }
 
 func NewAuthError(error *authErrorFormat, statusCode int, logID string) *AuthError {
@@ -104,6 +149,20 @@ func (e *AuthError) Unwrap() error {
 	return e.parent
 }
 
+// Is matches the kind of the error, from its code or else from its HTTP status.
+func (e *AuthError) Is(target error) bool {
+	kind := errorKindOfStatus(e.HttpCode)
+	switch e.Code {
+	case SlowDown:
+		kind = ErrRateLimited
+	case AccessDenied:
+		kind = ErrPermissionDenied
+	case ExpiredToken:
+		kind = ErrUnauthorized
+	}
+	return kind != nil && kind == target
+}
+
 // AsAuthError 判断错误是否为 CozeAuthError 类型
 func AsAuthError(err error) (*AuthError, bool) {
 	var authErr *AuthError
@@ -112,3 +171,194 @@ func AsAuthError(err error) (*AuthError, bool) {
 	}
 	return nil, false
 }
+
+// StreamTimeoutError is returned by the Recv of a stream which timed out, see
+// WithCallStreamIdleTimeout and WithCallStreamTimeout. It matches context.DeadlineExceeded with
+// errors.Is.
+type StreamTimeoutError struct {
+	// Idle is true if no event was received during the idle timeout, false if the stream exceeded
+	// its total timeout.
+	Idle    bool
+	Timeout time.Duration
+	// LogID and HTTPStatusCode are the ones of the response of the stream.
+	LogID          string
+	HTTPStatusCode int
+}
+
+// Error implements the error interface
+func (e *StreamTimeoutError) Error() string {
+	kind := "stream timeout"
+	if e.Idle {
+		kind = "stream idle timeout"
+	}
+	return fmt.Sprintf("%s exceeded: %s, status=%d, logid=%s", kind, e.Timeout, e.HTTPStatusCode, e.LogID)
+}
+
+// Is makes the error match context.DeadlineExceeded and ErrTimeout.
+func (e *StreamTimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded || target == ErrTimeout
+}
+
+// AsStreamTimeoutError checks if the error is of type StreamTimeoutError
+func AsStreamTimeoutError(err error) (*StreamTimeoutError, bool) {
+	var timeoutErr *StreamTimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr, true
+	}
+	return nil, false
+}
+
+// StreamError is an error reported by the server in the middle of a stream: the error event of a
+// chat, or the Error event of a workflow, which Recv returns after the event. It matches the kind
+// of its code with errors.Is, such as ErrQuotaExceeded, when the code is known to LookupErrorCode.
+type StreamError struct {
+	Code    int
+	Message string
+	// LogID and HTTPStatusCode are the ones of the response of the stream.
+	LogID          string
+	HTTPStatusCode int
+	// RawBody is the data of the error event.
+	RawBody string
+
+	// NodeTitle is the title of the workflow node of the last event before the error, empty for
+	// the chats.
+	NodeTitle string
+	// LastChatEvent and LastWorkflowEvent are the last event received before the error, nil if
+	// there was none.
+	LastChatEvent     *ChatEvent
+	LastWorkflowEvent *WorkflowEvent
+}
+
+// Error implements the error interface
+func (e *StreamError) Error() string {
+	msg := fmt.Sprintf("stream error: code=%d, message=%s, logid=%s", e.Code, e.Message, e.LogID)
+	if e.NodeTitle != "" {
+		msg += ", node=" + e.NodeTitle
+	}
+	return msg
+}
+
+// Is matches the kind of the code of the error.
+func (e *StreamError) Is(target error) bool {
+	info, ok := LookupErrorCode(e.Code)
+	return ok && info.Kind != nil && info.Kind == target
+}
+
+// AsStreamError checks if the error is of type StreamError
+func AsStreamError(err error) (*StreamError, bool) {
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		return streamErr, true
+	}
+	return nil, false
+}
+
+// setLastWorkflowEvent sets the last event of a workflow stream before the error, and the title
+// of its node.
+func (e *StreamError) setLastWorkflowEvent(event *WorkflowEvent) {
+	e.LastWorkflowEvent = event
+	if event == nil {
+		return
+	}
+	switch {
+	case event.Message != nil:
+		e.NodeTitle = event.Message.NodeTitle
+	case event.Interrupt != nil:
+		e.NodeTitle = event.Interrupt.NodeTitle
+	}
+}
+
+// StreamProtocolError is returned by the Recv of a stream whose response or events are malformed.
+// It matches ErrStreamProtocol with errors.Is.
+type StreamProtocolError struct {
+	Message        string
+	LogID          string
+	HTTPStatusCode int
+	// RawBody is the data of the malformed event.
+	RawBody string
+	// Err is the cause of the error, such as the error decoding the event.
+	Err error
+}
+
+// Error implements the error interface
+func (e *StreamProtocolError) Error() string {
+	msg := "stream protocol error: " + e.Message
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg + ", logid=" + e.LogID
+}
+
+// Unwrap returns the cause of the error
+func (e *StreamProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes the error match ErrStreamProtocol.
+func (e *StreamProtocolError) Is(target error) bool {
+	return target == ErrStreamProtocol
+}
+
+// AsStreamProtocolError checks if the error is of type StreamProtocolError
+func AsStreamProtocolError(err error) (*StreamProtocolError, bool) {
+	var protocolErr *StreamProtocolError
+	if errors.As(err, &protocolErr) {
+		return protocolErr, true
+	}
+	return nil, false
+}
+
+// IsRetryable reports whether the call failing with err is worth retrying: rate limits, server
+// errors, timeouts and network errors are transient, the other errors of the API are not. The
+// errors of the API are classified by their code only for the codes known to LookupErrorCode,
+// else by their HTTP status, so that a stream or chat error with another code is not retryable.
+// The errors of a canceled or expired context of the caller are not retryable, unlike the
+// timeouts of the SDK, such as StreamTimeoutError.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer) || errors.Is(err, ErrTimeout) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var cozeErr *Error
+	var authErr *AuthError
+	var streamErr *StreamError
+	var chatErr *ChatFailedError
+	var protocolErr *StreamProtocolError
+	if errors.As(err, &cozeErr) || errors.As(err, &authErr) || errors.As(err, &streamErr) || errors.As(err, &chatErr) ||
+		errors.As(err, &protocolErr) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// errorKindOfStatus returns the kind of the failures with the HTTP status, nil if unknown.
+func errorKindOfStatus(status int) error {
+	switch {
+	case status == http.StatusBadRequest:
+		return ErrInvalidParameter
+	case status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case status == http.StatusForbidden:
+		return ErrPermissionDenied
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusRequestTimeout, status == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return nil
+	}
+}
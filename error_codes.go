@@ -0,0 +1,31 @@
+package coze
+
+// ErrorCodeInfo describes a known code of the Coze API.
+type ErrorCodeInfo struct {
+	Code int
+	// Kind is the kind of the failures with the code, such as ErrRateLimited, nil if it has none.
+	Kind        error
+	Description string
+}
+
+// errorCodes catalogs a few common codes of the Coze API. It is not exhaustive: the errors with
+// another code are classified by the HTTP status of their response, when it tells.
+var errorCodes = map[int]ErrorCodeInfo{
+	4000: {4000, ErrInvalidParameter, "The request parameters are invalid."},
+	4001: {4001, ErrInvalidParameter, "The request parameters are invalid, such as a malformed ID."},
+	4013: {4013, ErrRateLimited, "The request rate exceeds the limit."},
+	4015: {4015, ErrPermissionDenied, "The bot is not published to the API channel."},
+	4016: {4016, nil, "A chat of the conversation is in progress, a conversation runs one chat at a time."},
+	4019: {4019, ErrQuotaExceeded, "The balance of the account is insufficient."},
+	4100: {4100, ErrUnauthorized, "The access token is invalid or expired."},
+	4101: {4101, ErrPermissionDenied, "The access token lacks the permission of the resource."},
+	4200: {4200, ErrNotFound, "The resource does not exist."},
+	5000: {5000, ErrServer, "Internal server error."},
+}
+
+// LookupErrorCode returns the description of a code of the Coze API, if it is one of the few
+// common codes known to the SDK. Most codes are not known, and are reported with ok false.
+func LookupErrorCode(code int) (ErrorCodeInfo, bool) {
+	info, ok := errorCodes[code]
+	return info, ok
+}
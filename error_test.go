@@ -1,11 +1,17 @@
 package coze
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewCozeError(t *testing.T) {
@@ -230,3 +236,99 @@ func TestAsAuthError(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"known code", NewError(4013, "too many requests", "log"), ErrRateLimited},
+		{"known code over status", &Error{Code: 4200, HTTPStatusCode: http.StatusOK}, ErrNotFound},
+		{"quota", NewError(4019, "insufficient balance", "log"), ErrQuotaExceeded},
+		{"unknown code", &Error{Code: 123456, HTTPStatusCode: http.StatusServiceUnavailable}, ErrServer},
+		{"known code without kind", &Error{Code: 4016, HTTPStatusCode: http.StatusTooManyRequests}, ErrRateLimited},
+		{"status", &Error{HTTPStatusCode: http.StatusForbidden}, ErrPermissionDenied},
+		{"wrapped", fmt.Errorf("wrapped: %w", &Error{HTTPStatusCode: http.StatusGatewayTimeout}), ErrTimeout},
+		{"auth status", &AuthError{HttpCode: http.StatusUnauthorized, Code: "invalid_token"}, ErrUnauthorized},
+		{"auth code", &AuthError{HttpCode: http.StatusBadRequest, Code: SlowDown}, ErrRateLimited},
+		{"stream timeout", &StreamTimeoutError{Idle: true}, ErrTimeout},
+		{"stream protocol", &StreamProtocolError{Message: "invalid event"}, ErrStreamProtocol},
+	}
+	kinds := []error{
+		ErrRateLimited, ErrQuotaExceeded, ErrUnauthorized, ErrPermissionDenied, ErrNotFound,
+		ErrInvalidParameter, ErrServer, ErrTimeout, ErrStreamProtocol,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, kind := range kinds {
+				assert.Equal(t, kind == tt.kind, errors.Is(tt.err, kind), "%v", kind)
+			}
+		})
+	}
+
+	assert.False(t, errors.Is(NewError(4016, "chat in progress", "log"), ErrInvalidParameter))
+	info, ok := LookupErrorCode(4016)
+	require.True(t, ok)
+	assert.Nil(t, info.Kind)
+	assert.NotEmpty(t, info.Description)
+	_, ok = LookupErrorCode(1)
+	assert.False(t, ok)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"rate limited", NewError(4013, "too many requests", "log"), true},
+		{"server", &Error{HTTPStatusCode: http.StatusBadGateway}, true},
+		{"invalid parameter", NewError(4000, "invalid", "log"), false},
+		{"unknown code", NewError(1, "unknown", "log"), false},
+		{"auth slow down", &AuthError{HttpCode: http.StatusBadRequest, Code: SlowDown}, true},
+		{"auth denied", &AuthError{HttpCode: http.StatusForbidden, Code: AccessDenied}, false},
+		{"stream timeout", &StreamTimeoutError{}, true},
+		{"stream protocol", &StreamProtocolError{}, false},
+		{"canceled", fmt.Errorf("call: %w", context.Canceled), false},
+		{"deadline", context.DeadlineExceeded, false},
+		{"network", &url.Error{Op: "Get", URL: "https://api.coze.com", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{"unexpected eof", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestStreamProtocolError(t *testing.T) {
+	cause := errors.New("unexpected end of JSON input")
+	err := &StreamProtocolError{Message: "invalid conversation.message.delta event", LogID: "log", RawBody: "{", Err: cause}
+	assert.Equal(t, "stream protocol error: invalid conversation.message.delta event: unexpected end of JSON input, logid=log", err.Error())
+	assert.ErrorIs(t, err, cause)
+
+	protocolErr, ok := AsStreamProtocolError(fmt.Errorf("wrapped: %w", err))
+	require.True(t, ok)
+	assert.Equal(t, "{", protocolErr.RawBody)
+}
+
+func TestStreamError(t *testing.T) {
+	err := &StreamError{Code: 4019, Message: "insufficient balance", LogID: "log"}
+	assert.Equal(t, "stream error: code=4019, message=insufficient balance, logid=log", err.Error())
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.NotErrorIs(t, err, ErrServer)
+	assert.False(t, IsRetryable(err))
+
+	err = &StreamError{Code: 5000, Message: "node failed", NodeTitle: "LLM"}
+	assert.Equal(t, "stream error: code=5000, message=node failed, logid=, node=LLM", err.Error())
+	assert.True(t, IsRetryable(err))
+
+	streamErr, ok := AsStreamError(fmt.Errorf("wrapped: %w", err))
+	require.True(t, ok)
+	assert.Equal(t, "LLM", streamErr.NodeTitle)
+	_, ok = AsStreamError(errors.New("other"))
+	assert.False(t, ok)
+}
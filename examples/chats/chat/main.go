@@ -105,12 +105,19 @@ func main() {
 	}
 	fmt.Println(chat2)
 
-	// the developer can also set the timeout.
-	pollTimeout := 10
-	chat3, err := cozeCli.Chat.CreateAndPoll(ctx, req, &pollTimeout)
+	// the developer can also bound the polling with the context, and set its intervals.
+	pollCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	chat3, err := cozeCli.Chat.CreateAndPoll(pollCtx, req, &coze.ChatPollOptions{
+		PollOptions: coze.PollOptions{InitialInterval: 500 * time.Millisecond, MaxInterval: 2 * time.Second, BackoffFactor: 2},
+	})
+	if chatErr, ok := coze.AsChatFailedError(err); ok {
+		fmt.Println("Chat failed:", chatErr.LastError)
+		return
+	}
 	if err != nil {
 		fmt.Println("Error in CreateAndPollWithTimeout:", err)
 		return
 	}
-	fmt.Println(chat3)
+	fmt.Println(chat3.Chat.Status, chat3.Polls, chat3.Duration)
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/coze-dev/coze-go"
+)
+
+// This use case teaches you how to chat with a bot over several turns, and save the session to
+// continue it later.
+func main() {
+	// Get an access_token through personal access token or oauth.
+	token := os.Getenv("COZE_API_TOKEN")
+	botID := os.Getenv("PUBLISHED_BOT_ID")
+	userID := os.Getenv("USER_ID")
+
+	authCli := coze.NewTokenAuth(token)
+
+	// Init the Coze client through the access_token.
+	cozeCli := coze.NewCozeAPI(authCli, coze.WithBaseURL(os.Getenv("COZE_API_BASE")))
+
+	ctx := context.Background()
+
+	// The conversation is created with the first message, and the custom variables are sent with
+	// every chat.
+	session := coze.NewChatSession(cozeCli, coze.NewChatSessionParam{
+		BotID:           botID,
+		UserID:          userID,
+		CustomVariables: map[string]string{"name": "Ada"},
+	})
+
+	poll, err := session.Send(ctx, "Recommend me a book")
+	if err != nil {
+		fmt.Println("Error sending message:", err)
+		return
+	}
+	for _, message := range poll.Messages {
+		if message.Type == coze.MessageTypeAnswer {
+			fmt.Println(message.Content)
+		}
+	}
+
+	// Save the session, and restore it to go on with the same conversation.
+	state, err := session.MarshalState()
+	if err != nil {
+		fmt.Println("Error saving session:", err)
+		return
+	}
+	session, err = coze.RestoreChatSession(cozeCli, state)
+	if err != nil {
+		fmt.Println("Error restoring session:", err)
+		return
+	}
+
+	stream, err := session.SendStream(ctx, "Another one, shorter")
+	if err != nil {
+		fmt.Println("Error starting stream:", err)
+		return
+	}
+	err = (&coze.ChatEventHandler{
+		OnMessageDelta: func(event *coze.ChatEvent) error {
+			fmt.Print(event.Message.Content)
+			return nil
+		},
+	}).Handle(ctx, stream)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("\n%d messages in the transcript\n", len(session.Transcript()))
+
+	// Start over: the bot forgets the previous messages of the conversation.
+	if err := session.Reset(ctx); err != nil {
+		fmt.Println("Error resetting session:", err)
+	}
+}
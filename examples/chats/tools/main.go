@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/coze-dev/coze-go"
+)
+
+type weatherArgs struct {
+	Location string `json:"location"`
+}
+
+// This use case teaches you how to answer the local plugins of a bot with Go functions.
+func main() {
+	// Get an access_token through personal access token or oauth.
+	token := os.Getenv("COZE_API_TOKEN")
+	botID := os.Getenv("PUBLISHED_BOT_ID")
+	userID := os.Getenv("USER_ID")
+
+	authCli := coze.NewTokenAuth(token)
+
+	// Init the Coze client through the access_token.
+	cozeCli := coze.NewCozeAPI(authCli, coze.WithBaseURL(os.Getenv("COZE_API_BASE")))
+
+	ctx := context.Background()
+
+	// Register the functions answering the local plugins, by name. The arguments are decoded into
+	// the struct, and the failures are reported to the bot.
+	tools := coze.NewToolRegistry()
+	coze.RegisterTool(tools, "get_weather", func(ctx context.Context, args weatherArgs) (string, error) {
+		return fmt.Sprintf("It is sunny in %s, 25 degrees", args.Location), nil
+	}, coze.WithToolTimeout(10*time.Second))
+
+	req := &coze.CreateChatsReq{
+		BotID:  botID,
+		UserID: userID,
+		Messages: []*coze.Message{
+			coze.BuildUserQuestionText("What's the weather like in Shenzhen today?", nil),
+		},
+	}
+
+	stream, err := cozeCli.Chat.StreamWithTools(ctx, req, tools, &coze.ToolRunOptions{
+		OnToolCall: func(call *coze.ChatToolCall, output *coze.ToolOutput, err error) {
+			fmt.Printf("\ncall %s(%s): %s\n", call.Function.Name, call.Function.Arguments, output.Output)
+		},
+	})
+	if err != nil {
+		fmt.Println("Error starting stream:", err)
+		return
+	}
+	err = (&coze.ChatEventHandler{
+		OnMessageDelta: func(event *coze.ChatEvent) error {
+			fmt.Print(event.Message.Content)
+			return nil
+		},
+		OnChatCompleted: func(event *coze.ChatEvent) error {
+			fmt.Printf("\nToken usage:%d\n", event.Chat.Usage.TokenCount)
+			return nil
+		},
+	}).Handle(ctx, stream)
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
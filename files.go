@@ -6,7 +6,8 @@ import (
 	"net/http"
 )
 
-func (r *files) Upload(ctx context.Context, req *UploadFilesReq) (*UploadFilesResp, error) {
+func (r *files) Upload(ctx context.Context, req *UploadFilesReq, opts ...CallOption) (*UploadFilesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	path := "/v1/files/upload"
 	resp := &uploadFilesResp{}
 	err := r.core.UploadFile(ctx, path, req.File, req.File.Name(), nil, resp)
@@ -18,7 +19,8 @@ func (r *files) Upload(ctx context.Context, req *UploadFilesReq) (*UploadFilesRe
 	return resp.FileInfo, nil
 }
 
-func (r *files) Retrieve(ctx context.Context, req *RetrieveFilesReq) (*RetrieveFilesResp, error) {
+func (r *files) Retrieve(ctx context.Context, req *RetrieveFilesReq, opts ...CallOption) (*RetrieveFilesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/files/retrieve"
 	resp := &retrieveFilesResp{}
@@ -30,6 +32,14 @@ func (r *files) Retrieve(ctx context.Context, req *RetrieveFilesReq) (*RetrieveF
 	return resp.FileInfo, nil
 }
 
+// FilesService is the file api, see CozeAPI.Files.
+type FilesService interface {
+	Upload(ctx context.Context, req *UploadFilesReq, opts ...CallOption) (*UploadFilesResp, error)
+	Retrieve(ctx context.Context, req *RetrieveFilesReq, opts ...CallOption) (*RetrieveFilesResp, error)
+}
+
+var _ FilesService = (*files)(nil)
+
 type files struct {
 	core *core
 }
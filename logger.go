@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
 // Logger ...
@@ -17,6 +18,48 @@ type LevelLogger interface {
 	SetLevel(level LogLevel)
 }
 
+// StructuredLogger is implemented by loggers that accept typed fields, such as the slog adapter.
+// The SDK formats the fields into the message for loggers that only implement Logger.
+type StructuredLogger interface {
+	Logger
+	LogFields(ctx context.Context, level LogLevel, message string, fields ...LogField)
+}
+
+// LogField is a typed attribute of a structured log record.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Keys of the fields attached to the structured logs of the SDK.
+const (
+	LogKeyOperation      = "operation"
+	LogKeyMethod         = "method"
+	LogKeyPath           = "path"
+	LogKeyStatus         = "status"
+	LogKeyCode           = "code"
+	LogKeyLogID          = "log_id"
+	LogKeyLatency        = "latency"
+	LogKeyAttempt        = "attempt"
+	LogKeyConversationID = "conversation_id"
+	LogKeyChatID         = "chat_id"
+	LogKeyWorkflowID     = "workflow_id"
+	LogKeyError          = "error"
+)
+
+// formatLogFields renders the fields as "message, key=value, key=value".
+func formatLogFields(message string, fields []LogField) string {
+	var b strings.Builder
+	b.WriteString(message)
+	for _, field := range fields {
+		b.WriteString(", ")
+		b.WriteString(field.Key)
+		b.WriteString("=")
+		b.WriteString(fmt.Sprint(field.Value))
+	}
+	return b.String()
+}
+
 type LogLevel int
 
 // LogLevelTrace ...
@@ -69,6 +112,9 @@ func (l *stdLogger) Log(ctx context.Context, level LogLevel, message string, arg
 type levelLogger struct {
 	Logger
 	level LogLevel
+
+	// redactor masks the secrets of the messages and fields, nil uses the default redaction
+	redactor *redactor
 }
 
 // NewLevelLogger ...
@@ -86,9 +132,31 @@ func (l *levelLogger) SetLevel(level LogLevel) {
 
 // Log ...
 func (l *levelLogger) Log(ctx context.Context, level LogLevel, message string, args ...interface{}) {
-	if level >= l.level {
-		l.Logger.Log(ctx, level, message, args...)
+	if l == nil {
+		l = &defaultLogger
 	}
+	if level < l.level {
+		return
+	}
+	// the format and args are passed through, the logger formats them
+	l.Logger.Log(ctx, level, l.redactor.redactText(message), l.redactor.redactArgs(args)...)
+}
+
+// LogFields ...
+func (l *levelLogger) LogFields(ctx context.Context, level LogLevel, message string, fields ...LogField) {
+	if l == nil {
+		l = &defaultLogger
+	}
+	if level < l.level {
+		return
+	}
+	message = l.redactor.redactText(message)
+	fields = l.redactor.redactFields(fields)
+	if structured, ok := l.Logger.(StructuredLogger); ok {
+		structured.LogFields(ctx, level, message, fields...)
+		return
+	}
+	l.Logger.Log(ctx, level, formatLogFields(message, fields))
 }
 
 func (l *levelLogger) Debugf(ctx context.Context, message string, args ...interface{}) {
@@ -107,15 +175,24 @@ func (l *levelLogger) Errorf(ctx context.Context, message string, args ...interf
 	l.Log(ctx, LogLevelError, message, args...)
 }
 
-var logger = levelLogger{
+// defaultLogger is used by clients created without WithLogger. It is never modified, every client
+// owns its logger and level, so that several clients can be configured independently.
+var defaultLogger = levelLogger{
 	Logger: newStdLogger(),
 	level:  LogLevelInfo,
 }
 
-func setLogger(l Logger) {
-	logger.Logger = l
-}
-
-func setLevel(level LogLevel) {
-	logger.level = level
+// newClientLogger returns the logger of a client, falling back to the default logger.
+func newClientLogger(l Logger, level LogLevel, redaction *RedactionConfig) *levelLogger {
+	if l == nil {
+		l = defaultLogger.Logger
+	}
+	if level == 0 {
+		level = defaultLogger.level
+	}
+	return &levelLogger{
+		Logger:   l,
+		level:    level,
+		redactor: newRedactor(redaction),
+	}
 }
@@ -0,0 +1,137 @@
+package coze
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogger records the formatted messages it receives
+type captureLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *captureLogger) Log(ctx context.Context, level LogLevel, message string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, "["+level.String()+"] "+fmt.Sprintf(message, args...))
+}
+
+func (l *captureLogger) Messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string{}, l.messages...)
+}
+
+// loggerFunc adapts a function to the Logger interface
+type loggerFunc func(ctx context.Context, level LogLevel, message string, args ...interface{})
+
+func (f loggerFunc) Log(ctx context.Context, level LogLevel, message string, args ...interface{}) {
+	f(ctx, level, message, args...)
+}
+
+func businessErrorClient(t *testing.T, opts ...CozeAPIOption) CozeAPI {
+	transport := &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			return mockResponse(http.StatusOK, &baseResponse{Code: 4000, Msg: "invalid param"})
+		},
+	}
+	opts = append([]CozeAPIOption{WithHttpClient(&http.Client{Transport: transport})}, opts...)
+	return NewCozeAPI(NewTokenAuth("token"), opts...)
+}
+
+func TestClientLogger(t *testing.T) {
+	t.Run("clients keep their own logger and level", func(t *testing.T) {
+		logger1, logger2 := &captureLogger{}, &captureLogger{}
+		api1 := businessErrorClient(t, WithLogger(logger1), WithLogLevel(LogLevelWarn))
+		api2 := businessErrorClient(t, WithLogger(logger2), WithLogLevel(LogLevelError))
+
+		_, err := api1.Users.Me(context.Background())
+		require.Error(t, err)
+		_, err = api2.Users.Me(context.Background())
+		require.Error(t, err)
+
+		require.Len(t, logger1.Messages(), 1)
+		assert.Contains(t, logger1.Messages()[0], "[WARN] request failed")
+		assert.Empty(t, logger2.Messages())
+		assert.Equal(t, LogLevelInfo, defaultLogger.level)
+	})
+
+	t.Run("concurrent clients", func(t *testing.T) {
+		var wg sync.WaitGroup
+		loggers := make([]*captureLogger, 10)
+		for i := range loggers {
+			loggers[i] = &captureLogger{}
+			wg.Add(1)
+			go func(logger *captureLogger, level LogLevel) {
+				defer wg.Done()
+				api := businessErrorClient(t, WithLogger(logger), WithLogLevel(level))
+				_, _ = api.Users.Me(context.Background())
+			}(loggers[i], LogLevel(i%5+1))
+		}
+		wg.Wait()
+		for i, logger := range loggers {
+			if LogLevel(i%5+1) <= LogLevelWarn {
+				assert.NotEmpty(t, logger.Messages())
+			} else {
+				assert.Empty(t, logger.Messages())
+			}
+		}
+	})
+
+	t.Run("stream reader uses the client logger", func(t *testing.T) {
+		core := newCore(&mockHTTP{}, ComBaseURL)
+		core.logger = newClientLogger(&captureLogger{}, LogLevelDebug, nil)
+		resp, err := mockStreamResponse("event: done\ndata: \n")
+		require.NoError(t, err)
+
+		reader := newStreamReader(context.Background(), core, resp, chatEventDecoder(core.logger))
+		assert.Same(t, core.logger, reader.logger)
+	})
+
+	t.Run("oauth client logger", func(t *testing.T) {
+		logger := &captureLogger{}
+		client, err := NewDeviceOAuthClient("client_id", WithAuthLogger(logger), WithAuthLogLevel(LogLevelDebug))
+		require.NoError(t, err)
+		client.core.logger.Debugf(context.Background(), "hello %s", "oauth")
+		assert.Equal(t, []string{"[DEBUG] hello oauth"}, logger.Messages())
+	})
+
+	t.Run("fields are formatted for printf loggers", func(t *testing.T) {
+		logger := &captureLogger{}
+		api := businessErrorClient(t, WithLogger(logger), WithLogLevel(LogLevelDebug))
+		_, err := api.Chat.Retrieve(context.Background(), &RetrieveChatsReq{ConversationID: "conv1", ChatID: "chat1"})
+		require.Error(t, err)
+
+		messages := logger.Messages()
+		require.Len(t, messages, 2)
+		assert.Contains(t, messages[0], "[DEBUG] request attempt, operation=chat.retrieve, method=GET, path=/v3/chat/retrieve")
+		assert.Contains(t, messages[0], "conversation_id=conv1, chat_id=chat1, attempt=1")
+		assert.Contains(t, messages[0], "status=200, log_id=test_log_id")
+		assert.Contains(t, messages[1], "[WARN] request failed, code=4000")
+	})
+
+	t.Run("format and args are passed through", func(t *testing.T) {
+		var gotMessage string
+		var gotArgs []interface{}
+		logger := &levelLogger{Logger: loggerFunc(func(ctx context.Context, level LogLevel, message string, args ...interface{}) {
+			gotMessage, gotArgs = message, args
+		}), level: LogLevelDebug}
+		logger.Infof(context.Background(), "get token %s, status=%d", "Bearer eyJhbGci.eyJpc3Mi.sig", 401)
+		assert.Equal(t, "get token %s, status=%d", gotMessage)
+		assert.Equal(t, []interface{}{"Bearer ***", 401}, gotArgs)
+	})
+
+	t.Run("nil logger falls back to the default logger", func(t *testing.T) {
+		var l *levelLogger
+		assert.NotPanics(t, func() {
+			l.Debugf(context.Background(), "message")
+		})
+	})
+}
@@ -0,0 +1,180 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Metrics receives the measurements of the API calls of the client. The methods are called
+// synchronously by the calls, so they must be fast and safe for concurrent use. See
+// InMemoryMetrics for a Prometheus-style implementation.
+type Metrics interface {
+	// ObserveRequest is called once per API call, after the retries. The latency of streams and
+	// binary responses covers the time to the response headers, see ObserveStream.
+	ObserveRequest(m RequestMetric)
+
+	// ObserveRetry is called before every retry of a request.
+	ObserveRetry(m RetryMetric)
+
+	// ObserveStream is called once the body of a stream is fully read or closed.
+	ObserveStream(m StreamMetric)
+
+	// ObserveTokenUsage is called for every chat or workflow run reporting its token usage, i.e.
+	// completed chats returned by Create, Retrieve, CreateAndPoll or a stream, and workflow runs.
+	ObserveTokenUsage(m TokenUsageMetric)
+}
+
+// Outcomes of the API calls and streams.
+const (
+	OutcomeSuccess      = "success"
+	OutcomeAPIError     = "api_error"     // the server returned a non zero Coze code
+	OutcomeAuthError    = "auth_error"    // the server returned 401 or 403
+	OutcomeHTTPError    = "http_error"    // the server returned another non 200 status
+	OutcomeNetworkError = "network_error" // the request failed without response
+	OutcomeCanceled     = "canceled"      // the context was canceled, or the stream closed early
+	OutcomeTimeout      = "timeout"       // the deadline of the context was exceeded
+)
+
+// RequestMetric is the measurement of an API call.
+type RequestMetric struct {
+	Operation  string
+	Method     string
+	Path       string
+	Outcome    string
+	StatusCode int // zero if there is no response
+	Code       int // the Coze code of the response
+	Latency    time.Duration
+	Err        error
+}
+
+// RetryMetric is the measurement of a retry.
+type RetryMetric struct {
+	Operation string
+	// Attempt is the attempt which failed, starting from 1.
+	Attempt    int
+	StatusCode int // zero if the attempt failed without response
+	Err        error
+}
+
+// StreamMetric is the measurement of a stream.
+type StreamMetric struct {
+	Operation string
+	Outcome   string
+	// Duration is the time from the start of the call to the end of the stream.
+	Duration time.Duration
+	// TimeToFirstEvent is zero if the stream had no event.
+	TimeToFirstEvent time.Duration
+	Events           int
+}
+
+// TokenUsageMetric is the token usage of a chat or a workflow run. Workflow runs only report the
+// total.
+type TokenUsageMetric struct {
+	Operation    string
+	BotID        string
+	WorkflowID   string
+	InputTokens  int
+	OutputTokens int
+	TotalTokens  int
+}
+
+// WithMetrics reports the measurements of the API calls of the client to metrics
+func WithMetrics(metrics Metrics) CozeAPIOption {
+	return func(opt *newCozeAPIOpt) {
+		opt.metrics = metrics
+	}
+}
+
+// callOutcome classifies the result of a call.
+func callOutcome(resp *http.Response, err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	if errors.Is(err, context.Canceled) {
+		return OutcomeCanceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return OutcomeTimeout
+	}
+	status := 0
+	if cozeErr, ok := AsCozeError(err); ok {
+		if cozeErr.HTTPStatusCode == 0 || cozeErr.HTTPStatusCode == http.StatusOK {
+			return OutcomeAPIError
+		}
+		status = cozeErr.HTTPStatusCode
+	}
+	if authErr, ok := AsAuthError(err); ok {
+		status = authErr.HttpCode
+	}
+	if status == http.StatusUnauthorized || status == http.StatusForbidden {
+		return OutcomeAuthError
+	}
+	if status != 0 {
+		return OutcomeHTTPError
+	}
+	if resp != nil && resp.StatusCode != http.StatusOK {
+		return OutcomeHTTPError
+	}
+	return OutcomeNetworkError
+}
+
+// callLabels returns the bot and workflow the request refers to.
+func callLabels(request any) (botID, workflowID string) {
+	switch req := request.(type) {
+	case *CreateChatsReq:
+		return req.BotID, ""
+	case *RunWorkflowsReq:
+		return "", req.WorkflowID
+	case *ResumeRunWorkflowsReq:
+		return "", req.WorkflowID
+	case *WorkflowsChatStreamReq:
+		return ptrValue(req.BotID), req.WorkflowID
+	}
+	return "", ""
+}
+
+// observeUsage reports the token usage of a decoded response.
+func (o *callObserver) observeUsage(response any) {
+	switch resp := response.(type) {
+	case *createChatsResp:
+		if resp.Chat != nil {
+			o.observeChatUsage(&resp.Chat.Chat)
+		}
+	case *retrieveChatsResp:
+		if resp.Chat != nil {
+			o.observeChatUsage(&resp.Chat.Chat)
+		}
+	case *submitToolOutputsChatResp:
+		if resp.Chat != nil {
+			o.observeChatUsage(&resp.Chat.Chat)
+		}
+	case *runWorkflowsResp:
+		if resp.RunWorkflowsResp != nil && resp.Token > 0 {
+			o.metrics.ObserveTokenUsage(TokenUsageMetric{
+				Operation:   o.operation,
+				WorkflowID:  o.workflowID,
+				TotalTokens: resp.Token,
+			})
+		}
+	}
+}
+
+func (o *callObserver) observeChatUsage(chat *Chat) {
+	if chat.Usage == nil {
+		return
+	}
+	botID := chat.BotID
+	if botID == "" {
+		botID = o.botID
+	}
+	o.metrics.ObserveTokenUsage(TokenUsageMetric{
+		Operation:    o.operation,
+		BotID:        botID,
+		WorkflowID:   o.workflowID,
+		InputTokens:  chat.Usage.InputCount,
+		OutputTokens: chat.Usage.OutputCount,
+		TotalTokens:  chat.Usage.TokenCount,
+	})
+}
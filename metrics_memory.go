@@ -0,0 +1,278 @@
+package coze
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the metrics of InMemoryMetrics.
+const (
+	MetricRequestsTotal     = "coze_requests_total"
+	MetricRequestDuration   = "coze_request_duration_seconds"
+	MetricRetriesTotal      = "coze_retries_total"
+	MetricStreamDuration    = "coze_stream_duration_seconds"
+	MetricStreamFirstEvent  = "coze_stream_time_to_first_event_seconds"
+	MetricStreamEventsTotal = "coze_stream_events_total"
+	MetricTokensTotal       = "coze_tokens_total"
+)
+
+const (
+	metricLabelOperation  = "operation"
+	metricLabelOutcome    = "outcome"
+	metricLabelStatus     = "status"
+	metricLabelBotID      = "bot_id"
+	metricLabelWorkflowID = "workflow_id"
+	metricLabelTokenType  = "type"
+
+	metricTokenTypeInput        = "input"
+	metricTokenTypeOutput       = "output"
+	metricTokenTypeTotal        = "total"
+	metricRetryStatusNoResponse = "error"
+)
+
+// DefaultMetricBuckets are the upper bounds, in seconds, of the histogram buckets of
+// InMemoryMetrics. Streams can last minutes, hence the large buckets.
+var DefaultMetricBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// InMemoryMetrics is a Metrics keeping Prometheus-style counters and histograms in memory. It can
+// be inspected in tests, or exposed in the Prometheus text format with WritePrometheus.
+//
+// The metrics are:
+//   - coze_requests_total{operation, outcome} and coze_request_duration_seconds{operation, outcome}
+//   - coze_retries_total{operation, status}, status being "error" for requests without response
+//   - coze_stream_duration_seconds{operation, outcome}, coze_stream_time_to_first_event_seconds{operation}
+//     and coze_stream_events_total{operation}
+//   - coze_tokens_total{operation, bot_id, workflow_id, type}, type being input, output or total
+type InMemoryMetrics struct {
+	buckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]map[string]*counterValue
+	histograms map[string]map[string]*histogramValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // cumulative counts are computed when written
+	count  uint64
+	sum    float64
+}
+
+var _ Metrics = (*InMemoryMetrics)(nil)
+
+// NewInMemoryMetrics creates an InMemoryMetrics, the histograms use DefaultMetricBuckets unless
+// buckets are given.
+func NewInMemoryMetrics(buckets ...float64) *InMemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultMetricBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &InMemoryMetrics{
+		buckets:    buckets,
+		counters:   map[string]map[string]*counterValue{},
+		histograms: map[string]map[string]*histogramValue{},
+	}
+}
+
+// ObserveRequest ...
+func (m *InMemoryMetrics) ObserveRequest(r RequestMetric) {
+	labels := []string{metricLabelOperation, r.Operation, metricLabelOutcome, r.Outcome}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.add(MetricRequestsTotal, labels, 1)
+	m.observe(MetricRequestDuration, labels, r.Latency)
+}
+
+// ObserveRetry ...
+func (m *InMemoryMetrics) ObserveRetry(r RetryMetric) {
+	status := metricRetryStatusNoResponse
+	if r.StatusCode != 0 {
+		status = strconv.Itoa(r.StatusCode)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.add(MetricRetriesTotal, []string{metricLabelOperation, r.Operation, metricLabelStatus, status}, 1)
+}
+
+// ObserveStream ...
+func (m *InMemoryMetrics) ObserveStream(r StreamMetric) {
+	operation := []string{metricLabelOperation, r.Operation}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observe(MetricStreamDuration, []string{metricLabelOperation, r.Operation, metricLabelOutcome, r.Outcome}, r.Duration)
+	if r.Events > 0 {
+		m.observe(MetricStreamFirstEvent, operation, r.TimeToFirstEvent)
+	}
+	m.add(MetricStreamEventsTotal, operation, float64(r.Events))
+}
+
+// ObserveTokenUsage ...
+func (m *InMemoryMetrics) ObserveTokenUsage(r TokenUsageMetric) {
+	labels := func(tokenType string) []string {
+		return []string{
+			metricLabelOperation, r.Operation,
+			metricLabelBotID, r.BotID,
+			metricLabelWorkflowID, r.WorkflowID,
+			metricLabelTokenType, tokenType,
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r.InputTokens > 0 {
+		m.add(MetricTokensTotal, labels(metricTokenTypeInput), float64(r.InputTokens))
+	}
+	if r.OutputTokens > 0 {
+		m.add(MetricTokensTotal, labels(metricTokenTypeOutput), float64(r.OutputTokens))
+	}
+	m.add(MetricTokensTotal, labels(metricTokenTypeTotal), float64(r.TotalTokens))
+}
+
+// Counter returns the value of a counter, labels being name value pairs, e.g.
+// Counter("coze_requests_total", "operation", "chat.create", "outcome", "success").
+func (m *InMemoryMetrics) Counter(name string, labels ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.counters[name][labelsKey(labels)]; ok {
+		return c.value
+	}
+	return 0
+}
+
+// HistogramCount returns the number of observations of a histogram, see Counter for the labels.
+func (m *InMemoryMetrics) HistogramCount(name string, labels ...string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok := m.histograms[name][labelsKey(labels)]; ok {
+		return h.count
+	}
+	return 0
+}
+
+// HistogramSum returns the sum of the observations of a histogram, in seconds.
+func (m *InMemoryMetrics) HistogramSum(name string, labels ...string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok := m.histograms[name][labelsKey(labels)]; ok {
+		return h.sum
+	}
+	return 0
+}
+
+// Reset removes all the observations.
+func (m *InMemoryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters = map[string]map[string]*counterValue{}
+	m.histograms = map[string]map[string]*histogramValue{}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *InMemoryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := &strings.Builder{}
+	for _, name := range sortedKeys(m.counters) {
+		fmt.Fprintf(b, "# TYPE %s counter\n", name)
+		series := m.counters[name]
+		for _, key := range sortedKeys(series) {
+			fmt.Fprintf(b, "%s%s %s\n", name, formatLabels(series[key].labels), formatFloat(series[key].value))
+		}
+	}
+	for _, name := range sortedKeys(m.histograms) {
+		fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+		series := m.histograms[name]
+		for _, key := range sortedKeys(series) {
+			h := series[key]
+			var cumulative uint64
+			for i, bound := range m.buckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(b, "%s_bucket%s %d\n", name, formatLabels(append(h.labels[:len(h.labels):len(h.labels)], "le", formatFloat(bound))), cumulative)
+			}
+			fmt.Fprintf(b, "%s_bucket%s %d\n", name, formatLabels(append(h.labels[:len(h.labels):len(h.labels)], "le", "+Inf")), h.count)
+			fmt.Fprintf(b, "%s_sum%s %s\n", name, formatLabels(h.labels), formatFloat(h.sum))
+			fmt.Fprintf(b, "%s_count%s %d\n", name, formatLabels(h.labels), h.count)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (m *InMemoryMetrics) add(name string, labels []string, value float64) {
+	series, ok := m.counters[name]
+	if !ok {
+		series = map[string]*counterValue{}
+		m.counters[name] = series
+	}
+	key := labelsKey(labels)
+	c, ok := series[key]
+	if !ok {
+		c = &counterValue{labels: labels}
+		series[key] = c
+	}
+	c.value += value
+}
+
+func (m *InMemoryMetrics) observe(name string, labels []string, d time.Duration) {
+	series, ok := m.histograms[name]
+	if !ok {
+		series = map[string]*histogramValue{}
+		m.histograms[name] = series
+	}
+	key := labelsKey(labels)
+	h, ok := series[key]
+	if !ok {
+		h = &histogramValue{labels: labels, counts: make([]uint64, len(m.buckets))}
+		series[key] = h
+	}
+	seconds := d.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func labelsKey(labels []string) string {
+	return strings.Join(labels, "\xff")
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, labels[i]+`="`+labelValueEscaper.Replace(labels[i+1])+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,162 @@
+package coze
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("requests and token usage", func(t *testing.T) {
+		metrics := NewInMemoryMetrics()
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithMetrics(metrics),
+			WithHttpClient(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					switch req.URL.Path {
+					case "/v3/chat/retrieve":
+						return mockResponse(http.StatusOK, &retrieveChatsResp{Chat: &RetrieveChatsResp{Chat: Chat{
+							ID: "chat1", BotID: "bot1", Status: ChatStatusCompleted,
+							Usage: &ChatUsage{InputCount: 10, OutputCount: 5, TokenCount: 15},
+						}}})
+					case "/v1/workflow/run":
+						return mockResponse(http.StatusOK, &runWorkflowsResp{RunWorkflowsResp: &RunWorkflowsResp{Token: 42}})
+					}
+					return mockResponse(http.StatusOK, &baseResponse{Code: 4000, Msg: "invalid param"})
+				},
+			}}),
+		)
+
+		_, err := api.Chat.Retrieve(context.Background(), &RetrieveChatsReq{ConversationID: "conv1", ChatID: "chat1"})
+		require.NoError(t, err)
+		_, err = api.Workflows.Runs.Create(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"})
+		require.NoError(t, err)
+		_, err = api.Users.Me(context.Background())
+		require.Error(t, err)
+
+		assert.Equal(t, float64(1), metrics.Counter(MetricRequestsTotal, "operation", "chat.retrieve", "outcome", OutcomeSuccess))
+		assert.Equal(t, float64(1), metrics.Counter(MetricRequestsTotal, "operation", "users.me", "outcome", OutcomeAPIError))
+		assert.Equal(t, uint64(1), metrics.HistogramCount(MetricRequestDuration, "operation", "workflows.runs.create", "outcome", OutcomeSuccess))
+
+		chatLabels := func(tokenType string) []string {
+			return []string{"operation", "chat.retrieve", "bot_id", "bot1", "workflow_id", "", "type", tokenType}
+		}
+		assert.Equal(t, float64(10), metrics.Counter(MetricTokensTotal, chatLabels("input")...))
+		assert.Equal(t, float64(5), metrics.Counter(MetricTokensTotal, chatLabels("output")...))
+		assert.Equal(t, float64(15), metrics.Counter(MetricTokensTotal, chatLabels("total")...))
+		assert.Equal(t, float64(42), metrics.Counter(MetricTokensTotal,
+			"operation", "workflows.runs.create", "bot_id", "", "workflow_id", "wf1", "type", "total"))
+	})
+
+	t.Run("retries and outcomes", func(t *testing.T) {
+		metrics := NewInMemoryMetrics()
+		attempts := 0
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithMetrics(metrics),
+			WithRetryPolicy(newRetryTestPolicy()),
+			WithHttpClient(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					attempts++
+					if attempts == 1 {
+						return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+					}
+					return mockResponse(http.StatusServiceUnavailable, map[string]string{})
+				},
+			}}),
+		)
+
+		_, err := api.Users.Me(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, float64(1), metrics.Counter(MetricRetriesTotal, "operation", "users.me", "status", "error"))
+		assert.Equal(t, float64(1), metrics.Counter(MetricRetriesTotal, "operation", "users.me", "status", "503"))
+		assert.Equal(t, float64(1), metrics.Counter(MetricRequestsTotal, "operation", "users.me", "outcome", OutcomeHTTPError))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = api.Users.Me(ctx)
+		require.Error(t, err)
+		assert.Equal(t, float64(1), metrics.Counter(MetricRequestsTotal, "operation", "users.me", "outcome", OutcomeCanceled))
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		metrics := NewInMemoryMetrics()
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithMetrics(metrics),
+			WithHttpClient(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{},
+						Body: io.NopCloser(strings.NewReader(`event: conversation.message.delta
+data: {"content":"a"}
+
+event: conversation.chat.completed
+data: {"id":"chat1","bot_id":"bot1","usage":{"token_count":30,"output_count":20,"input_count":10}}
+
+event: done
+data:
+`)),
+					}, nil
+				},
+			}}),
+		)
+
+		stream, err := api.Chat.Stream(context.Background(), &CreateChatsReq{BotID: "bot1"})
+		require.NoError(t, err)
+		for {
+			_, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+		}
+		require.NoError(t, stream.Close())
+
+		assert.Equal(t, uint64(1), metrics.HistogramCount(MetricStreamDuration, "operation", "chat.stream", "outcome", OutcomeSuccess))
+		assert.Equal(t, uint64(1), metrics.HistogramCount(MetricStreamFirstEvent, "operation", "chat.stream"))
+		assert.Equal(t, float64(3), metrics.Counter(MetricStreamEventsTotal, "operation", "chat.stream"))
+		assert.Equal(t, float64(30), metrics.Counter(MetricTokensTotal,
+			"operation", "chat.stream", "bot_id", "bot1", "workflow_id", "", "type", "total"))
+
+		// a stream closed before its end is canceled
+		stream, err = api.Chat.Stream(context.Background(), &CreateChatsReq{BotID: "bot1"})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		assert.Equal(t, uint64(1), metrics.HistogramCount(MetricStreamDuration, "operation", "chat.stream", "outcome", OutcomeCanceled))
+	})
+
+	t.Run("prometheus format", func(t *testing.T) {
+		metrics := NewInMemoryMetrics(0.1, 1)
+		metrics.ObserveRequest(RequestMetric{Operation: "chat.create", Outcome: OutcomeSuccess, Latency: 500e6})
+		metrics.ObserveTokenUsage(TokenUsageMetric{Operation: "chat.create", BotID: `bot"1`, TotalTokens: 7})
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, metrics.WritePrometheus(buf))
+		assert.Equal(t, `# TYPE coze_requests_total counter
+coze_requests_total{operation="chat.create",outcome="success"} 1
+# TYPE coze_tokens_total counter
+coze_tokens_total{operation="chat.create",bot_id="bot\"1",workflow_id="",type="total"} 7
+# TYPE coze_request_duration_seconds histogram
+coze_request_duration_seconds_bucket{operation="chat.create",outcome="success",le="0.1"} 0
+coze_request_duration_seconds_bucket{operation="chat.create",outcome="success",le="1"} 1
+coze_request_duration_seconds_bucket{operation="chat.create",outcome="success",le="+Inf"} 1
+coze_request_duration_seconds_sum{operation="chat.create",outcome="success"} 0.5
+coze_request_duration_seconds_count{operation="chat.create",outcome="success"} 1
+`, buf.String())
+
+		metrics.Reset()
+		assert.Zero(t, metrics.Counter(MetricRequestsTotal, "operation", "chat.create", "outcome", OutcomeSuccess))
+	})
+}
@@ -0,0 +1,58 @@
+package coze
+
+import (
+	"context"
+	"net/http"
+)
+
+// Call describes a single API call passing through the middleware chain.
+type Call struct {
+	// Operation is the logical name of the call, such as "chat.create" or "workflows.runs.stream".
+	Operation string
+
+	// Request is the request struct passed to the service method. It is nil for calls without body,
+	// and holds the extra form fields for file uploads.
+	Request any
+
+	// HTTPRequest is the request that will be sent. Middlewares can modify it, e.g. add headers,
+	// before calling the next handler.
+	HTTPRequest *http.Request
+
+	// HTTPResponse is the raw response, available once the next handler has returned. A middleware
+	// that does not call the next handler must either set it or return an error.
+	HTTPResponse *http.Response
+
+	// Response is the instance the json response is decoded into once the next handler has
+	// returned. It is nil for raw calls, such as streams and binary responses.
+	Response any
+
+	path    string
+	stream  bool
+	rawBody bool // the response body is returned to the caller instead of being decoded
+}
+
+// Handler handles a call, it returns the final error of the call.
+type Handler func(ctx context.Context, call *Call) error
+
+// Middleware wraps a handler to intercept every call made by the client, e.g. for auditing, header
+// injection or fault injection.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware appends middlewares to the client. The first middleware is the outermost one.
+func WithMiddleware(middlewares ...Middleware) CozeAPIOption {
+	return func(opt *newCozeAPIOpt) {
+		opt.middlewares = append(opt.middlewares, middlewares...)
+	}
+}
+
+// handle runs the call through the middleware chain, final being the innermost handler.
+func (c *core) handle(ctx context.Context, call *Call, final Handler) error {
+	ctx, observer := c.observe(ctx, call)
+	h := final
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	err := h(ctx, call)
+	observer.finish(call, err)
+	return err
+}
@@ -0,0 +1,127 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	t.Run("middleware sees the whole call", func(t *testing.T) {
+		var seen []*Call
+		var order []string
+		recorder := func(name string) Middleware {
+			return func(next Handler) Handler {
+				return func(ctx context.Context, call *Call) error {
+					order = append(order, name+" before")
+					call.HTTPRequest.Header.Set("X-Tenant", "tenant1")
+					err := next(ctx, call)
+					order = append(order, name+" after")
+					seen = append(seen, call)
+					return err
+				}
+			}
+		}
+		transport := &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "tenant1", req.Header.Get("X-Tenant"))
+				return mockResponse(http.StatusOK, &createChatsResp{
+					Chat: &CreateChatsResp{Chat: Chat{ID: "chat1"}},
+				})
+			},
+		}
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithHttpClient(&http.Client{Transport: transport}),
+			WithMiddleware(recorder("outer"), recorder("inner")),
+		)
+
+		req := &CreateChatsReq{BotID: "bot1", UserID: "user1"}
+		resp, err := api.Chat.Create(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "chat1", resp.ID)
+		assert.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, order)
+
+		call := seen[0]
+		assert.Equal(t, "chat.create", call.Operation)
+		assert.Equal(t, req, call.Request)
+		assert.Equal(t, http.StatusOK, call.HTTPResponse.StatusCode)
+		chatResp, ok := call.Response.(*createChatsResp)
+		require.True(t, ok)
+		assert.Equal(t, "chat1", chatResp.Chat.ID)
+	})
+
+	t.Run("middleware sees the final error", func(t *testing.T) {
+		var finalErr error
+		core := newCore(&mockHTTP{Error: errors.New("network error")}, ComBaseURL)
+		core.middlewares = []Middleware{func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) error {
+				finalErr = next(ctx, call)
+				return finalErr
+			}
+		}}
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+		require.Error(t, err)
+		assert.Equal(t, err, finalErr)
+	})
+
+	t.Run("fault injection", func(t *testing.T) {
+		injected := errors.New("injected")
+		core := newCore(&mockHTTP{}, ComBaseURL)
+		core.middlewares = []Middleware{func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) error {
+				return injected
+			}
+		}}
+
+		_, err := core.StreamRequest(context.Background(), http.MethodPost, "/v3/chat", nil)
+		assert.Equal(t, injected, err)
+		_, err = core.RawRequest(context.Background(), http.MethodPost, "/v1/audio/speech", nil)
+		assert.Equal(t, injected, err)
+	})
+
+	t.Run("stream and upload calls", func(t *testing.T) {
+		var operations []string
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == "/v1/files/upload" {
+					return mockResponse(http.StatusOK, &uploadFilesResp{FileInfo: &UploadFilesResp{}})
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+					Body:       io.NopCloser(strings.NewReader("event: done\ndata: \n")),
+				}, nil
+			},
+		}}, ComBaseURL)
+		core.middlewares = []Middleware{func(next Handler) Handler {
+			return func(ctx context.Context, call *Call) error {
+				operations = append(operations, call.Operation)
+				return next(ctx, call)
+			}
+		}}
+
+		resp, err := core.StreamRequest(context.Background(), http.MethodPost, "/v1/workflow/stream_run", &RunWorkflowsReq{})
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		err = core.UploadFile(context.Background(), "/v1/files/upload", strings.NewReader("data"), "a.txt", nil, &uploadFilesResp{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"workflows.runs.stream", "files.upload"}, operations)
+	})
+}
+
+func TestOperationName(t *testing.T) {
+	assert.Equal(t, "chat.create", operationName(http.MethodPost, "/v3/chat", false))
+	assert.Equal(t, "chat.stream", operationName(http.MethodPost, "/v3/chat", true))
+	assert.Equal(t, "datasets.update", operationName(http.MethodPut, "/v1/datasets/123", false))
+	assert.Equal(t, "datasets.delete", operationName(http.MethodDelete, "/v1/datasets/123", false))
+	assert.Equal(t, "workflows.runs.histories.retrieve", operationName(http.MethodGet, "/v1/workflows/1/run_histories/2", false))
+	assert.Equal(t, "GET /v1/unknown", operationName(http.MethodGet, "/v1/unknown?a=b", false))
+}
@@ -0,0 +1,221 @@
+package coze
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// callObserver reports a call to the tracer and the metrics of the client. The report of a call
+// whose response body is returned to the caller, such as a stream, ends once the body is fully
+// read or closed.
+type callObserver struct {
+	span       Span
+	metrics    Metrics
+	operation  string
+	botID      string
+	workflowID string
+	start      time.Time
+
+	mu         sync.Mutex
+	ended      bool
+	body       bool
+	stream     bool
+	firstEvent time.Duration
+	events     map[string]int
+	total      int
+}
+
+// observe starts the report of the call, it returns nil if the client has no tracer nor metrics.
+func (c *core) observe(ctx context.Context, call *Call) (context.Context, *callObserver) {
+	if c.tracer == nil && c.metrics == nil {
+		return ctx, nil
+	}
+	o := &callObserver{
+		metrics:   c.metrics,
+		operation: call.Operation,
+		start:     time.Now(),
+	}
+	o.botID, o.workflowID = callLabels(call.Request)
+	if c.tracer != nil {
+		ctx, o.span = c.startSpan(ctx, call)
+	}
+	return ctx, o
+}
+
+// finish records the outcome of the call. The report ends at once, unless the body of the
+// response is returned to the caller.
+func (o *callObserver) finish(call *Call, err error) {
+	if o == nil {
+		return
+	}
+	resp := call.HTTPResponse
+	code, hasCode := 0, false
+	if cozeErr, ok := AsCozeError(err); ok {
+		code, hasCode = cozeErr.Code, true
+	} else if baseResp, ok := call.Response.(baseRespInterface); ok && err == nil {
+		code, hasCode = baseResp.GetCode(), true
+	}
+
+	if o.span != nil {
+		if resp != nil {
+			o.span.SetAttributes(
+				Attribute{Key: AttrHTTPStatusCode, Value: resp.StatusCode},
+				Attribute{Key: AttrLogID, Value: resp.Header.Get(logIDHeader)},
+			)
+		}
+		if hasCode {
+			o.span.SetAttributes(Attribute{Key: AttrCode, Value: code})
+		}
+		if err != nil {
+			o.span.RecordError(err)
+		}
+	}
+	if o.metrics != nil {
+		m := RequestMetric{
+			Operation: o.operation,
+			Method:    call.HTTPRequest.Method,
+			Path:      call.path,
+			Outcome:   callOutcome(resp, err),
+			Code:      code,
+			Latency:   time.Since(o.start),
+			Err:       err,
+		}
+		if resp != nil {
+			m.StatusCode = resp.StatusCode
+		}
+		o.metrics.ObserveRequest(m)
+		if err == nil {
+			o.observeUsage(call.Response)
+		}
+	}
+
+	if err == nil && call.rawBody && resp != nil && resp.Body != nil {
+		resp.Body = &observedBody{ReadCloser: resp.Body, observer: o}
+		o.body = true
+		o.stream = call.stream
+		return
+	}
+	o.end(io.EOF)
+}
+
+// observeEvent counts an event of a stream, and reports the token usage of completed chats.
+func (o *callObserver) observeEvent(event any) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	if o.total == 0 {
+		o.firstEvent = time.Since(o.start)
+	}
+	o.total++
+	if o.events == nil {
+		o.events = map[string]int{}
+	}
+	o.events[streamEventType(event)]++
+	o.mu.Unlock()
+
+	if e, ok := event.(*ChatEvent); ok && o.metrics != nil && e.Chat != nil &&
+		(e.Event == ChatEventConversationChatCompleted || e.Event == ChatEventConversationChatFailed) {
+		o.observeChatUsage(e.Chat)
+	}
+}
+
+// end ends the report. io.EOF means the body was fully read, nil that it was closed before.
+func (o *callObserver) end(err error) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.ended {
+		return
+	}
+	o.ended = true
+	duration := time.Since(o.start)
+
+	if o.span != nil {
+		if err != nil && err != io.EOF {
+			o.span.RecordError(err)
+		}
+		if o.total > 0 {
+			attrs := []Attribute{
+				{Key: AttrStreamFirstEventMs, Value: o.firstEvent.Milliseconds()},
+				{Key: AttrStreamEvents, Value: o.total},
+			}
+			for eventType, count := range o.events {
+				attrs = append(attrs, Attribute{Key: AttrStreamEventsPrefix + eventType, Value: count})
+			}
+			o.span.SetAttributes(attrs...)
+		}
+		if o.body {
+			o.span.SetAttributes(Attribute{Key: AttrStreamDurationMs, Value: duration.Milliseconds()})
+		}
+		o.span.End()
+	}
+	if o.metrics != nil && o.stream {
+		outcome := OutcomeCanceled
+		if err == io.EOF {
+			outcome = OutcomeSuccess
+		} else if err != nil {
+			outcome = callOutcome(nil, err)
+		}
+		o.metrics.ObserveStream(StreamMetric{
+			Operation:        o.operation,
+			Outcome:          outcome,
+			Duration:         duration,
+			TimeToFirstEvent: o.firstEvent,
+			Events:           o.total,
+		})
+	}
+}
+
+// observedBody ends the report of a call once the response body is fully read or closed. The
+// report of a stream is ended by the stream reader instead, since the body may be fully buffered
+// before the last events are parsed.
+type observedBody struct {
+	io.ReadCloser
+	observer *callObserver
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && !b.observer.stream {
+		b.observer.end(err)
+	}
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.observer.end(nil)
+	return err
+}
+
+// streamEventType returns the type of a stream event, used to count the events by type.
+func streamEventType(event any) string {
+	switch e := event.(type) {
+	case *ChatEvent:
+		return string(e.Event)
+	case *WorkflowEvent:
+		return string(e.Event)
+	}
+	return ""
+}
+
+// callObserverOf returns the observer of the call which returned the response, if any.
+func callObserverOf(resp *http.Response) *callObserver {
+	body := resp.Body
+	for {
+		switch b := body.(type) {
+		case *observedBody:
+			return b.observer
+		case *cancelBody:
+			body = b.ReadCloser
+		default:
+			return nil
+		}
+	}
+}
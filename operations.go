@@ -0,0 +1,111 @@
+package coze
+
+import (
+	"net/http"
+	"strings"
+)
+
+// operationRoute maps an endpoint to the logical name of the operation. In pattern, a "*" segment
+// matches any path segment, such as a dataset ID.
+type operationRoute struct {
+	method  string
+	pattern string
+	stream  bool
+	name    string
+}
+
+var operationRoutes = []operationRoute{
+	{http.MethodPost, "/v1/audio/rooms", false, "audio.rooms.create"},
+	{http.MethodPost, "/v1/audio/speech", false, "audio.speech.create"},
+	{http.MethodPost, "/v1/audio/voices/clone", false, "audio.voices.clone"},
+	{http.MethodGet, "/v1/audio/voices", false, "audio.voices.list"},
+	{http.MethodPost, "/v1/bot/create", false, "bots.create"},
+	{http.MethodPost, "/v1/bot/update", false, "bots.update"},
+	{http.MethodPost, "/v1/bot/publish", false, "bots.publish"},
+	{http.MethodGet, "/v1/bot/get_online_info", false, "bots.retrieve"},
+	{http.MethodGet, "/v1/space/published_bots_list", false, "bots.list"},
+	{http.MethodPost, "/v3/chat", false, "chat.create"},
+	{http.MethodPost, "/v3/chat", true, "chat.stream"},
+	{http.MethodPost, "/v3/chat/cancel", false, "chat.cancel"},
+	{http.MethodGet, "/v3/chat/retrieve", false, "chat.retrieve"},
+	{http.MethodPost, "/v3/chat/submit_tool_outputs", false, "chat.submit_tool_outputs"},
+	{http.MethodPost, "/v3/chat/submit_tool_outputs", true, "chat.stream_submit_tool_outputs"},
+	{http.MethodGet, "/v3/chat/message/list", false, "chat.messages.list"},
+	{http.MethodGet, "/v1/conversations", false, "conversations.list"},
+	{http.MethodPost, "/v1/conversation/create", false, "conversations.create"},
+	{http.MethodGet, "/v1/conversation/retrieve", false, "conversations.retrieve"},
+	{http.MethodPost, "/v1/conversations/*/clear", false, "conversations.clear"},
+	{http.MethodPost, "/v1/conversation/message/create", false, "conversations.messages.create"},
+	{http.MethodPost, "/v1/conversation/message/list", false, "conversations.messages.list"},
+	{http.MethodGet, "/v1/conversation/message/retrieve", false, "conversations.messages.retrieve"},
+	{http.MethodPost, "/v1/conversation/message/modify", false, "conversations.messages.update"},
+	{http.MethodPost, "/v1/conversation/message/delete", false, "conversations.messages.delete"},
+	{http.MethodPost, "/v1/datasets", false, "datasets.create"},
+	{http.MethodGet, "/v1/datasets", false, "datasets.list"},
+	{http.MethodPut, "/v1/datasets/*", false, "datasets.update"},
+	{http.MethodDelete, "/v1/datasets/*", false, "datasets.delete"},
+	{http.MethodPost, "/v1/datasets/*/process", false, "datasets.process"},
+	{http.MethodPost, "/open_api/knowledge/document/create", false, "datasets.documents.create"},
+	{http.MethodPost, "/open_api/knowledge/document/update", false, "datasets.documents.update"},
+	{http.MethodPost, "/open_api/knowledge/document/delete", false, "datasets.documents.delete"},
+	{http.MethodPost, "/open_api/knowledge/document/list", false, "datasets.documents.list"},
+	{http.MethodPut, "/v1/datasets/*/images/*", false, "datasets.images.update"},
+	{http.MethodGet, "/v1/datasets/*/images", false, "datasets.images.list"},
+	{http.MethodPost, "/v1/files/upload", false, "files.upload"},
+	{http.MethodGet, "/v1/files/retrieve", false, "files.retrieve"},
+	{http.MethodPost, "/v1/files/retrieve", false, "files.retrieve"},
+	{http.MethodPost, "/v1/templates/*/duplicate", false, "templates.duplicate"},
+	{http.MethodGet, "/v1/users/me", false, "users.me"},
+	{http.MethodPost, "/v1/workflows/chat", true, "workflows.chat.stream"},
+	{http.MethodPost, "/v1/workflow/run", false, "workflows.runs.create"},
+	{http.MethodPost, "/v1/workflow/stream_run", true, "workflows.runs.stream"},
+	{http.MethodPost, "/v1/workflow/stream_resume", true, "workflows.runs.resume"},
+	{http.MethodGet, "/v1/workflows/*/run_histories/*", false, "workflows.runs.histories.retrieve"},
+	{http.MethodGet, "/v1/workspaces", false, "workspaces.list"},
+	{http.MethodPost, "/api/permission/oauth2/token", false, "oauth.token"},
+	{http.MethodPost, "/api/permission/oauth2/account/*/token", false, "oauth.account_token"},
+	{http.MethodPost, "/api/permission/oauth2/device/code", false, "oauth.device_code"},
+	{http.MethodPost, "/api/permission/oauth2/workspace_id/*/device/code", false, "oauth.workspace_device_code"},
+}
+
+// operationName returns the logical name of the call. Unknown endpoints are named after the method
+// and path, e.g. "GET /v1/unknown".
+func operationName(method, path string, stream bool) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for _, route := range operationRoutes {
+		if route.method == method && route.stream == stream && matchRoutePattern(route.pattern, path) {
+			return route.name
+		}
+	}
+	return method + " " + path
+}
+
+// routePattern returns the pattern of the route matching the path, so that the paths embedding IDs
+// map to one endpoint, e.g. "/v1/conversations/*/clear". Unknown paths are returned unchanged.
+func routePattern(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for _, route := range operationRoutes {
+		if matchRoutePattern(route.pattern, path) {
+			return route.pattern
+		}
+	}
+	return path
+}
+
+func matchRoutePattern(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range patternSegments {
+		if segment != "*" && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package coze
+
+import (
+	"context"
+	"time"
+)
+
+// PollOptions configures the polling of an asynchronous operation until its end, such as a chat
+// created by Chat.CreateAndPoll. The interval grows by BackoffFactor after every poll, up to
+// MaxInterval. The polling is bounded by the deadline of the context.
+type PollOptions struct {
+	// InitialInterval is the interval before the first poll.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the interval between two polls.
+	MaxInterval time.Duration
+
+	// BackoffFactor multiplies the interval after every poll, 1 keeps it fixed.
+	BackoffFactor float64
+}
+
+// DefaultPollOptions returns the poll options with sensible defaults: a first poll after 1s, then
+// intervals growing by 1.5 up to 5s.
+func DefaultPollOptions() *PollOptions {
+	return &PollOptions{
+		InitialInterval: time.Second,
+		MaxInterval:     5 * time.Second,
+		BackoffFactor:   1.5,
+	}
+}
+
+// withDefaults returns the options, the unset fields having their default.
+func (o *PollOptions) withDefaults() *PollOptions {
+	res := DefaultPollOptions()
+	if o == nil {
+		return res
+	}
+	if o.InitialInterval > 0 {
+		res.InitialInterval = o.InitialInterval
+	}
+	if o.MaxInterval > 0 {
+		res.MaxInterval = o.MaxInterval
+	}
+	if o.MaxInterval <= 0 && res.MaxInterval < res.InitialInterval {
+		res.MaxInterval = res.InitialInterval
+	}
+	if o.BackoffFactor >= 1 {
+		res.BackoffFactor = o.BackoffFactor
+	}
+	return res
+}
+
+// interval returns the interval before the given poll, counted from 1.
+func (o *PollOptions) interval(poll int) time.Duration {
+	interval := float64(o.InitialInterval)
+	for i := 1; i < poll && interval < float64(o.MaxInterval); i++ {
+		interval *= o.BackoffFactor
+	}
+	if interval > float64(o.MaxInterval) {
+		return o.MaxInterval
+	}
+	return time.Duration(interval)
+}
+
+// PollUntil calls check after every interval of the options until it reports the end of the
+// operation or fails, and returns its last result and the number of polls. It returns the error of
+// the context as soon as it is done. Nil options poll with DefaultPollOptions.
+func PollUntil[T any](ctx context.Context, opts *PollOptions, check func(ctx context.Context) (T, bool, error)) (T, int, error) {
+	opts = opts.withDefaults()
+	var zero T
+	for poll := 1; ; poll++ {
+		if err := sleepContext(ctx, opts.interval(poll)); err != nil {
+			return zero, poll - 1, err
+		}
+		res, done, err := check(ctx)
+		if err != nil {
+			return zero, poll, err
+		}
+		if done {
+			return res, poll, nil
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollOptions(t *testing.T) {
+	opts := DefaultPollOptions()
+	assert.Equal(t, time.Second, opts.interval(1))
+	assert.Equal(t, 1500*time.Millisecond, opts.interval(2))
+	assert.Equal(t, 2250*time.Millisecond, opts.interval(3))
+	assert.Equal(t, 5*time.Second, opts.interval(10))
+
+	assert.Equal(t, DefaultPollOptions(), (*PollOptions)(nil).withDefaults())
+	opts = (&PollOptions{InitialInterval: 10 * time.Second}).withDefaults()
+	assert.Equal(t, 10*time.Second, opts.MaxInterval)
+	assert.Equal(t, 10*time.Second, opts.interval(3))
+	opts = (&PollOptions{InitialInterval: time.Second, BackoffFactor: 1}).withDefaults()
+	assert.Equal(t, time.Second, opts.interval(5))
+}
+
+func TestPollUntil(t *testing.T) {
+	opts := &PollOptions{InitialInterval: time.Millisecond, BackoffFactor: 1}
+
+	t.Run("until done", func(t *testing.T) {
+		res, polls, err := PollUntil(context.Background(), opts, func(ctx context.Context) (int, bool, error) {
+			return 42, true, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 42, res)
+		assert.Equal(t, 1, polls)
+
+		count := 0
+		_, polls, err = PollUntil(context.Background(), opts, func(ctx context.Context) (int, bool, error) {
+			count++
+			return count, count == 3, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, polls)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		failure := errors.New("failure")
+		_, polls, err := PollUntil(context.Background(), opts, func(ctx context.Context) (int, bool, error) {
+			return 0, false, failure
+		})
+		assert.Equal(t, failure, err)
+		assert.Equal(t, 1, polls)
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err := PollUntil(ctx, &PollOptions{InitialInterval: time.Hour}, func(ctx context.Context) (int, bool, error) {
+			return 0, false, nil
+		})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,238 @@
+package coze
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimitConfig bounds the outbound traffic of a client.
+type RateLimitConfig struct {
+	// RequestsPerSecond is the sustained request rate. Zero means the rate is not limited.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that can be sent at once. Defaults to ceil(RequestsPerSecond).
+	Burst int
+
+	// MaxInFlight is the maximum number of concurrent requests. A request stays in flight until its
+	// response body has been read or closed, so streams hold their slot until they are closed.
+	// Zero means the concurrency is not limited.
+	MaxInFlight int
+
+	// PerEndpoint applies the limits to every endpoint separately, e.g. /v3/chat and
+	// /v1/workflow/run each get their own budget. The paths embedding IDs share the budget of
+	// their endpoint, e.g. /v1/conversations/{id}/clear.
+	PerEndpoint bool
+
+	// Adaptive halves the rate every time the server reports a rate limit error and recovers it
+	// linearly over RecoveryPeriod.
+	Adaptive bool
+
+	// MinRequestsPerSecond is the floor of the adaptive rate. Defaults to RequestsPerSecond / 10.
+	MinRequestsPerSecond float64
+
+	// RecoveryPeriod is the time needed to recover from MinRequestsPerSecond back to
+	// RequestsPerSecond. Defaults to 30 seconds.
+	RecoveryPeriod time.Duration
+}
+
+type rateLimiter struct {
+	config RateLimitConfig
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointLimiter
+}
+
+type endpointLimiter struct {
+	bucket *tokenBucket
+	sem    chan struct{}
+}
+
+func newRateLimiter(config *RateLimitConfig) *rateLimiter {
+	if config == nil {
+		return nil
+	}
+	cfg := *config
+	if cfg.RequestsPerSecond > 0 {
+		if cfg.Burst <= 0 {
+			cfg.Burst = int(math.Ceil(cfg.RequestsPerSecond))
+		}
+		if cfg.MinRequestsPerSecond <= 0 || cfg.MinRequestsPerSecond > cfg.RequestsPerSecond {
+			cfg.MinRequestsPerSecond = cfg.RequestsPerSecond / 10
+		}
+		if cfg.RecoveryPeriod <= 0 {
+			cfg.RecoveryPeriod = 30 * time.Second
+		}
+	}
+	return &rateLimiter{
+		config:    cfg,
+		endpoints: map[string]*endpointLimiter{},
+	}
+}
+
+func (l *rateLimiter) endpoint(path string) *endpointLimiter {
+	key := ""
+	if l.config.PerEndpoint {
+		key = routePattern(path)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.endpoints[key]
+	if !ok {
+		e = &endpointLimiter{}
+		if l.config.RequestsPerSecond > 0 {
+			e.bucket = newTokenBucket(&l.config)
+		}
+		if l.config.MaxInFlight > 0 {
+			e.sem = make(chan struct{}, l.config.MaxInFlight)
+		}
+		l.endpoints[key] = e
+	}
+	return e
+}
+
+// acquire blocks until the request is allowed to be sent or the context is done. The returned
+// function releases the in-flight slot and must be called exactly once.
+func (l *rateLimiter) acquire(ctx context.Context, path string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	e := l.endpoint(path)
+	if e.sem != nil {
+		select {
+		case e.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if e.sem != nil {
+			<-e.sem
+		}
+	}
+	if e.bucket != nil {
+		if err := e.bucket.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	var once sync.Once
+	return func() { once.Do(release) }, nil
+}
+
+// observe shrinks the budget of the endpoint when the response reports a rate limit error, it
+// returns whether the budget was shrunk.
+func (l *rateLimiter) observe(path string, resp *http.Response) bool {
+	if l == nil || resp == nil || !l.config.Adaptive || l.config.RequestsPerSecond <= 0 {
+		return false
+	}
+	limited := resp.StatusCode == http.StatusTooManyRequests
+	if !limited && resp.StatusCode == http.StatusOK {
+		if code, ok := peekCozeCode(resp); ok {
+			limited = code == cozeCodeRateLimit
+		}
+	}
+	if limited {
+		l.endpoint(path).bucket.throttle()
+	}
+	return limited
+}
+
+// releaseOnDone releases the in-flight slot once the response body is fully read or closed.
+func releaseOnDone(resp *http.Response, release func()) {
+	if resp == nil || resp.Body == nil {
+		release()
+		return
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *releaseBody) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil {
+		r.release()
+	}
+	return n, err
+}
+
+func (r *releaseBody) Close() error {
+	r.release()
+	return r.ReadCloser.Close()
+}
+
+// tokenBucket is a token bucket whose refill rate can shrink and recover over time.
+type tokenBucket struct {
+	mu         sync.Mutex
+	rate       float64
+	maxRate    float64
+	minRate    float64
+	recoverPer float64 // rate recovered per second
+	burst      float64
+	tokens     float64
+	last       time.Time
+}
+
+func newTokenBucket(config *RateLimitConfig) *tokenBucket {
+	return &tokenBucket{
+		rate:       config.RequestsPerSecond,
+		maxRate:    config.RequestsPerSecond,
+		minRate:    config.MinRequestsPerSecond,
+		recoverPer: (config.RequestsPerSecond - config.MinRequestsPerSecond) / config.RecoveryPeriod.Seconds(),
+		burst:      float64(config.Burst),
+		tokens:     float64(config.Burst),
+		last:       time.Now(),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.last = now
+	if b.rate < b.maxRate {
+		b.rate = math.Min(b.maxRate, b.rate+b.recoverPer*elapsed)
+	}
+	b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		b.refill(time.Now())
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *tokenBucket) throttle() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.rate = math.Max(b.minRate, b.rate/2)
+	if b.tokens > 0 {
+		b.tokens = 0
+	}
+}
+
+func (b *tokenBucket) currentRate() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rate
+}
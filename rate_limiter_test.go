@@ -0,0 +1,146 @@
+package coze
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("nil limiter does not block", func(t *testing.T) {
+		var limiter *rateLimiter
+		release, err := limiter.acquire(context.Background(), "/v3/chat")
+		require.NoError(t, err)
+		release()
+		limiter.observe("/v3/chat", &http.Response{StatusCode: http.StatusTooManyRequests})
+	})
+
+	t.Run("token bucket allows burst then waits", func(t *testing.T) {
+		limiter := newRateLimiter(&RateLimitConfig{RequestsPerSecond: 50, Burst: 2})
+		start := time.Now()
+		for i := 0; i < 4; i++ {
+			release, err := limiter.acquire(context.Background(), "/v3/chat")
+			require.NoError(t, err)
+			release()
+		}
+		// 2 requests from the burst, 2 more at 50/s
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("wait respects context", func(t *testing.T) {
+		limiter := newRateLimiter(&RateLimitConfig{RequestsPerSecond: 0.1, Burst: 1})
+		release, err := limiter.acquire(context.Background(), "/v3/chat")
+		require.NoError(t, err)
+		release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = limiter.acquire(ctx, "/v3/chat")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("max in flight", func(t *testing.T) {
+		limiter := newRateLimiter(&RateLimitConfig{MaxInFlight: 1})
+		release, err := limiter.acquire(context.Background(), "/v3/chat")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = limiter.acquire(ctx, "/v3/chat")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		release()
+		release() // releasing twice is a no-op
+		release, err = limiter.acquire(context.Background(), "/v3/chat")
+		require.NoError(t, err)
+		release()
+	})
+
+	t.Run("per endpoint", func(t *testing.T) {
+		limiter := newRateLimiter(&RateLimitConfig{MaxInFlight: 1, PerEndpoint: true})
+		release1, err := limiter.acquire(context.Background(), "/v3/chat")
+		require.NoError(t, err)
+		defer release1()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		release2, err := limiter.acquire(ctx, "/v1/workflow/run")
+		require.NoError(t, err)
+		release2()
+	})
+
+	t.Run("per endpoint shares the budget of the paths embedding IDs", func(t *testing.T) {
+		limiter := newRateLimiter(&RateLimitConfig{MaxInFlight: 1, PerEndpoint: true})
+		release, err := limiter.acquire(context.Background(), "/v1/conversations/123/clear")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = limiter.acquire(ctx, "/v1/conversations/456/clear")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Len(t, limiter.endpoints, 1)
+		release()
+	})
+
+	t.Run("adaptive shrinks and recovers", func(t *testing.T) {
+		limiter := newRateLimiter(&RateLimitConfig{
+			RequestsPerSecond: 100,
+			Adaptive:          true,
+			RecoveryPeriod:    50 * time.Millisecond,
+		})
+		resp, err := mockResponse(http.StatusTooManyRequests, map[string]string{})
+		require.NoError(t, err)
+		limiter.observe("/v3/chat", resp)
+		bucket := limiter.endpoint("/v3/chat").bucket
+		assert.InDelta(t, 50, bucket.currentRate(), 1)
+
+		resp, err = mockResponse(http.StatusOK, &baseResponse{Code: cozeCodeRateLimit})
+		require.NoError(t, err)
+		resp.Header.Set("Content-Type", "application/json")
+		limiter.observe("/v3/chat", resp)
+		assert.InDelta(t, 25, bucket.currentRate(), 1)
+
+		time.Sleep(60 * time.Millisecond)
+		bucket.mu.Lock()
+		bucket.refill(time.Now())
+		bucket.mu.Unlock()
+		assert.Equal(t, float64(100), bucket.currentRate())
+	})
+}
+
+func TestCoreRateLimit(t *testing.T) {
+	var inFlight, maxInFlight int32
+	core := newCore(&http.Client{Transport: &mockTransport{
+		roundTripFunc: func(req *http.Request) (*http.Response, error) {
+			n := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+			for {
+				m := atomic.LoadInt32(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return mockResponse(http.StatusOK, &TestResponse{})
+		},
+	}}, ComBaseURL)
+	core.rateLimiter = newRateLimiter(&RateLimitConfig{MaxInFlight: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var resp TestResponse
+			assert.NoError(t, core.Request(context.Background(), http.MethodGet, "/test", nil, &resp))
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, maxInFlight, int32(2))
+}
@@ -0,0 +1,241 @@
+package coze
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// RedactionConfig configures the masking of secrets and personal data in the logs of the SDK.
+//
+// A path is a dot separated list of JSON keys, such as "data.access_token". Arrays are transparent,
+// "messages.content" matches the content of every message. A path matches a field when it is a
+// suffix of the path of the field, so "content" matches the content fields at any depth. Keys are
+// case-insensitive, and http header names are matched as single keys.
+//
+// The fields access_token, refresh_token, private_key, client_secret, Authorization and content
+// are masked by default.
+type RedactionConfig struct {
+	// DenyPaths are masked in addition to the default ones.
+	DenyPaths []string
+
+	// AllowPaths are never masked, even if they are denied, e.g. "content" keeps the messages
+	// in the logs.
+	AllowPaths []string
+}
+
+var defaultRedactedPaths = []string{
+	"access_token",
+	"refresh_token",
+	"private_key",
+	"client_secret",
+	"Authorization",
+	"content",
+}
+
+const redactedValue = "***"
+
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s",;]+`)
+
+type redactor struct {
+	deny  [][]string
+	allow [][]string
+
+	// patterns mask the denied keys in text which is not JSON, such as a truncated body
+	jsonKeyPattern *regexp.Regexp
+	textKeyPattern *regexp.Regexp
+}
+
+var defaultRedactor = newRedactor(nil)
+
+func newRedactor(config *RedactionConfig) *redactor {
+	if config == nil {
+		config = &RedactionConfig{}
+	}
+	r := &redactor{
+		deny:  splitPaths(append(append([]string{}, defaultRedactedPaths...), config.DenyPaths...)),
+		allow: splitPaths(config.AllowPaths),
+	}
+
+	keys := []string{}
+	for _, path := range r.deny {
+		key := path[len(path)-1]
+		if !r.allowed([]string{key}) {
+			keys = append(keys, regexp.QuoteMeta(key))
+		}
+	}
+	if len(keys) > 0 {
+		alternation := strings.Join(keys, "|")
+		r.jsonKeyPattern = regexp.MustCompile(`(?i)("(?:` + alternation + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+		r.textKeyPattern = regexp.MustCompile(`(?i)\b((?:` + alternation + `)=)[^,&\s]+`)
+	}
+	return r
+}
+
+func splitPaths(paths []string) [][]string {
+	res := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path != "" {
+			res = append(res, strings.Split(path, "."))
+		}
+	}
+	return res
+}
+
+func matchPaths(patterns [][]string, path []string) bool {
+	for _, pattern := range patterns {
+		if len(pattern) > len(path) {
+			continue
+		}
+		tail := path[len(path)-len(pattern):]
+		matched := true
+		for i := range pattern {
+			if !strings.EqualFold(pattern[i], tail[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) allowed(path []string) bool {
+	return matchPaths(r.allow, path)
+}
+
+func (r *redactor) masked(path []string) bool {
+	return matchPaths(r.deny, path) && !r.allowed(path)
+}
+
+// redactText masks the denied fields of a JSON document, or the denied keys and bearer tokens of
+// any other text.
+func (r *redactor) redactText(text string) string {
+	if r == nil {
+		r = defaultRedactor
+	}
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		if redacted, ok := r.redactJSON([]byte(trimmed)); ok {
+			return redacted
+		}
+	}
+	text = bearerPattern.ReplaceAllString(text, "${1}"+redactedValue)
+	if r.jsonKeyPattern != nil {
+		text = r.jsonKeyPattern.ReplaceAllString(text, `${1}"`+redactedValue+`"`)
+		text = r.textKeyPattern.ReplaceAllString(text, "${1}"+redactedValue)
+	}
+	return text
+}
+
+// redactArgs masks the args of a log message, args of other types than strings, errors and
+// stringers are left untouched.
+func (r *redactor) redactArgs(args []interface{}) []interface{} {
+	if len(args) == 0 {
+		return args
+	}
+	res := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			res[i] = r.redactText(v)
+		case []byte:
+			res[i] = r.redactText(string(v))
+		case error:
+			res[i] = r.redactText(v.Error())
+		case fmt.Stringer:
+			res[i] = r.redactText(v.String())
+		default:
+			res[i] = arg
+		}
+	}
+	return res
+}
+
+func (r *redactor) redactJSON(data []byte) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return "", false
+	}
+	value = r.walk(value, nil)
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+func (r *redactor) walk(value interface{}, path []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := append(path[:len(path):len(path)], key)
+			if r.masked(childPath) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = r.walk(child, childPath)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = r.walk(v[i], path)
+		}
+	case string:
+		// strings may embed JSON documents or headers, such as the data of a workflow
+		return r.redactText(v)
+	}
+	return value
+}
+
+func (r *redactor) redactHeader(header http.Header) http.Header {
+	res := make(http.Header, len(header))
+	for name, values := range header {
+		if r.masked([]string{name}) {
+			res[name] = []string{redactedValue}
+			continue
+		}
+		redacted := make([]string, len(values))
+		for i, value := range values {
+			redacted[i] = r.redactText(value)
+		}
+		res[name] = redacted
+	}
+	return res
+}
+
+// redactFields masks the values of the log fields, fields of other types than strings, errors and
+// headers are left untouched.
+func (r *redactor) redactFields(fields []LogField) []LogField {
+	if r == nil {
+		r = defaultRedactor
+	}
+	res := make([]LogField, len(fields))
+	for i, field := range fields {
+		res[i] = LogField{Key: field.Key, Value: field.Value}
+		if r.masked([]string{field.Key}) {
+			res[i].Value = redactedValue
+			continue
+		}
+		switch v := field.Value.(type) {
+		case string:
+			res[i].Value = r.redactText(v)
+		case []byte:
+			res[i].Value = r.redactText(string(v))
+		case error:
+			res[i].Value = r.redactText(v.Error())
+		case http.Header:
+			res[i].Value = r.redactHeader(v)
+		}
+	}
+	return res
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -19,8 +18,14 @@ type HTTPClient interface {
 }
 
 type core struct {
-	httpClient HTTPClient
-	baseURL    string
+	httpClient  HTTPClient
+	baseURL     string
+	retryPolicy *RetryPolicy
+	rateLimiter *rateLimiter
+	middlewares []Middleware
+	logger      *levelLogger
+	tracer      Tracer
+	metrics     Metrics
 }
 
 func newCore(httpClient HTTPClient, baseURL string) *core {
@@ -32,6 +37,7 @@ func newCore(httpClient HTTPClient, baseURL string) *core {
 	return &core{
 		httpClient: httpClient,
 		baseURL:    baseURL,
+		logger:     newClientLogger(nil, 0, nil),
 	}
 }
 
@@ -58,12 +64,20 @@ func withHTTPQuery(key, value string) RequestOption {
 
 // Request send http request
 func (c *core) Request(ctx context.Context, method, path string, body any, instance any, opts ...RequestOption) error {
-	resp, err := c.RawRequest(ctx, method, path, body, opts...)
+	ctx, cancel := callTimeout(ctx)
+	defer cancel()
+	call, err := c.newCall(ctx, method, path, body, false, false, opts...)
 	if err != nil {
 		return err
 	}
-
-	return packInstance(ctx, instance, resp)
+	call.Response = instance
+	return c.handle(ctx, call, func(ctx context.Context, call *Call) error {
+		resp, err := c.send(ctx, call)
+		if err != nil {
+			return err
+		}
+		return packInstance(ctx, c.logger, call.Response, resp)
+	})
 }
 
 // UploadFile 上传文件
@@ -91,50 +105,212 @@ func (c *core) UploadFile(ctx context.Context, path string, reader io.Reader, fi
 		return fmt.Errorf("close multipart writer: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s%s", c.baseURL, path), body)
+	ctx, cancel := callTimeout(ctx)
+	defer cancel()
+
+	// 整个 body 已缓存在内存中，重试时可以重复读取
+	req, err := c.newRequest(ctx, http.MethodPost, path, body.Bytes(), writer.FormDataContentType(), opts...)
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return err
+	}
+	call := &Call{
+		Operation:   operationName(http.MethodPost, path, false),
+		Request:     fields,
+		HTTPRequest: req,
+		Response:    instance,
+		path:        path,
 	}
+	return c.handle(ctx, call, func(ctx context.Context, call *Call) error {
+		resp, err := c.do(ctx, call)
+		if err != nil {
+			return fmt.Errorf("do request: %w", err)
+		}
+		return packInstance(ctx, c.logger, call.Response, resp)
+	})
+}
 
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+func (c *core) RawRequest(ctx context.Context, method, path string, body any, opts ...RequestOption) (*http.Response, error) {
+	ctx, cancel := callTimeout(ctx)
+	call, err := c.newCall(ctx, method, path, body, false, true, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	err = c.handle(ctx, call, func(ctx context.Context, call *Call) error {
+		_, err := c.send(ctx, call)
+		return err
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	cancelOnClose(call.HTTPResponse, cancel)
+	return call.HTTPResponse, nil
+}
 
-	// 应用请求选项
-	for _, opt := range opts {
-		if err := opt(req); err != nil {
-			return fmt.Errorf("apply option: %w", err)
+func (c *core) StreamRequest(ctx context.Context, method, path string, body any, opts ...RequestOption) (*http.Response, error) {
+	ctx, cancel := callTimeout(ctx)
+	call, err := c.newCall(ctx, method, path, body, true, true, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	err = c.handle(ctx, call, func(ctx context.Context, call *Call) error {
+		resp, err := c.send(ctx, call)
+		if err != nil {
+			return err
+		}
+		contentType := resp.Header.Get("Content-Type")
+		if contentType != "" && strings.Contains(contentType, "application/json") {
+			return packInstance(ctx, c.logger, &baseResponse{}, resp)
 		}
+		return nil
+	})
+	if err != nil {
+		cancel()
+		return nil, err
 	}
-	setUserAgent(req)
+	cancelOnClose(call.HTTPResponse, cancel)
+	return call.HTTPResponse, nil
+}
 
-	resp, err := c.httpClient.Do(req)
+// newCall marshals the json body and builds the call passed to the middleware chain.
+func (c *core) newCall(ctx context.Context, method, path string, body any, stream, rawBody bool, opts ...RequestOption) (*Call, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request body: %w", err)
+		}
+	}
+	req, err := c.newRequest(ctx, method, path, data, "application/json", opts...)
 	if err != nil {
-		return fmt.Errorf("do request: %w", err)
+		return nil, err
 	}
+	return &Call{
+		Operation:   operationName(method, path, stream),
+		Request:     body,
+		HTTPRequest: req,
+		path:        path,
+		stream:      stream,
+		rawBody:     rawBody,
+	}, nil
+}
 
-	return packInstance(ctx, instance, resp)
+// send sends the call and checks the http status of the response.
+func (c *core) send(ctx context.Context, call *Call) (*http.Response, error) {
+	resp, err := c.do(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	err = checkHttpResp(ctx, c.logger, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
-func (c *core) RawRequest(ctx context.Context, method, path string, body any, opts ...RequestOption) (*http.Response, error) {
-	urlInfo := fmt.Sprintf("%s%s", c.baseURL, path)
+// do sends the request of the call and retries it according to the retry policy of the client, or
+// the one of the call options. Every retry sends a clone of the request with a fresh body.
+func (c *core) do(ctx context.Context, call *Call) (*http.Response, error) {
+	resp, err := c.doWithRetry(ctx, call)
+	runResponseHooks(ctx, resp)
+	return resp, err
+}
 
-	var bodyReader io.Reader
-	if body != nil {
-		data, err := json.Marshal(body)
+func (c *core) doWithRetry(ctx context.Context, call *Call) (*http.Response, error) {
+	policy := c.retryPolicyFor(ctx)
+	req := call.HTTPRequest.WithContext(ctx)
+	method, path := req.Method, call.path
+	canRetry := policy.enabled() && policy.allowMethod(method) && (req.Body == nil || req.GetBody != nil)
+	baseFields := callLogFields(call)
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("get request body: %w", err)
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		release, err := c.rateLimiter.acquire(ctx, path)
 		if err != nil {
-			return nil, fmt.Errorf("marshal request body: %w", err)
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := c.httpClient.Do(attemptReq)
+		releaseOnDone(resp, release)
+		call.HTTPResponse = resp
+
+		fields := append(baseFields[:len(baseFields):len(baseFields)],
+			LogField{Key: LogKeyAttempt, Value: attempt},
+			LogField{Key: LogKeyLatency, Value: time.Since(start)},
+		)
+		if resp != nil {
+			fields = append(fields,
+				LogField{Key: LogKeyStatus, Value: resp.StatusCode},
+				LogField{Key: LogKeyLogID, Value: resp.Header.Get(logIDHeader)},
+			)
+		} else {
+			fields = append(fields, LogField{Key: LogKeyError, Value: err})
 		}
-		bodyReader = bytes.NewReader(data)
+		c.logger.LogFields(ctx, LogLevelDebug, "request attempt", fields...)
+		if c.rateLimiter.observe(path, resp) {
+			c.logger.LogFields(ctx, LogLevelWarn, "rate limited by server", fields...)
+		}
+		if !canRetry || attempt >= policy.MaxAttempts {
+			return resp, err
+		}
+
+		retry, retryAfter, reason := shouldRetry(ctx, policy, resp, err)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		if c.metrics != nil {
+			m := RetryMetric{Operation: call.Operation, Attempt: attempt, Err: err}
+			if resp != nil {
+				m.StatusCode = resp.StatusCode
+			}
+			c.metrics.ObserveRetry(m)
+		}
+		delay := policy.backoff(attempt, retryAfter)
+		c.logger.LogFields(ctx, LogLevelWarn, "retry request", append(fields,
+			LogField{Key: "reason", Value: reason},
+			LogField{Key: "delay", Value: delay},
+		)...)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *core) newRequest(ctx context.Context, method, path string, body []byte, contentType string, opts ...RequestOption) (*http.Request, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, urlInfo, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", c.baseURLFor(ctx), path), bodyReader)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
 
 	// 设置默认请求头
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 
-	// 应用请求选项
+	// 应用请求选项，调用方传入的选项最后生效
+	if callOpt := getCallOption(ctx); callOpt != nil {
+		opts = append(opts[:len(opts):len(opts)], callOpt.requestOptions...)
+	}
 	for _, opt := range opts {
 		if err := opt(req); err != nil {
 			return nil, fmt.Errorf("apply option: %w", err)
@@ -142,31 +318,81 @@ func (c *core) RawRequest(ctx context.Context, method, path string, body any, op
 	}
 
 	setUserAgent(req)
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+	return req, nil
+}
+
+// callLogFields returns the fields identifying the call in the logs, including the IDs of the
+// chat, conversation or workflow the request refers to.
+func callLogFields(call *Call) []LogField {
+	fields := []LogField{
+		{Key: LogKeyOperation, Value: call.Operation},
+		{Key: LogKeyMethod, Value: call.HTTPRequest.Method},
+		{Key: LogKeyPath, Value: call.path},
+	}
+	add := func(key, value string) {
+		if value != "" {
+			fields = append(fields, LogField{Key: key, Value: value})
+		}
 	}
-	err = checkHttpResp(ctx, resp)
+	switch req := call.Request.(type) {
+	case *CancelChatsReq:
+		add(LogKeyConversationID, req.ConversationID)
+		add(LogKeyChatID, req.ChatID)
+	case *RunWorkflowsReq:
+		add(LogKeyWorkflowID, req.WorkflowID)
+	case *ResumeRunWorkflowsReq:
+		add(LogKeyWorkflowID, req.WorkflowID)
+	case *WorkflowsChatStreamReq:
+		add(LogKeyWorkflowID, req.WorkflowID)
+	}
+	// the other chat APIs pass the IDs as query parameters
+	query := call.HTTPRequest.URL.Query()
+	add(LogKeyConversationID, query.Get("conversation_id"))
+	add(LogKeyChatID, query.Get("chat_id"))
+	return fields
+}
+
+// shouldRetry decides whether the attempt failed with a transient error. When the response body
+// has to be read to find the Coze code, it is restored so that the caller can still consume it.
+func shouldRetry(ctx context.Context, policy *RetryPolicy, resp *http.Response, err error) (bool, time.Duration, string) {
 	if err != nil {
-		return nil, err
+		return policy.isRetryableError(ctx, err), 0, err.Error()
 	}
-	return resp, err
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if resp.StatusCode != http.StatusOK {
+		return policy.isRetryableStatus(resp.StatusCode), retryAfter, fmt.Sprintf("status=%d", resp.StatusCode)
+	}
+	if len(policy.RetryableCodes) == 0 {
+		return false, 0, ""
+	}
+	code, ok := peekCozeCode(resp)
+	if !ok {
+		return false, 0, ""
+	}
+	return policy.isRetryableCode(code), retryAfter, fmt.Sprintf("code=%d", code)
 }
 
-func (c *core) StreamRequest(ctx context.Context, method, path string, body any, opts ...RequestOption) (*http.Response, error) {
-	resp, err := c.RawRequest(ctx, method, path, body, opts...)
+// peekCozeCode reads the Coze code from a JSON response and restores the body, so that it can
+// still be consumed by the caller.
+func peekCozeCode(resp *http.Response) (int, bool) {
+	if !strings.Contains(resp.Header.Get("Content-Type"), "application/json") {
+		return 0, false
+	}
+	bodyBytes, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(bodyBytes))
 	if err != nil {
-		return nil, err
+		return 0, false
 	}
-	contentType := resp.Header.Get("Content-Type")
-	if contentType != "" && strings.Contains(contentType, "application/json") {
-		return nil, packInstance(ctx, &baseResponse{}, resp)
+	baseResp := &baseResponse{}
+	if json.Unmarshal(bodyBytes, baseResp) != nil {
+		return 0, false
 	}
-	return resp, nil
+	return baseResp.Code, true
 }
 
-func packInstance(ctx context.Context, instance any, resp *http.Response) error {
-	err := checkHttpResp(ctx, resp)
+func packInstance(ctx context.Context, logger *levelLogger, instance any, resp *http.Response) error {
+	err := checkHttpResp(ctx, logger, resp)
 	if err != nil {
 		return err
 	}
@@ -181,35 +407,58 @@ func packInstance(ctx context.Context, instance any, resp *http.Response) error
 		return err
 	}
 	if baseResp, ok := instance.(baseRespInterface); ok {
-		return isResponseSuccess(ctx, baseResp, bodyBytes, httpResponse)
+		return isResponseSuccess(ctx, logger, baseResp, bodyBytes, httpResponse)
 	}
 	return nil
 }
 
-func isResponseSuccess(ctx context.Context, baseResp baseRespInterface, bodyBytes []byte, httpResponse *httpResponse) error {
+func isResponseSuccess(ctx context.Context, logger *levelLogger, baseResp baseRespInterface, bodyBytes []byte, httpResponse *httpResponse) error {
 	baseResp.SetHTTPResponse(httpResponse)
 	if baseResp.GetCode() != 0 {
-		logger.Warnf(ctx, "request failed, body=%s, log_id=%s", string(bodyBytes), httpResponse.LogID())
-		return NewError(baseResp.GetCode(), baseResp.GetMsg(), httpResponse.LogID())
+		logger.LogFields(ctx, LogLevelWarn, "request failed",
+			LogField{Key: LogKeyCode, Value: baseResp.GetCode()},
+			LogField{Key: "body", Value: string(bodyBytes)},
+			LogField{Key: LogKeyLogID, Value: httpResponse.LogID()},
+		)
+		err := NewError(baseResp.GetCode(), baseResp.GetMsg(), httpResponse.LogID())
+		err.HTTPStatusCode = httpResponse.Status
+		err.RawBody = string(bodyBytes)
+		return err
 	}
 	return nil
 }
 
-func checkHttpResp(ctx context.Context, resp *http.Response) error {
+// checkHttpResp returns the error of a response which is not 200: an AuthError if the body is an
+// OAuth error, or else an Error with the code of the body, if any.
+func checkHttpResp(ctx context.Context, logger *levelLogger, resp *http.Response) error {
 	logID := resp.Header.Get(logIDHeader)
-	// 鉴权的情况，需要解析
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("coze read response body failed: %w, log_id: %s", err, logID)
-		}
-		errorInfo := authErrorFormat{}
-		err = json.Unmarshal(bodyBytes, &errorInfo)
-		if err != nil {
-			logger.Errorf(ctx, fmt.Sprintf("unmarshal response body: %s", string(bodyBytes)))
-			return errors.New(string(bodyBytes) + " log_id: " + logID)
-		}
-		return NewAuthError(&errorInfo, resp.StatusCode, logID)
+	if resp.StatusCode == http.StatusOK {
+		return nil
 	}
-	return nil
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("coze read response body failed: %w, log_id: %s", err, logID)
+	}
+	errorInfo := authErrorFormat{}
+	if json.Unmarshal(bodyBytes, &errorInfo) == nil && (errorInfo.ErrorCode != "" || errorInfo.Error != "") {
+		authErr := NewAuthError(&errorInfo, resp.StatusCode, logID)
+		authErr.RawBody = string(bodyBytes)
+		return authErr
+	}
+
+	logger.LogFields(ctx, LogLevelWarn, "request failed",
+		LogField{Key: LogKeyStatus, Value: resp.StatusCode},
+		LogField{Key: "body", Value: string(bodyBytes)},
+		LogField{Key: LogKeyLogID, Value: logID},
+	)
+	cozeErr := &Error{LogID: logID, HTTPStatusCode: resp.StatusCode, RawBody: string(bodyBytes)}
+	baseResp := &baseResponse{}
+	if json.Unmarshal(bodyBytes, baseResp) == nil && baseResp.Code != 0 {
+		cozeErr.Code, cozeErr.Message = baseResp.Code, baseResp.Msg
+	} else if text := strings.TrimSpace(string(bodyBytes)); text != "" {
+		cozeErr.Message = text
+	} else {
+		cozeErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return cozeErr
 }
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestResponse 用于测试的响应结构
@@ -108,6 +109,44 @@ func TestClient_Request_Error(t *testing.T) {
 		assert.Equal(t, "business error", cozeErr.Message)
 	})
 
+	t.Run("Non Auth Error Status", func(t *testing.T) {
+		mockResp := &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader("upstream unavailable")),
+			Header:     make(http.Header),
+		}
+		mockResp.Header.Set(logIDHeader, "test-log-id")
+		core := newCore(&mockHTTP{Response: mockResp}, "https://api.test.com")
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+		cozeErr, ok := AsCozeError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, http.StatusServiceUnavailable, cozeErr.HTTPStatusCode)
+		assert.Equal(t, "upstream unavailable", cozeErr.Message)
+		assert.Equal(t, "upstream unavailable", cozeErr.RawBody)
+		assert.Equal(t, "test-log-id", cozeErr.LogID)
+		assert.ErrorIs(t, err, ErrServer)
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("Non Auth Error Code", func(t *testing.T) {
+		mockResp := &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(strings.NewReader(`{"code":4200,"msg":"bot not found"}`)),
+			Header:     make(http.Header),
+		}
+		core := newCore(&mockHTTP{Response: mockResp}, "https://api.test.com")
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+		cozeErr, ok := AsCozeError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, 4200, cozeErr.Code)
+		assert.Equal(t, "bot not found", cozeErr.Message)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
 	// 测试认证错误
 	t.Run("Auth Error", func(t *testing.T) {
 		errorResp := &authErrorFormat{
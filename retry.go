@@ -0,0 +1,179 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy describes how the client retries requests that failed with a transient error.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. A value <= 1 disables retry.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. It is doubled for every following retry.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between two attempts, including the one requested by Retry-After.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of the delay, between 0 and 1, that is randomized to spread retries.
+	Jitter float64
+
+	// RetryableStatusCodes are the HTTP status codes that are considered transient.
+	RetryableStatusCodes []int
+
+	// RetryableCodes are the Coze business codes (the code field of the response body) that are
+	// considered transient.
+	RetryableCodes []int
+
+	// RetryNonIdempotent allows retrying non-idempotent requests, such as POST and PATCH. Most of
+	// the Coze open api is exposed through POST, so this must be enabled explicitly.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a retry policy with sensible defaults: 3 attempts, exponential
+// backoff from 500ms up to 8s with 20% jitter, retrying 429, 5xx and the Coze rate limit code.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    8 * time.Second,
+		Jitter:      0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryableCodes: []int{
+			cozeCodeRateLimit,
+		},
+	}
+}
+
+// cozeCodeRateLimit is the Coze business code returned when the request rate exceeds the limit.
+const cozeCodeRateLimit = 4013
+
+func (p *RetryPolicy) enabled() bool {
+	return p != nil && p.MaxAttempts > 1
+}
+
+// allowMethod reports whether a request with the given method may be retried.
+func (p *RetryPolicy) allowMethod(method string) bool {
+	if p.RetryNonIdempotent {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *RetryPolicy) isRetryableStatus(status int) bool {
+	for _, code := range p.RetryableStatusCodes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) isRetryableCode(code int) bool {
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// isRetryableError reports whether a transport error is transient: a network timeout, a connection
+// reset, refused or closed midway, or a temporary DNS failure. Errors caused by the caller's
+// context, and the other errors, such as the failures to get the access token or TLS errors, are
+// never retried.
+func (p *RetryPolicy) isRetryableError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoff returns the delay before the given retry (1 for the first retry). retryAfter, when
+// positive, is the delay requested by the server and takes precedence over the computed one.
+func (p *RetryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(retry-1)))
+		if p.Jitter > 0 {
+			jitter := math.Min(p.Jitter, 1)
+			delay = time.Duration(float64(delay) * (1 - jitter + 2*jitter*rand.Float64()))
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a number of seconds or an HTTP
+// date. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
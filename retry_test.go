@@ -0,0 +1,280 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingAuth fails to get the access token, and counts its calls.
+type failingAuth struct {
+	err   error
+	calls int
+}
+
+func (a *failingAuth) Token(ctx context.Context) (string, error) {
+	a.calls++
+	return "", a.err
+}
+
+func newRetryTestPolicy() *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("backoff grows and is capped", func(t *testing.T) {
+		policy := &RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+		assert.Equal(t, 100*time.Millisecond, policy.backoff(1, 0))
+		assert.Equal(t, 200*time.Millisecond, policy.backoff(2, 0))
+		assert.Equal(t, 300*time.Millisecond, policy.backoff(3, 0))
+	})
+
+	t.Run("backoff honors retry after", func(t *testing.T) {
+		policy := &RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 3 * time.Second}
+		assert.Equal(t, 2*time.Second, policy.backoff(1, 2*time.Second))
+		assert.Equal(t, 3*time.Second, policy.backoff(1, 10*time.Second))
+	})
+
+	t.Run("backoff with jitter", func(t *testing.T) {
+		policy := &RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, Jitter: 0.5}
+		for i := 0; i < 100; i++ {
+			delay := policy.backoff(1, 0)
+			assert.GreaterOrEqual(t, delay, 50*time.Millisecond)
+			assert.LessOrEqual(t, delay, 150*time.Millisecond)
+		}
+	})
+
+	t.Run("allow method", func(t *testing.T) {
+		policy := DefaultRetryPolicy()
+		assert.True(t, policy.allowMethod(http.MethodGet))
+		assert.True(t, policy.allowMethod(http.MethodDelete))
+		assert.False(t, policy.allowMethod(http.MethodPost))
+		policy.RetryNonIdempotent = true
+		assert.True(t, policy.allowMethod(http.MethodPost))
+	})
+
+	t.Run("parse retry after", func(t *testing.T) {
+		assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+		assert.Equal(t, time.Duration(0), parseRetryAfter("invalid"))
+		assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+		date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		assert.Greater(t, parseRetryAfter(date), 50*time.Minute)
+	})
+}
+
+func TestCoreRetry(t *testing.T) {
+	t.Run("retry idempotent request on 503", func(t *testing.T) {
+		attempts := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts < 3 {
+					return mockResponse(http.StatusServiceUnavailable, map[string]string{})
+				}
+				return mockResponse(http.StatusOK, &TestResponse{})
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("stop after max attempts", func(t *testing.T) {
+		attempts := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return mockResponse(http.StatusBadGateway, map[string]string{"error_code": "bad_gateway"})
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+		require.Error(t, err)
+		assert.Equal(t, 3, attempts)
+		authErr, ok := AsAuthError(err)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusBadGateway, authErr.HttpCode)
+	})
+
+	t.Run("do not retry post by default", func(t *testing.T) {
+		attempts := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return mockResponse(http.StatusServiceUnavailable, map[string]string{})
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodPost, "/test", &TestReq{Test: "test"}, &resp)
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("retry post with coze rate limit code when allowed", func(t *testing.T) {
+		attempts := 0
+		var bodies []string
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				body, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(body))
+				if attempts == 1 {
+					resp, err := mockResponse(http.StatusOK, &baseResponse{Code: cozeCodeRateLimit, Msg: "rate limit"})
+					resp.Header.Set("Content-Type", "application/json")
+					return resp, err
+				}
+				resp, err := mockResponse(http.StatusOK, &TestResponse{})
+				resp.Header.Set("Content-Type", "application/json")
+				return resp, err
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+		core.retryPolicy.RetryNonIdempotent = true
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodPost, "/test", &TestReq{Test: "test"}, &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, bodies[0], bodies[1])
+	})
+
+	t.Run("non retryable code is returned", func(t *testing.T) {
+		attempts := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				resp, err := mockResponse(http.StatusOK, &baseResponse{Code: 4000, Msg: "invalid param"})
+				resp.Header.Set("Content-Type", "application/json")
+				return resp, err
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+		cozeErr, ok := AsCozeError(err)
+		require.True(t, ok)
+		assert.Equal(t, 4000, cozeErr.Code)
+	})
+
+	t.Run("retry network error", func(t *testing.T) {
+		attempts := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+				}
+				return mockResponse(http.StatusOK, &TestResponse{})
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+
+		var resp TestResponse
+		err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("do not retry other errors", func(t *testing.T) {
+		for name, failure := range map[string]error{
+			"token":       errors.New("failed to get the access token"),
+			"auth":        &AuthError{ErrorMessage: "invalid client"},
+			"certificate": errors.New("x509: certificate signed by unknown authority"),
+		} {
+			attempts := 0
+			transport := &mockTransport{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return nil, failure
+			}}
+			core := newCore(&http.Client{Transport: transport}, ComBaseURL)
+			core.retryPolicy = newRetryTestPolicy()
+
+			var resp TestResponse
+			err := core.Request(context.Background(), http.MethodGet, "/test", nil, &resp)
+			require.Error(t, err, name)
+			assert.Equal(t, 1, attempts, name)
+		}
+	})
+
+	t.Run("do not retry the failure to get the access token", func(t *testing.T) {
+		attempts := 0
+		auth := &failingAuth{err: errors.New("token expired")}
+		api := NewCozeAPI(auth, WithRetryPolicy(newRetryTestPolicy()),
+			WithTransport(&mockTransport{roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return mockResponse(http.StatusOK, &TestResponse{})
+			}}))
+		_, err := api.Users.Me(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, 1, auth.calls)
+		assert.Equal(t, 0, attempts)
+	})
+
+	t.Run("upload file is buffered for retry", func(t *testing.T) {
+		attempts := 0
+		var bodies []string
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				body, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(body))
+				if attempts == 1 {
+					return mockResponse(http.StatusTooManyRequests, map[string]string{})
+				}
+				return mockResponse(http.StatusOK, &TestResponse{})
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = newRetryTestPolicy()
+		core.retryPolicy.RetryNonIdempotent = true
+
+		var resp TestResponse
+		err := core.UploadFile(context.Background(), "/upload", strings.NewReader("file content"), "test.txt", nil, &resp)
+		require.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Contains(t, bodies[1], "file content")
+		assert.Equal(t, bodies[0], bodies[1])
+	})
+
+	t.Run("context cancelled while waiting", func(t *testing.T) {
+		attempts := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				attempts++
+				resp, err := mockResponse(http.StatusServiceUnavailable, map[string]string{})
+				resp.Header.Set("Retry-After", "60")
+				return resp, err
+			},
+		}}, ComBaseURL)
+		core.retryPolicy = DefaultRetryPolicy()
+		core.retryPolicy.MaxDelay = time.Minute
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		var resp TestResponse
+		err := core.Request(ctx, http.MethodGet, "/test", nil, &resp)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,73 @@
+//go:build go1.21
+
+package coze
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// LevelTrace is the slog level of the trace logs of the SDK.
+const LevelTrace = slog.LevelDebug - 4
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing the logs of the SDK to a slog.Logger, the fields of the
+// logs, such as operation, status or log_id, are written as typed attributes.
+func NewSlogLogger(logger *slog.Logger) StructuredLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger}
+}
+
+// Log ...
+func (l *slogLogger) Log(ctx context.Context, level LogLevel, message string, args ...interface{}) {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	l.write(ctx, level, message, nil)
+}
+
+// LogFields ...
+func (l *slogLogger) LogFields(ctx context.Context, level LogLevel, message string, fields ...LogField) {
+	l.write(ctx, level, message, fields)
+}
+
+func (l *slogLogger) write(ctx context.Context, level LogLevel, message string, fields []LogField) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	slogLevel := toSlogLevel(level)
+	handler := l.logger.Handler()
+	if !handler.Enabled(ctx, slogLevel) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), slogLevel, message, pcs[0])
+	for _, field := range fields {
+		record.AddAttrs(slog.Any(field.Key, field.Value))
+	}
+	_ = handler.Handle(ctx, record)
+}
+
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelTrace:
+		return LevelTrace
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
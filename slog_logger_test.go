@@ -0,0 +1,71 @@
+//go:build go1.21
+
+package coze
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	t.Run("fields are typed attributes", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: LevelTrace})))
+		api := businessErrorClient(t, WithLogger(logger), WithLogLevel(LogLevelDebug))
+
+		_, err := api.Chat.Retrieve(context.Background(), &RetrieveChatsReq{ConversationID: "conv1", ChatID: "chat1"})
+		require.Error(t, err)
+
+		var records []map[string]interface{}
+		decoder := json.NewDecoder(buf)
+		for decoder.More() {
+			record := map[string]interface{}{}
+			require.NoError(t, decoder.Decode(&record))
+			records = append(records, record)
+		}
+		require.Len(t, records, 2)
+
+		attempt := records[0]
+		assert.Equal(t, "DEBUG", attempt["level"])
+		assert.Equal(t, "request attempt", attempt["msg"])
+		assert.Equal(t, "chat.retrieve", attempt[LogKeyOperation])
+		assert.Equal(t, http.MethodGet, attempt[LogKeyMethod])
+		assert.Equal(t, "/v3/chat/retrieve", attempt[LogKeyPath])
+		assert.Equal(t, float64(http.StatusOK), attempt[LogKeyStatus])
+		assert.Equal(t, float64(1), attempt[LogKeyAttempt])
+		assert.Equal(t, "test_log_id", attempt[LogKeyLogID])
+		assert.Equal(t, "conv1", attempt[LogKeyConversationID])
+		assert.Equal(t, "chat1", attempt[LogKeyChatID])
+		assert.IsType(t, float64(0), attempt[LogKeyLatency])
+
+		failed := records[1]
+		assert.Equal(t, "WARN", failed["level"])
+		assert.Equal(t, "request failed", failed["msg"])
+		assert.Equal(t, float64(4000), failed[LogKeyCode])
+		assert.Equal(t, "test_log_id", failed[LogKeyLogID])
+	})
+
+	t.Run("printf messages", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := NewSlogLogger(slog.New(slog.NewTextHandler(buf, nil)))
+		logger.Log(context.Background(), LogLevelInfo, "hello %s", "coze")
+		logger.Log(context.Background(), LogLevelDebug, "filtered by the handler")
+		assert.Contains(t, buf.String(), `level=INFO msg="hello coze"`)
+		assert.NotContains(t, buf.String(), "filtered")
+	})
+
+	t.Run("levels", func(t *testing.T) {
+		assert.Equal(t, LevelTrace, toSlogLevel(LogLevelTrace))
+		assert.Equal(t, slog.LevelDebug, toSlogLevel(LogLevelDebug))
+		assert.Equal(t, slog.LevelInfo, toSlogLevel(LogLevelInfo))
+		assert.Equal(t, slog.LevelWarn, toSlogLevel(LogLevelWarn))
+		assert.Equal(t, slog.LevelError, toSlogLevel(LogLevelError))
+	})
+}
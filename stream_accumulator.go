@@ -0,0 +1,300 @@
+package coze
+
+import (
+	"context"
+	"sync"
+)
+
+// ChatStreamAccumulator assembles the events of a chat stream into the messages and the chat they
+// describe: the deltas of a message are concatenated by message id, content and reasoning content
+// apart, until the completed message replaces them. It is safe for concurrent use, so that the
+// messages in progress can be read while the stream is consumed.
+//
+// The audio deltas are not assembled, their content being encoded chunks.
+type ChatStreamAccumulator struct {
+	mu        sync.Mutex
+	messages  map[string]*Message
+	order     []string
+	completed map[string]bool
+	chat      *Chat
+	debugURL  string
+	done      bool
+}
+
+// NewChatStreamAccumulator creates an empty ChatStreamAccumulator.
+func NewChatStreamAccumulator() *ChatStreamAccumulator {
+	return &ChatStreamAccumulator{
+		messages:  map[string]*Message{},
+		completed: map[string]bool{},
+	}
+}
+
+// Add accumulates an event of the stream.
+func (a *ChatStreamAccumulator) Add(event *ChatEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch event.Event {
+	case ChatEventConversationMessageDelta:
+		if event.Message == nil || a.completed[event.Message.ID] {
+			return
+		}
+		message, ok := a.messages[event.Message.ID]
+		if !ok {
+			message = &Message{}
+			*message = *event.Message
+			a.messages[message.ID] = message
+			a.order = append(a.order, message.ID)
+			return
+		}
+		message.Content += event.Message.Content
+		message.ReasoningContent += event.Message.ReasoningContent
+	case ChatEventConversationMessageCompleted:
+		if event.Message == nil {
+			return
+		}
+		message := &Message{}
+		*message = *event.Message
+		if _, ok := a.messages[message.ID]; !ok {
+			a.order = append(a.order, message.ID)
+		}
+		a.messages[message.ID] = message
+		a.completed[message.ID] = true
+	case ChatEventConversationChatCreated, ChatEventConversationChatInProgress, ChatEventConversationChatCompleted,
+		ChatEventConversationChatFailed, ChatEventConversationChatRequiresAction:
+		if event.Chat != nil {
+			chat := *event.Chat
+			a.chat = &chat
+		}
+	case ChatEventDone:
+		if event.WorkflowDebug != nil {
+			a.debugURL = event.WorkflowDebug.DebugUrl
+		}
+		a.done = true
+	}
+}
+
+// Consume adds the events of the stream until its end, closes it, and returns the result, see
+// Poll.
+func (a *ChatStreamAccumulator) Consume(ctx context.Context, stream Stream[ChatEvent]) (*ChatPoll, error) {
+	err := consumeStream(ctx, stream, func(event *ChatEvent) error {
+		a.Add(event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.Poll(), nil
+}
+
+// Messages returns the messages in progress and completed, in the order of their first event.
+func (a *ChatStreamAccumulator) Messages() []*Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	messages := make([]*Message, 0, len(a.order))
+	for _, id := range a.order {
+		messages = append(messages, copyMessage(a.messages[id]))
+	}
+	return messages
+}
+
+// CompletedMessages returns the completed messages, in the order of their first event.
+func (a *ChatStreamAccumulator) CompletedMessages() []*Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	messages := make([]*Message, 0, len(a.completed))
+	for _, id := range a.order {
+		if a.completed[id] {
+			messages = append(messages, copyMessage(a.messages[id]))
+		}
+	}
+	return messages
+}
+
+// Message returns the message with the given id, and whether it is completed. It returns nil if
+// the stream had no event of the message.
+func (a *ChatStreamAccumulator) Message(id string) (*Message, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	message, ok := a.messages[id]
+	if !ok {
+		return nil, false
+	}
+	return copyMessage(message), a.completed[id]
+}
+
+// Chat returns the chat of the last chat event, with its status, usage and required action, or nil
+// before the first one.
+func (a *ChatStreamAccumulator) Chat() *Chat {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.chat == nil {
+		return nil
+	}
+	chat := *a.chat
+	return &chat
+}
+
+// DebugURL returns the debug url of the workflow run by the chat, sent by the done event.
+func (a *ChatStreamAccumulator) DebugURL() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.debugURL
+}
+
+// Done reports whether the done event was received.
+func (a *ChatStreamAccumulator) Done() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.done
+}
+
+// Poll returns the chat and its completed messages, as returned by Chat.CreateAndPoll.
+func (a *ChatStreamAccumulator) Poll() *ChatPoll {
+	return &ChatPoll{Chat: a.Chat(), Messages: a.CompletedMessages()}
+}
+
+func copyMessage(message *Message) *Message {
+	res := *message
+	return &res
+}
+
+// WorkflowNodeOutput is the output of a workflow node assembled from its messages.
+type WorkflowNodeOutput struct {
+	// The name of the node, such as "End".
+	NodeTitle string
+
+	// The contents of the messages of the node, concatenated.
+	Content string
+
+	// The number of messages of the node.
+	Messages int
+
+	// Whether the last message of the node was received.
+	Finished bool
+}
+
+// WorkflowStreamAccumulator assembles the messages of a workflow stream into the output of each
+// node, keyed by node title. A message whose NodeSeqID was already received for the node is
+// skipped, such as the messages sent again after a reconnection. It is safe for concurrent use.
+type WorkflowStreamAccumulator struct {
+	mu        sync.Mutex
+	nodes     map[string]*WorkflowNodeOutput
+	order     []string
+	seen      map[string]map[string]bool
+	interrupt *WorkflowEventInterrupt
+	err       *WorkflowEventError
+	debugURL  string
+	done      bool
+}
+
+// NewWorkflowStreamAccumulator creates an empty WorkflowStreamAccumulator.
+func NewWorkflowStreamAccumulator() *WorkflowStreamAccumulator {
+	return &WorkflowStreamAccumulator{
+		nodes: map[string]*WorkflowNodeOutput{},
+		seen:  map[string]map[string]bool{},
+	}
+}
+
+// Add accumulates an event of the stream.
+func (a *WorkflowStreamAccumulator) Add(event *WorkflowEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch event.Event {
+	case WorkflowEventTypeMessage:
+		if event.Message == nil {
+			return
+		}
+		title := event.Message.NodeTitle
+		node, ok := a.nodes[title]
+		if !ok {
+			node = &WorkflowNodeOutput{NodeTitle: title}
+			a.nodes[title] = node
+			a.seen[title] = map[string]bool{}
+			a.order = append(a.order, title)
+		}
+		if seqID := event.Message.NodeSeqID; seqID != "" {
+			if a.seen[title][seqID] {
+				return
+			}
+			a.seen[title][seqID] = true
+		}
+		node.Content += event.Message.Content
+		node.Messages++
+		node.Finished = node.Finished || event.Message.NodeIsFinish
+	case WorkflowEventTypeInterrupt:
+		a.interrupt = event.Interrupt
+	case WorkflowEventTypeError:
+		a.err = event.Error
+	case WorkflowEventTypeDone:
+		if event.DebugURL != nil {
+			a.debugURL = event.DebugURL.URL
+		}
+		a.done = true
+	}
+}
+
+// Consume adds the events of the stream until its end, and closes it. A workflow failing with an
+// Error event is not an error of Consume, see Error.
+func (a *WorkflowStreamAccumulator) Consume(ctx context.Context, stream Stream[WorkflowEvent]) error {
+	err := consumeStream(ctx, stream, func(event *WorkflowEvent) error {
+		a.Add(event)
+		return nil
+	})
+	if _, ok := AsStreamError(err); ok && a.Error() != nil {
+		return nil
+	}
+	return err
+}
+
+// Nodes returns the output of the nodes, in the order of their first message.
+func (a *WorkflowStreamAccumulator) Nodes() []*WorkflowNodeOutput {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	nodes := make([]*WorkflowNodeOutput, 0, len(a.order))
+	for _, title := range a.order {
+		node := *a.nodes[title]
+		nodes = append(nodes, &node)
+	}
+	return nodes
+}
+
+// Node returns the output of the node with the given title, or nil if it sent no message.
+func (a *WorkflowStreamAccumulator) Node(title string) *WorkflowNodeOutput {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	node, ok := a.nodes[title]
+	if !ok {
+		return nil
+	}
+	res := *node
+	return &res
+}
+
+// Interrupt returns the interruption of the workflow, to be passed to Workflows.Runs.Resume, or
+// nil if the workflow was not interrupted.
+func (a *WorkflowStreamAccumulator) Interrupt() *WorkflowEventInterrupt {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.interrupt
+}
+
+// Error returns the error event of the workflow, or nil if it did not fail.
+func (a *WorkflowStreamAccumulator) Error() *WorkflowEventError {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// DebugURL returns the debug url of the run, sent by the done event.
+func (a *WorkflowStreamAccumulator) DebugURL() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.debugURL
+}
+
+// Done reports whether the done event was received.
+func (a *WorkflowStreamAccumulator) Done() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.done
+}
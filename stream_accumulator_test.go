@@ -0,0 +1,118 @@
+package coze
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatStreamAccumulator(t *testing.T) {
+	resp, err := mockStreamResponse(`event: conversation.chat.created
+data: {"id":"chat1","status":"created"}
+
+event: conversation.message.delta
+data: {"id":"msg1","role":"assistant","type":"answer","reasoning_content":"let me "}
+
+event: conversation.message.delta
+data: {"id":"msg1","role":"assistant","type":"answer","reasoning_content":"think","content":"Hel"}
+
+event: conversation.message.delta
+data: {"id":"msg2","role":"assistant","type":"follow_up","content":"More?"}
+
+event: conversation.message.delta
+data: {"id":"msg1","role":"assistant","type":"answer","content":"lo"}
+
+event: conversation.message.completed
+data: {"id":"msg1","role":"assistant","type":"answer","reasoning_content":"let me think","content":"Hello"}
+
+event: conversation.chat.completed
+data: {"id":"chat1","status":"completed","usage":{"token_count":12}}
+
+event: done
+data: {"debug_url":"https://www.coze.com/debug"}
+
+`)
+	require.NoError(t, err)
+	stream := newStreamReader(context.Background(), newCore(&mockHTTP{}, ComBaseURL), resp, chatEventDecoder(newClientLogger(newStdLogger(), LogLevelError, nil)))
+
+	acc := NewChatStreamAccumulator()
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	acc.Add(event)
+	for i := 0; i < 4; i++ {
+		event, err = stream.Recv()
+		require.NoError(t, err)
+		acc.Add(event)
+	}
+
+	// in progress
+	message, completed := acc.Message("msg1")
+	require.NotNil(t, message)
+	assert.False(t, completed)
+	assert.Equal(t, "Hello", message.Content)
+	assert.Equal(t, "let me think", message.ReasoningContent)
+	assert.Len(t, acc.Messages(), 2)
+	assert.Empty(t, acc.CompletedMessages())
+	assert.Equal(t, ChatStatusCreated, acc.Chat().Status)
+
+	poll, err := acc.Consume(context.Background(), stream)
+	require.NoError(t, err)
+	assert.True(t, acc.Done())
+	assert.Equal(t, "https://www.coze.com/debug", acc.DebugURL())
+	assert.Equal(t, ChatStatusCompleted, poll.Chat.Status)
+	assert.Equal(t, 12, poll.Chat.Usage.TokenCount)
+	require.Len(t, poll.Messages, 1)
+	assert.Equal(t, "Hello", poll.Messages[0].Content)
+	assert.Equal(t, "let me think", poll.Messages[0].ReasoningContent)
+
+	messages := acc.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "msg1", messages[0].ID)
+	assert.Equal(t, "More?", messages[1].Content)
+
+	// the returned messages are copies
+	messages[0].Content = "changed"
+	message, completed = acc.Message("msg1")
+	assert.True(t, completed)
+	assert.Equal(t, "Hello", message.Content)
+}
+
+func TestWorkflowStreamAccumulator(t *testing.T) {
+	acc := NewWorkflowStreamAccumulator()
+	events := []*WorkflowEvent{
+		{ID: 0, Event: WorkflowEventTypeMessage, Message: &WorkflowEventMessage{NodeTitle: "Message", NodeSeqID: "0", Content: "Hel"}},
+		{ID: 1, Event: WorkflowEventTypeMessage, Message: &WorkflowEventMessage{NodeTitle: "End", NodeSeqID: "0", Content: "{\"out\":"}},
+		{ID: 2, Event: WorkflowEventTypeMessage, Message: &WorkflowEventMessage{NodeTitle: "Message", NodeSeqID: "1", Content: "lo", NodeIsFinish: true}},
+		{ID: 2, Event: WorkflowEventTypeMessage, Message: &WorkflowEventMessage{NodeTitle: "Message", NodeSeqID: "1", Content: "lo", NodeIsFinish: true}},
+		{ID: 3, Event: WorkflowEventTypeMessage, Message: &WorkflowEventMessage{NodeTitle: "End", NodeSeqID: "1", Content: "1}", NodeIsFinish: true}},
+		{ID: 4, Event: WorkflowEventTypeDone, DebugURL: &WorkflowEventDebugURL{URL: "https://www.coze.com/debug"}},
+	}
+	for _, event := range events {
+		acc.Add(event)
+	}
+
+	nodes := acc.Nodes()
+	require.Len(t, nodes, 2)
+	assert.Equal(t, &WorkflowNodeOutput{NodeTitle: "Message", Content: "Hello", Messages: 2, Finished: true}, nodes[0])
+	assert.Equal(t, "{\"out\":1}", acc.Node("End").Content)
+	assert.Nil(t, acc.Node("Unknown"))
+	assert.True(t, acc.Done())
+	assert.Equal(t, "https://www.coze.com/debug", acc.DebugURL())
+	assert.Nil(t, acc.Error())
+	assert.Nil(t, acc.Interrupt())
+
+	t.Run("interrupt and error", func(t *testing.T) {
+		resp, err := mockStreamResponse("id: 0\nevent: Interrupt\ndata: {\"interrupt_data\":{\"event_id\":\"e1\",\"type\":2},\"node_title\":\"Question\"}\n\n" +
+			"id: 1\nevent: Error\ndata: {\"error_code\":1,\"error_message\":\"failed\"}\n\n")
+		require.NoError(t, err)
+		stream := newStreamReader(context.Background(), newCore(&mockHTTP{}, ComBaseURL), resp, decodeWorkflowEvent)
+
+		acc := NewWorkflowStreamAccumulator()
+		require.NoError(t, acc.Consume(context.Background(), stream))
+		assert.Equal(t, "failed", acc.Error().ErrorMessage)
+		assert.Equal(t, "e1", acc.Interrupt().InterruptData.EventID)
+		assert.False(t, acc.Done())
+	})
+}
@@ -0,0 +1,36 @@
+//go:build go1.23
+
+package coze
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+var errStopIteration = errors.New("stop iteration")
+
+// StreamAll returns an iterator over the events of the stream, to be used with range:
+//
+//	for event, err := range coze.StreamAll(ctx, stream) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(event.Event)
+//	}
+//
+// The error which ended the stream, if any, is yielded last with a nil event. The stream is closed
+// once the loop ends, or as soon as the context is done.
+func StreamAll[T streamable](ctx context.Context, stream Stream[T]) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		err := consumeStream(ctx, stream, func(event *T) error {
+			if !yield(event, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && err != errStopIteration {
+			yield(nil, err)
+		}
+	}
+}
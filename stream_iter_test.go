@@ -0,0 +1,50 @@
+//go:build go1.23
+
+package coze
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamAll(t *testing.T) {
+	t.Run("all events", func(t *testing.T) {
+		stream, closed := newTestChatStream(strings.NewReader(testChatStream))
+		count := 0
+		for event, err := range StreamAll(context.Background(), stream) {
+			require.NoError(t, err)
+			require.NotNil(t, event)
+			count++
+		}
+		assert.Equal(t, 5, count)
+		assert.True(t, *closed)
+	})
+
+	t.Run("break closes the stream", func(t *testing.T) {
+		stream, closed := newTestChatStream(strings.NewReader(testChatStream))
+		for event := range StreamAll(context.Background(), stream) {
+			assert.Equal(t, ChatEventConversationChatCreated, event.Event)
+			break
+		}
+		assert.True(t, *closed)
+	})
+
+	t.Run("error is yielded last", func(t *testing.T) {
+		stream, _ := newTestChatStream(strings.NewReader(testChatStream[:strings.Index(testChatStream, "event: done")] +
+			"event: error\ndata: internal error\n\n"))
+		var last error
+		count := 0
+		for _, err := range StreamAll(context.Background(), stream) {
+			last = err
+			count++
+		}
+		assert.Equal(t, 5, count)
+		streamErr, ok := AsStreamError(last)
+		require.True(t, ok)
+		assert.Equal(t, "internal error", streamErr.Message)
+	})
+}
@@ -2,10 +2,17 @@ package coze
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type streamable interface {
@@ -17,70 +24,555 @@ type Stream[T streamable] interface {
 	Close() error
 	Recv() (*T, error)
 }
-type eventProcessor[T streamable] func(line []byte, reader *bufio.Reader) (*T, bool, error)
+
+// eventDecoder decodes a server-sent event into an event of the stream. It returns a nil event for
+// the events to skip, and whether the event is the last one of the stream.
+type eventDecoder[T streamable] func(event *sseEvent) (*T, bool, error)
 
 type streamReader[T streamable] struct {
 	isFinished bool
+	checked    bool
 	ctx        context.Context
+	// last is the last event returned by Recv, and pendingErr the error ending the stream after
+	// the event reporting it.
+	last       *T
+	pendingErr error
 
-	reader       *bufio.Reader
+	sse          *sseDecoder
 	response     *http.Response
-	processor    eventProcessor[T]
+	decoder      eventDecoder[T]
 	httpResponse *httpResponse
+	logger       *levelLogger
+	observer     *callObserver
+
+	idleTimeout time.Duration
+	totalTimer  *time.Timer
+	mu          sync.Mutex
+	timeoutErr  *StreamTimeoutError
+	// onTimeout is called once the stream timed out, before Recv returns the error.
+	onTimeout func(ctx context.Context)
+	// stopWatch stops watching the context of the stream, see release.
+	stopWatch func()
+}
+
+func newStreamReader[T streamable](ctx context.Context, core *core, resp *http.Response, decoder eventDecoder[T]) *streamReader[T] {
+	s := &streamReader[T]{
+		ctx:          ctx,
+		response:     resp,
+		sse:          newSSEDecoder(resp.Body),
+		decoder:      decoder,
+		httpResponse: newHTTPResponse(resp),
+		logger:       core.logger,
+		observer:     callObserverOf(resp),
+	}
+	if opt := getCallOption(ctx); opt != nil {
+		s.idleTimeout = opt.streamIdleTimeout
+		if timeout := opt.streamTimeout; timeout > 0 {
+			s.totalTimer = time.AfterFunc(timeout, func() { s.timeout(false, timeout) })
+		}
+	}
+	return s
 }
 
 func (s *streamReader[T]) Recv() (response *T, err error) {
-	return s.processLines()
+	var idleTimer *time.Timer
+	if timeout := s.idleTimeout; timeout > 0 {
+		idleTimer = time.AfterFunc(timeout, func() { s.timeout(true, timeout) })
+	}
+	response, err = s.processEvents()
+	if idleTimer != nil {
+		idleTimer.Stop()
+	}
+	if err != nil {
+		err = s.recvError(err)
+		s.observer.end(err)
+		return nil, err
+	}
+	s.last = response
+	s.observer.observeEvent(response)
+	if s.isFinished {
+		s.release()
+	}
+	return response, nil
+}
+
+// timeout closes the body of the stream, which ends a blocked Recv with the timeout error.
+func (s *streamReader[T]) timeout(idle bool, timeout time.Duration) {
+	s.mu.Lock()
+	if s.timeoutErr != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.timeoutErr = &StreamTimeoutError{
+		Idle: idle, Timeout: timeout, LogID: s.httpResponse.LogID(), HTTPStatusCode: s.response.StatusCode,
+	}
+	s.mu.Unlock()
+	s.logger.LogFields(s.ctx, LogLevelWarn, "stream timed out",
+		LogField{Key: "idle", Value: idle},
+		LogField{Key: "timeout", Value: timeout},
+		LogField{Key: LogKeyLogID, Value: s.httpResponse.LogID()},
+	)
+	_ = s.response.Body.Close()
+}
+
+// recvError returns the error ending the stream, the timeout error if the stream timed out.
+func (s *streamReader[T]) recvError(err error) error {
+	s.release()
+	s.mu.Lock()
+	timeoutErr, onTimeout := s.timeoutErr, s.onTimeout
+	s.onTimeout = nil
+	s.mu.Unlock()
+	if timeoutErr == nil {
+		return err
+	}
+	if onTimeout != nil {
+		onTimeout(s.ctx)
+	}
+	return timeoutErr
 }
 
-func (s *streamReader[T]) processLines() (*T, error) {
+func (s *streamReader[T]) processEvents() (*T, error) {
 	err := s.checkRespErr()
 	if err != nil {
 		return nil, err
 	}
+	if s.pendingErr != nil {
+		s.isFinished = true
+		return nil, s.pendingErr
+	}
 	for {
-		line, _, readErr := s.reader.ReadLine()
-		if readErr != nil {
-			return nil, readErr
-		}
-
-		if line == nil {
-			s.isFinished = true
-			break
-		}
-		if len(line) == 0 {
-			continue
-		}
-		event, isDone, err := s.processor(line, s.reader)
+		sseEvent, err := s.sse.Next()
 		if err != nil {
+			if err == io.EOF {
+				s.isFinished = true
+			}
+			if errors.Is(err, errSSELineTooLong) {
+				return nil, s.protocolError("invalid event", "", err)
+			}
 			return nil, err
 		}
+		event, isDone, err := s.decoder(sseEvent)
+		if err != nil {
+			err = s.decodeError(sseEvent, err)
+			if event == nil {
+				return nil, err
+			}
+			// the event reporting the error is returned first
+			s.pendingErr = err
+		}
 		s.isFinished = isDone
 		if event == nil {
 			continue
 		}
 		return event, nil
 	}
-	return nil, io.EOF
 }
 
+// checkRespErr returns the error of a JSON response instead of events, once. The body is empty
+// if the request already checked it.
 func (s *streamReader[T]) checkRespErr() error {
+	if s.checked {
+		return nil
+	}
+	s.checked = true
 	contentType := s.response.Header.Get("Content-Type")
 	if contentType != "" && strings.Contains(contentType, "application/json") {
 		respStr, err := io.ReadAll(s.response.Body)
 		if err != nil {
-			logger.Warnf(s.ctx, "Error reading response body: ", err)
+			s.logger.Warnf(s.ctx, "Error reading response body: %v", err)
 			return err
 		}
-		return isResponseSuccess(s.ctx, &baseResponse{}, respStr, s.httpResponse)
+		if len(bytes.TrimSpace(respStr)) == 0 {
+			return nil
+		}
+		baseResp := &baseResponse{}
+		if err := json.Unmarshal(respStr, baseResp); err != nil {
+			return s.protocolError("invalid json response", string(respStr), err)
+		}
+		return isResponseSuccess(s.ctx, s.logger, baseResp, respStr, s.httpResponse)
 	}
 	return nil
 }
 
+// decodeError returns the error of an event which failed to decode: the StreamError reported by
+// an error event, or a StreamProtocolError for a malformed event.
+func (s *streamReader[T]) decodeError(event *sseEvent, err error) error {
+	if streamErr, ok := AsStreamError(err); ok {
+		streamErr.LogID = s.httpResponse.LogID()
+		streamErr.HTTPStatusCode = s.response.StatusCode
+		streamErr.RawBody = event.Data
+		switch last := any(s.last).(type) {
+		case *ChatEvent:
+			streamErr.LastChatEvent = last
+		case *WorkflowEvent:
+			streamErr.setLastWorkflowEvent(last)
+		}
+		return err
+	}
+	return s.protocolError(fmt.Sprintf("invalid %s event", event.Event), event.Data, err)
+}
+
+func (s *streamReader[T]) protocolError(message, body string, err error) error {
+	return &StreamProtocolError{
+		Message:        message,
+		LogID:          s.httpResponse.LogID(),
+		HTTPStatusCode: s.response.StatusCode,
+		RawBody:        body,
+		Err:            err,
+	}
+}
+
+// release stops the timers of the stream once it ended or was closed. The watch of the context
+// goes on if the context is done, so that the watcher reacts to it.
+func (s *streamReader[T]) release() {
+	if s.totalTimer != nil {
+		s.totalTimer.Stop()
+	}
+	if s.ctx.Err() != nil {
+		return
+	}
+	s.mu.Lock()
+	stopWatch := s.stopWatch
+	s.stopWatch = nil
+	s.mu.Unlock()
+	if stopWatch != nil {
+		stopWatch()
+	}
+}
+
 func (s *streamReader[T]) Close() error {
-	return s.response.Body.Close()
+	s.release()
+	err := s.response.Body.Close()
+	s.observer.end(nil)
+	return err
 }
 
 func (s *streamReader[T]) Response() HTTPResponse {
 	return s.httpResponse
 }
+
+// StreamEvents consumes the stream in a goroutine, sending its events on the returned channel
+// until the end of the stream. The channel is then closed, and the error which ended the stream,
+// nil at its normal end, is sent on the error channel. The stream is closed at its end, or as soon
+// as the context is done.
+func StreamEvents[T streamable](ctx context.Context, stream Stream[T]) (<-chan *T, <-chan error) {
+	events := make(chan *T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		err := consumeStream(ctx, stream, func(event *T) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(events)
+		errs <- err
+	}()
+	return events, errs
+}
+
+// ChatEventHandler handles the events of a chat stream by type. A callback returning an error
+// stops the stream, and Handle returns the error. Events without callback are passed to OnEvent,
+// if set.
+type ChatEventHandler struct {
+	OnChatCreated      func(event *ChatEvent) error
+	OnMessageDelta     func(event *ChatEvent) error
+	OnMessageCompleted func(event *ChatEvent) error
+	OnAudioDelta       func(event *ChatEvent) error
+	OnRequiresAction   func(event *ChatEvent) error
+	OnChatCompleted    func(event *ChatEvent) error
+	OnChatFailed       func(event *ChatEvent) error
+	OnDone             func(event *ChatEvent) error
+	OnEvent            func(event *ChatEvent) error
+
+	// OnError is called with the error which ended the stream, such as an error event of the
+	// server. It is not called when the context is done.
+	OnError func(err error)
+}
+
+// Handle dispatches the events of the stream to the handler until the end of the stream, and
+// closes it. The stream is closed as soon as the context is done.
+func (h *ChatEventHandler) Handle(ctx context.Context, stream Stream[ChatEvent]) error {
+	err := consumeStream(ctx, stream, func(event *ChatEvent) error {
+		if callback := h.callback(event.Event); callback != nil {
+			return callback(event)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil && h.OnError != nil {
+		h.OnError(err)
+	}
+	return err
+}
+
+func (h *ChatEventHandler) callback(eventType ChatEventType) func(event *ChatEvent) error {
+	var callback func(event *ChatEvent) error
+	switch eventType {
+	case ChatEventConversationChatCreated:
+		callback = h.OnChatCreated
+	case ChatEventConversationMessageDelta:
+		callback = h.OnMessageDelta
+	case ChatEventConversationMessageCompleted:
+		callback = h.OnMessageCompleted
+	case ChatEventConversationAudioDelta:
+		callback = h.OnAudioDelta
+	case ChatEventConversationChatRequiresAction:
+		callback = h.OnRequiresAction
+	case ChatEventConversationChatCompleted:
+		callback = h.OnChatCompleted
+	case ChatEventConversationChatFailed:
+		callback = h.OnChatFailed
+	case ChatEventDone:
+		callback = h.OnDone
+	}
+	if callback == nil {
+		callback = h.OnEvent
+	}
+	return callback
+}
+
+// WorkflowEventHandler handles the events of a workflow stream by type, see ChatEventHandler.
+type WorkflowEventHandler struct {
+	OnMessage           func(event *WorkflowEvent) error
+	OnWorkflowInterrupt func(event *WorkflowEvent) error
+	OnWorkflowError     func(event *WorkflowEvent) error
+	OnDone              func(event *WorkflowEvent) error
+	OnEvent             func(event *WorkflowEvent) error
+
+	// OnError is called with the error which ended the stream. It is not called when the context
+	// is done, nor for the error events of the workflow, see OnWorkflowError.
+	OnError func(err error)
+}
+
+// Handle dispatches the events of the stream to the handler until the end of the stream, and
+// closes it. The stream is closed as soon as the context is done.
+func (h *WorkflowEventHandler) Handle(ctx context.Context, stream Stream[WorkflowEvent]) error {
+	err := consumeStream(ctx, stream, func(event *WorkflowEvent) error {
+		if callback := h.callback(event.Event); callback != nil {
+			return callback(event)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil && h.OnError != nil {
+		h.OnError(err)
+	}
+	return err
+}
+
+func (h *WorkflowEventHandler) callback(eventType WorkflowEventType) func(event *WorkflowEvent) error {
+	var callback func(event *WorkflowEvent) error
+	switch eventType {
+	case WorkflowEventTypeMessage:
+		callback = h.OnMessage
+	case WorkflowEventTypeInterrupt:
+		callback = h.OnWorkflowInterrupt
+	case WorkflowEventTypeError:
+		callback = h.OnWorkflowError
+	case WorkflowEventTypeDone:
+		callback = h.OnDone
+	}
+	if callback == nil {
+		callback = h.OnEvent
+	}
+	return callback
+}
+
+// consumeStream calls fn with the events of the stream until its end, which is not an error, or
+// until fn fails. The stream is closed at the end, or as soon as the context is done to unblock
+// Recv, the error of the context being returned then.
+func consumeStream[T streamable](ctx context.Context, stream Stream[T], fn func(event *T) error) error {
+	stop := closeOnDone(ctx, stream)
+	defer func() {
+		stop()
+		_ = stream.Close()
+	}()
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+// closeOnDone closes c once the context is done, until stop is called.
+func closeOnDone(ctx context.Context, c io.Closer) (stop func()) {
+	return afterDone(ctx, func() {
+		_ = c.Close()
+	})
+}
+
+// afterDone calls fn in a goroutine once the context is done, unless stop is called before.
+func afterDone(ctx context.Context, fn func()) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	stopped := make(chan struct{})
+	var once sync.Once
+	go func() {
+		select {
+		case <-ctx.Done():
+			// both may be ready when the goroutine starts
+			select {
+			case <-stopped:
+			default:
+				fn()
+			}
+		case <-stopped:
+		}
+	}()
+	return func() { once.Do(func() { close(stopped) }) }
+}
+
+// sseEvent is an event of a server-sent events stream.
+type sseEvent struct {
+	// ID is the last event ID of the stream, it is kept from one event to the next.
+	ID string
+	// Event is the type of the event, empty if the event has no event field, which the spec
+	// defines as the "message" type.
+	Event string
+	// Data is the data of the event, the data lines being joined with "\n".
+	Data string
+}
+
+// maxSSELineSize bounds the memory used by a single line of a stream.
+const maxSSELineSize = 16 << 20
+
+var errSSELineTooLong = errors.New("server-sent event line too long")
+
+// sseDecoder decodes a text/event-stream following the WHATWG rules: lines end with CRLF, LF or
+// CR, lines starting with a colon are comments, a field without colon has an empty value, a
+// single space after the colon is removed, data lines are joined with LF, id and retry are kept
+// for the following events, unknown fields are ignored, and a blank line dispatches the event.
+//
+// Unlike the spec, an event interrupted by the end of the stream is dispatched, since the API
+// may close the stream right after the last field.
+type sseDecoder struct {
+	reader *bufio.Reader
+	skipLF bool
+
+	lastEventID string
+	retry       time.Duration
+}
+
+func newSSEDecoder(r io.Reader) *sseDecoder {
+	return &sseDecoder{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next event of the stream, or io.EOF at the end of the stream.
+func (d *sseDecoder) Next() (*sseEvent, error) {
+	var (
+		eventType string
+		data      strings.Builder
+		hasData   bool
+	)
+	dispatch := func() *sseEvent {
+		return &sseEvent{
+			ID:    d.lastEventID,
+			Event: eventType,
+			Data:  strings.TrimSuffix(data.String(), "\n"),
+		}
+	}
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			if err == io.EOF && hasData {
+				return dispatch(), nil
+			}
+			return nil, err
+		}
+		if len(line) == 0 {
+			if hasData {
+				return dispatch(), nil
+			}
+			eventType = ""
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				d.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 32); isASCIIDigits(value) && err == nil {
+				d.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// LastEventID returns the ID of the last event, to be sent in the Last-Event-ID header when
+// reconnecting.
+func (d *sseDecoder) LastEventID() string {
+	return d.lastEventID
+}
+
+// Retry returns the reconnection time sent by the server, zero if none was sent.
+func (d *sseDecoder) Retry() time.Duration {
+	return d.retry
+}
+
+// readLine reads a line ending with CRLF, LF or CR, without its ending.
+func (d *sseDecoder) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := d.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+		if d.skipLF {
+			d.skipLF = false
+			if b == '\n' {
+				continue
+			}
+		}
+		switch b {
+		case '\n':
+			return string(line), nil
+		case '\r':
+			d.skipLF = true
+			return string(line), nil
+		}
+		if len(line) >= maxSSELineSize {
+			return "", errSSELineTooLong
+		}
+		line = append(line, b)
+	}
+}
+
+func isASCIIDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
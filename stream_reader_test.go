@@ -1,13 +1,14 @@
 package coze
 
 import (
-	"bufio"
-	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,23 +22,19 @@ func mockHTTPResponse() *httpResponse {
 	}
 }
 
-// Mock event processor for testing
-func mockEventProcessor(line []byte, reader *bufio.Reader) (*WorkflowEvent, bool, error) {
-	if len(line) == 0 {
-		return nil, false, nil
-	}
-
+// Mock event decoder for testing
+func mockEventDecoder(sse *sseEvent) (*WorkflowEvent, bool, error) {
 	// Parse event data
 	event := &WorkflowEvent{
 		ID:    0,
 		Event: WorkflowEventTypeMessage,
 		Message: &WorkflowEventMessage{
-			Content: string(line),
+			Content: sse.Data,
 		},
 	}
 
 	// Check if this is the last event
-	isDone := string(line) == "done"
+	isDone := sse.Data == "done"
 	if isDone {
 		event.Event = WorkflowEventTypeDone
 	}
@@ -58,9 +55,9 @@ func TestStreamReader(t *testing.T) {
 		// Create stream reader
 		reader := &streamReader[WorkflowEvent]{
 			ctx:          ctx,
-			reader:       bufio.NewReader(resp.Body),
+			sse:          newSSEDecoder(resp.Body),
 			response:     resp,
-			processor:    mockEventProcessor,
+			decoder:      mockEventDecoder,
 			httpResponse: mockHTTPResponse(),
 		}
 		defer reader.Close()
@@ -102,9 +99,9 @@ func TestStreamReader(t *testing.T) {
 
 		reader := &streamReader[WorkflowEvent]{
 			ctx:          ctx,
-			reader:       bufio.NewReader(resp.Body),
+			sse:          newSSEDecoder(resp.Body),
 			response:     resp,
-			processor:    mockEventProcessor,
+			decoder:      mockEventDecoder,
 			httpResponse: mockHTTPResponse(),
 		}
 		defer reader.Close()
@@ -139,9 +136,9 @@ func TestStreamReader(t *testing.T) {
 
 		reader := &streamReader[WorkflowEvent]{
 			ctx:          ctx,
-			reader:       bufio.NewReader(errorResp.Body),
+			sse:          newSSEDecoder(errorResp.Body),
 			response:     errorResp,
-			processor:    mockEventProcessor,
+			decoder:      mockEventDecoder,
 			httpResponse: mockHTTPResponse(),
 		}
 		defer reader.Close()
@@ -152,6 +149,63 @@ func TestStreamReader(t *testing.T) {
 		assert.Nil(t, event)
 	})
 
+	t.Run("json error response", func(t *testing.T) {
+		body := `{"code": 4000, "msg": "invalid param"}`
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+		reader := newStreamReader(ctx, newCore(&mockHTTP{}, ComBaseURL), resp, mockEventDecoder)
+		defer reader.Close()
+
+		_, err := reader.Recv()
+		cozeErr, ok := AsCozeError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, 4000, cozeErr.Code)
+		assert.Equal(t, http.StatusOK, cozeErr.HTTPStatusCode)
+		assert.Equal(t, body, cozeErr.RawBody)
+		assert.ErrorIs(t, err, ErrInvalidParameter)
+	})
+
+	t.Run("malformed event", func(t *testing.T) {
+		stream, _ := newTestChatStream(strings.NewReader("event: conversation.message.delta\ndata: {\"id\":\n\n"))
+		_, err := stream.Recv()
+		protocolErr, ok := AsStreamProtocolError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "invalid conversation.message.delta event", protocolErr.Message)
+		assert.Equal(t, `{"id":`, protocolErr.RawBody)
+		assert.Equal(t, http.StatusOK, protocolErr.HTTPStatusCode)
+		assert.ErrorIs(t, err, ErrStreamProtocol)
+		assert.False(t, IsRetryable(err))
+	})
+
+	t.Run("error event", func(t *testing.T) {
+		stream, _ := newTestChatStream(strings.NewReader("event: error\ndata: {\"code\":4013,\"msg\":\"too many requests\"}\n\n"))
+		_, err := stream.Recv()
+		streamErr, ok := AsStreamError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, 4013, streamErr.Code)
+		assert.Equal(t, "too many requests", streamErr.Message)
+		assert.Nil(t, streamErr.LastChatEvent)
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("error event after events", func(t *testing.T) {
+		stream, _ := newTestChatStream(strings.NewReader("event: conversation.chat.created\ndata: {\"id\":\"chat_1\"}\n\n" +
+			"event: error\ndata: {\"code\":4019,\"msg\":\"insufficient balance\"}\n\n"))
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		streamErr, ok := AsStreamError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, event, streamErr.LastChatEvent)
+		assert.Equal(t, `{"code":4019,"msg":"insufficient balance"}`, streamErr.RawBody)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		assert.False(t, IsRetryable(err))
+	})
+
 	t.Run("LogID method", func(t *testing.T) {
 		reader := &streamReader[WorkflowEvent]{
 			ctx:          ctx,
@@ -163,11 +217,391 @@ func TestStreamReader(t *testing.T) {
 
 // Helper function to create mock response with events
 func createMockResponse(events []string) *http.Response {
-	// Join events with newlines
-	body := strings.Join(events, "\n")
+	// Send each event as a data line, empty events as blank lines
+	body := &strings.Builder{}
+	for _, event := range events {
+		if event == "" {
+			body.WriteString("\n")
+			continue
+		}
+		body.WriteString("data: " + event + "\n\n")
+	}
 
 	return &http.Response{
 		StatusCode: http.StatusOK,
-		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Body:       io.NopCloser(strings.NewReader(body.String())),
+	}
+}
+
+func TestSSEDecoder(t *testing.T) {
+	readAll := func(t *testing.T, stream string) []*sseEvent {
+		decoder := newSSEDecoder(strings.NewReader(stream))
+		var events []*sseEvent
+		for {
+			event, err := decoder.Next()
+			if err == io.EOF {
+				return events
+			}
+			require.NoError(t, err)
+			events = append(events, event)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		stream string
+		want   []*sseEvent
+	}{
+		{
+			name:   "fields in any order",
+			stream: "data: {\"a\":1}\nevent: message\nid: 1\n\nid: 2\nevent: done\ndata: [DONE]\n\n",
+			want: []*sseEvent{
+				{ID: "1", Event: "message", Data: `{"a":1}`},
+				{ID: "2", Event: "done", Data: "[DONE]"},
+			},
+		},
+		{
+			name:   "multi-line data",
+			stream: "event: message\ndata: first\ndata:second\ndata\n\n",
+			want:   []*sseEvent{{Event: "message", Data: "first\nsecond\n"}},
+		},
+		{
+			name:   "CRLF and CR line endings",
+			stream: "event: a\r\ndata: 1\r\n\r\nevent: b\rdata: 2\r\rdata: 3\n\n",
+			want: []*sseEvent{
+				{Event: "a", Data: "1"},
+				{Event: "b", Data: "2"},
+				{Data: "3"},
+			},
+		},
+		{
+			name:   "comments, unknown fields and events without data are ignored",
+			stream: ": ping\n\nevent: ignored\n\nfoo: bar\nevent: message\ndata:  two spaces\n\n",
+			want:   []*sseEvent{{Event: "message", Data: " two spaces"}},
+		},
+		{
+			name:   "id persists, and ids with NUL are ignored",
+			stream: "id: 7\ndata: a\n\ndata: b\n\nid: x\x00y\ndata: c\n\nid\ndata: d\n\n",
+			want: []*sseEvent{
+				{ID: "7", Data: "a"},
+				{ID: "7", Data: "b"},
+				{ID: "7", Data: "c"},
+				{ID: "", Data: "d"},
+			},
+		},
+		{
+			name:   "pending event is dispatched at the end of the stream",
+			stream: "event: done\ndata: {}",
+			want:   []*sseEvent{{Event: "done", Data: "{}"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, readAll(t, tt.stream))
+		})
 	}
+
+	t.Run("retry", func(t *testing.T) {
+		decoder := newSSEDecoder(strings.NewReader("retry: 1500\ndata: a\n\nretry: 1s\ndata: b\n\n"))
+		_, err := decoder.Next()
+		require.NoError(t, err)
+		assert.Equal(t, 1500*time.Millisecond, decoder.Retry())
+		_, err = decoder.Next()
+		require.NoError(t, err)
+		assert.Equal(t, 1500*time.Millisecond, decoder.Retry())
+	})
+
+	t.Run("chat and workflow streams", func(t *testing.T) {
+		testCore := &core{logger: newClientLogger(newStdLogger(), LogLevelError, nil)}
+		chat := newStreamReader(context.Background(), testCore, &http.Response{
+			Body: io.NopCloser(strings.NewReader("event:conversation.message.delta\r\ndata:{\"content\":\"hi\"}\r\n\r\n" +
+				": keep-alive\r\n\r\nevent:done\r\ndata:\"[DONE]\"\r\n\r\n")),
+		}, chatEventDecoder(testCore.logger))
+		event, err := chat.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, "hi", event.Message.Content)
+		event, err = chat.Recv()
+		require.NoError(t, err)
+		assert.True(t, event.IsDone())
+
+		workflow := newStreamReader(context.Background(), testCore, &http.Response{
+			Body: io.NopCloser(strings.NewReader("event: Message\ndata: {\"content\":\"hi\"}\nid: 3\n\n")),
+		}, decodeWorkflowEvent)
+		wfEvent, err := workflow.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, 3, wfEvent.ID)
+		assert.Equal(t, "hi", wfEvent.Message.Content)
+	})
+}
+
+func FuzzSSEDecoder(f *testing.F) {
+	f.Add("event: message\ndata: {}\nid: 1\n\n")
+	f.Add("data: a\r\ndata: b\r\rretry: 10\n: comment\n\n")
+	f.Add("id: \x00\nevent\ndata\n\n\r\n\r")
+	f.Add("event: conversation.chat.completed\ndata: {\"usage\":{}}\n\nevent: done\ndata: [DONE]")
+	f.Add("event: Interrupt\ndata: {\"interrupt_data\":null}\nid: x\n\nevent: Done\ndata: \n\n")
+	f.Fuzz(func(t *testing.T, stream string) {
+		decoder := newSSEDecoder(strings.NewReader(stream))
+		for i := 0; ; i++ {
+			event, err := decoder.Next()
+			if err != nil {
+				require.Equal(t, io.EOF, err)
+				break
+			}
+			require.NotNil(t, event)
+			require.LessOrEqual(t, i, len(stream))
+		}
+
+		// the chat and workflow decoders never panic either
+		testCore := &core{logger: newClientLogger(&captureLogger{}, LogLevelError, nil)}
+		newReader := func() *http.Response {
+			return &http.Response{Body: io.NopCloser(strings.NewReader(stream))}
+		}
+		chat := newStreamReader(context.Background(), testCore, newReader(), chatEventDecoder(testCore.logger))
+		for i := 0; i <= len(stream); i++ {
+			if _, err := chat.Recv(); err != nil {
+				break
+			}
+		}
+		workflow := newStreamReader(context.Background(), testCore, newReader(), decodeWorkflowEvent)
+		for i := 0; i <= len(stream); i++ {
+			if _, err := workflow.Recv(); err != nil {
+				break
+			}
+		}
+	})
+}
+
+const testChatStream = "event: conversation.chat.created\ndata: {\"id\":\"chat1\"}\n\n" +
+	"event: conversation.message.delta\ndata: {\"content\":\"a\"}\n\n" +
+	"event: conversation.message.delta\ndata: {\"content\":\"b\"}\n\n" +
+	"event: conversation.chat.completed\ndata: {\"id\":\"chat1\"}\n\n" +
+	"event: done\ndata: [DONE]\n\n"
+
+// newTestChatStream returns a chat stream reading the body, and whether its body was closed.
+func newTestChatStream(body io.Reader) (Stream[ChatEvent], *bool) {
+	core := newCore(&mockHTTP{}, ComBaseURL)
+	closed := false
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: &closeRecorder{Reader: body, closed: &closed}}
+	return newStreamReader(context.Background(), core, resp, chatEventDecoder(core.logger)), &closed
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed *bool
+}
+
+func (c *closeRecorder) Close() error {
+	*c.closed = true
+	if closer, ok := c.Reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func TestStreamEvents(t *testing.T) {
+	t.Run("events until the end of the stream", func(t *testing.T) {
+		stream, closed := newTestChatStream(strings.NewReader(testChatStream))
+		events, errs := StreamEvents(context.Background(), stream)
+
+		var types []ChatEventType
+		for event := range events {
+			types = append(types, event.Event)
+		}
+		require.NoError(t, <-errs)
+		assert.Equal(t, []ChatEventType{
+			ChatEventConversationChatCreated, ChatEventConversationMessageDelta, ChatEventConversationMessageDelta,
+			ChatEventConversationChatCompleted, ChatEventDone,
+		}, types)
+		assert.True(t, *closed)
+	})
+
+	t.Run("error of the stream", func(t *testing.T) {
+		stream, _ := newTestChatStream(strings.NewReader("event: error\ndata: internal error\n\n"))
+		events, errs := StreamEvents(context.Background(), stream)
+		for range events {
+		}
+		streamErr, ok := AsStreamError(<-errs)
+		require.True(t, ok)
+		assert.Equal(t, "internal error", streamErr.Message)
+	})
+
+	t.Run("stream is closed when the context is done", func(t *testing.T) {
+		body, writer := io.Pipe()
+		stream, _ := newTestChatStream(body)
+		ctx, cancel := context.WithCancel(context.Background())
+		events, errs := StreamEvents(ctx, stream)
+
+		go func() {
+			_, _ = writer.Write([]byte("event: conversation.message.delta\ndata: {\"content\":\"a\"}\n\n"))
+		}()
+		event := <-events
+		assert.Equal(t, "a", event.Message.Content)
+
+		// Recv is blocked until the stream is closed
+		cancel()
+		for range events {
+		}
+		assert.ErrorIs(t, <-errs, context.Canceled)
+	})
+}
+
+func TestEventHandlers(t *testing.T) {
+	t.Run("chat events are dispatched by type", func(t *testing.T) {
+		stream, closed := newTestChatStream(strings.NewReader(testChatStream))
+		content := ""
+		var others []ChatEventType
+		done := false
+		err := (&ChatEventHandler{
+			OnMessageDelta: func(event *ChatEvent) error {
+				content += event.Message.Content
+				return nil
+			},
+			OnDone: func(event *ChatEvent) error {
+				done = true
+				return nil
+			},
+			OnEvent: func(event *ChatEvent) error {
+				others = append(others, event.Event)
+				return nil
+			},
+		}).Handle(context.Background(), stream)
+		require.NoError(t, err)
+		assert.Equal(t, "ab", content)
+		assert.True(t, done)
+		assert.Equal(t, []ChatEventType{ChatEventConversationChatCreated, ChatEventConversationChatCompleted}, others)
+		assert.True(t, *closed)
+	})
+
+	t.Run("callback error stops the stream", func(t *testing.T) {
+		stream, closed := newTestChatStream(strings.NewReader(testChatStream))
+		stop := errors.New("stop")
+		deltas := 0
+		var handled error
+		err := (&ChatEventHandler{
+			OnMessageDelta: func(event *ChatEvent) error {
+				deltas++
+				return stop
+			},
+			OnError: func(err error) {
+				handled = err
+			},
+		}).Handle(context.Background(), stream)
+		assert.Equal(t, stop, err)
+		assert.Equal(t, stop, handled)
+		assert.Equal(t, 1, deltas)
+		assert.True(t, *closed)
+	})
+
+	t.Run("workflow events are dispatched by type", func(t *testing.T) {
+		resp, err := mockStreamResponse("id: 0\nevent: Message\ndata: {\"content\":\"a\"}\n\n" +
+			"id: 1\nevent: Error\ndata: {\"error_code\":1,\"error_message\":\"failed\"}\n\n" +
+			"id: 2\nevent: Interrupt\ndata: {\"interrupt_data\":{\"event_id\":\"e1\",\"type\":2}}\n\n")
+		require.NoError(t, err)
+		stream := newStreamReader(context.Background(), newCore(&mockHTTP{}, ComBaseURL), resp, decodeWorkflowEvent)
+
+		var handled []WorkflowEventType
+		record := func(event *WorkflowEvent) error {
+			handled = append(handled, event.Event)
+			return nil
+		}
+		var onError error
+		err = (&WorkflowEventHandler{
+			OnMessage:           record,
+			OnWorkflowError:     record,
+			OnWorkflowInterrupt: record,
+			OnError: func(err error) {
+				onError = err
+			},
+		}).Handle(context.Background(), stream)
+		streamErr, ok := AsStreamError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, "failed", streamErr.Message)
+		assert.Equal(t, err, onError)
+		assert.Equal(t, []WorkflowEventType{WorkflowEventTypeMessage, WorkflowEventTypeError}, handled)
+	})
+}
+
+func TestStreamTimeouts(t *testing.T) {
+	// newStalledResponse returns a response sending the events, then nothing until the body is closed.
+	newStalledResponse := func(events string) *http.Response {
+		body, writer := io.Pipe()
+		go func() {
+			_, _ = writer.Write([]byte(events))
+		}()
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body}
+	}
+	const createdEvent = "event: conversation.chat.created\ndata: {\"id\":\"chat1\",\"conversation_id\":\"conv1\"}\n\n"
+
+	t.Run("idle timeout", func(t *testing.T) {
+		ctx := withCallOptions(context.Background(), []CallOption{WithCallStreamIdleTimeout(50 * time.Millisecond)})
+		core := newCore(&mockHTTP{}, ComBaseURL)
+		stream := newStreamReader(ctx, core, newStalledResponse(createdEvent), chatEventDecoder(core.logger))
+
+		_, err := stream.Recv()
+		require.NoError(t, err)
+		start := time.Now()
+		_, err = stream.Recv()
+		timeoutErr, ok := AsStreamTimeoutError(err)
+		require.True(t, ok, err)
+		assert.True(t, timeoutErr.Idle)
+		assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+		assert.Equal(t, http.StatusOK, timeoutErr.HTTPStatusCode)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("idle timeout only runs during Recv", func(t *testing.T) {
+		ctx := withCallOptions(context.Background(), []CallOption{WithCallStreamIdleTimeout(20 * time.Millisecond)})
+		core := newCore(&mockHTTP{}, ComBaseURL)
+		resp, err := mockStreamResponse(createdEvent + "event: done\ndata: [DONE]\n\n")
+		require.NoError(t, err)
+		stream := newStreamReader(ctx, core, resp, chatEventDecoder(core.logger))
+
+		for i := 0; i < 2; i++ {
+			time.Sleep(40 * time.Millisecond)
+			_, err := stream.Recv()
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("total timeout", func(t *testing.T) {
+		ctx := withCallOptions(context.Background(), []CallOption{WithCallStreamTimeout(50 * time.Millisecond)})
+		core := newCore(&mockHTTP{}, ComBaseURL)
+		stream := newStreamReader(ctx, core, newStalledResponse(createdEvent), chatEventDecoder(core.logger))
+
+		_, err := stream.Recv()
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		timeoutErr, ok := AsStreamTimeoutError(err)
+		require.True(t, ok, err)
+		assert.False(t, timeoutErr.Idle)
+		require.NoError(t, stream.Close())
+	})
+
+	t.Run("cancel the chat on timeout", func(t *testing.T) {
+		var canceled *CancelChatsReq
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				switch req.URL.Path {
+				case "/v3/chat":
+					return newStalledResponse(createdEvent), nil
+				case "/v3/chat/cancel":
+					canceled = &CancelChatsReq{}
+					require.NoError(t, json.NewDecoder(req.Body).Decode(canceled))
+					return mockResponse(http.StatusOK, &cancelChatsResp{Chat: &CancelChatsResp{}})
+				}
+				return nil, errors.New("unexpected request")
+			},
+		}}, ComBaseURL)
+
+		stream, err := newChats(core).Stream(context.Background(), &CreateChatsReq{BotID: "bot1"},
+			WithCallStreamIdleTimeout(20*time.Millisecond), WithCallCancelChatOnTimeout())
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, &CancelChatsReq{ConversationID: "conv1", ChatID: "chat1"}, canceled)
+	})
 }
@@ -0,0 +1,306 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// StreamReconnectPolicy describes how a workflow stream recovers when its connection drops before
+// the end of the run. The run history is polled until the run ends, or, if the server supports
+// it, the stream is first requested again with the Last-Event-ID header and the events already
+// delivered are skipped.
+type StreamReconnectPolicy struct {
+	// Resume requests the stream again with the Last-Event-ID header after a drop. Only set it
+	// when the server resumes the stream from that header: otherwise the request starts the
+	// workflow again, with its side effects.
+	Resume bool
+
+	// MaxReconnects is the number of reconnections attempted after a drop when Resume is set.
+	// Zero skips straight to polling the run history.
+	MaxReconnects int
+
+	// BaseDelay is the delay before the first reconnection. It is doubled for every following one.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between two reconnections.
+	MaxDelay time.Duration
+
+	// Jitter is the fraction of the delay, between 0 and 1, that is randomized.
+	Jitter float64
+
+	// PollInterval is the interval between two polls of the run history. Zero disables polling,
+	// the error of the stream is then returned.
+	PollInterval time.Duration
+}
+
+// DefaultStreamReconnectPolicy returns a reconnect policy with sensible defaults: polling the run
+// history every 2s, and if Resume is set, 3 reconnections first with exponential backoff from
+// 500ms up to 8s with 20% jitter.
+func DefaultStreamReconnectPolicy() *StreamReconnectPolicy {
+	return &StreamReconnectPolicy{
+		MaxReconnects: 3,
+		BaseDelay:     500 * time.Millisecond,
+		MaxDelay:      8 * time.Second,
+		Jitter:        0.2,
+		PollInterval:  2 * time.Second,
+	}
+}
+
+// WithCallStreamReconnect makes a workflow stream, as returned by Workflows.Runs.Stream and
+// Resume, recover after its connection dropped, see StreamReconnectPolicy.
+//
+// Polling the run history requires the execute id of the run. The stream API has no field for it:
+// it is only found in the ext of a message or the debug url of an event, which the server usually
+// sends with the last events of the run. A stream dropped before returns its error, the polling
+// mostly recovers the streams dropped while delivering their last events.
+func WithCallStreamReconnect(policy *StreamReconnectPolicy) CallOption {
+	return func(opt *callOption) {
+		opt.streamReconnect = policy
+	}
+}
+
+func streamReconnectPolicyFor(ctx context.Context) *StreamReconnectPolicy {
+	if opt := getCallOption(ctx); opt != nil {
+		return opt.streamReconnect
+	}
+	return nil
+}
+
+const lastEventIDHeader = "Last-Event-ID"
+
+// workflowStreamConnector opens a workflow stream, resuming after lastEventID if it is not empty.
+type workflowStreamConnector func(ctx context.Context, lastEventID string) (*streamReader[WorkflowEvent], error)
+
+// reconnectingWorkflowStream is a workflow stream reconnecting after drops, see
+// StreamReconnectPolicy.
+type reconnectingWorkflowStream struct {
+	ctx        context.Context
+	policy     *StreamReconnectPolicy
+	connect    workflowStreamConnector
+	histories  *workflowRunsHistories
+	logger     *levelLogger
+	workflowID string
+
+	current    *streamReader[WorkflowEvent]
+	reconnects int
+	delivered  bool
+	lastID     int
+	executeID  string
+	finished   bool
+	pending    []*WorkflowEvent
+	// last is the last event delivered, and endErr the error returned once the stream ended with
+	// an Error event.
+	last   *WorkflowEvent
+	endErr error
+}
+
+func newReconnectingWorkflowStream(ctx context.Context, core *core, policy *StreamReconnectPolicy, workflowID string,
+	reader *streamReader[WorkflowEvent], connect workflowStreamConnector,
+) *reconnectingWorkflowStream {
+	return &reconnectingWorkflowStream{
+		ctx:        ctx,
+		policy:     policy,
+		connect:    connect,
+		histories:  newWorkflowRunsHistories(core),
+		logger:     core.logger,
+		workflowID: workflowID,
+		current:    reader,
+	}
+}
+
+func (s *reconnectingWorkflowStream) Recv() (*WorkflowEvent, error) {
+	for {
+		if len(s.pending) > 0 {
+			event := s.pending[0]
+			s.pending = s.pending[1:]
+			return s.deliver(event), nil
+		}
+		if s.finished {
+			if s.endErr != nil {
+				return nil, s.endErr
+			}
+			return nil, io.EOF
+		}
+
+		event, err := s.current.Recv()
+		if err == io.EOF {
+			// The server ended the stream without a terminal event known to the SDK, the run may
+			// still go on: its end is read from the history.
+			if pollErr := s.poll(err); pollErr != nil {
+				return nil, pollErr
+			}
+			continue
+		}
+		if err == nil {
+			if s.delivered && event.ID <= s.lastID {
+				continue
+			}
+			s.delivered, s.lastID = true, event.ID
+			s.reconnects = 0
+			if executeID := workflowEventExecuteID(event); executeID != "" {
+				s.executeID = executeID
+			}
+			s.finished = isTerminalWorkflowEvent(event)
+			return s.deliver(event), nil
+		}
+		if !isStreamDropped(s.ctx, err) {
+			return nil, err
+		}
+		if reconnectErr := s.reconnect(err); reconnectErr != nil {
+			return nil, reconnectErr
+		}
+	}
+}
+
+// deliver records the event returned by Recv, and the error reported by an Error event.
+func (s *reconnectingWorkflowStream) deliver(event *WorkflowEvent) *WorkflowEvent {
+	if event.Event == WorkflowEventTypeError {
+		streamErr := newWorkflowStreamError(event)
+		streamErr.LogID = s.current.Response().LogID()
+		streamErr.HTTPStatusCode = s.current.response.StatusCode
+		streamErr.setLastWorkflowEvent(s.last)
+		s.endErr = streamErr
+	}
+	s.last = event
+	return event
+}
+
+// reconnect replaces the dropped stream if the policy resumes streams, or polls the run history
+// once it cannot be resumed. The reconnections are counted until the stream delivers a new event,
+// so that a stream dropping right after every reconnection ends up polled.
+func (s *reconnectingWorkflowStream) reconnect(dropErr error) error {
+	if !s.policy.Resume {
+		_ = s.current.Close()
+		return s.poll(dropErr)
+	}
+	lastEventID := ""
+	if s.delivered {
+		lastEventID = strconv.Itoa(s.lastID)
+	}
+	_ = s.current.Close()
+
+	backoff := &RetryPolicy{BaseDelay: s.policy.BaseDelay, MaxDelay: s.policy.MaxDelay, Jitter: s.policy.Jitter}
+	err := dropErr
+	for s.reconnects < s.policy.MaxReconnects {
+		s.reconnects++
+		attempt := s.reconnects
+		delay := backoff.backoff(attempt, 0)
+		s.logger.LogFields(s.ctx, LogLevelWarn, "reconnect workflow stream",
+			LogField{Key: LogKeyWorkflowID, Value: s.workflowID},
+			LogField{Key: LogKeyAttempt, Value: attempt},
+			LogField{Key: "last_event_id", Value: lastEventID},
+			LogField{Key: "delay", Value: delay},
+			LogField{Key: LogKeyError, Value: err},
+		)
+		if sleepErr := sleepContext(s.ctx, delay); sleepErr != nil {
+			return sleepErr
+		}
+		var reader *streamReader[WorkflowEvent]
+		reader, err = s.connect(s.ctx, lastEventID)
+		if err == nil {
+			s.current = reader
+			return nil
+		}
+		if !isStreamDropped(s.ctx, err) {
+			break
+		}
+	}
+	return s.poll(dropErr)
+}
+
+// poll waits for the end of the run in its history, and turns the result into the last events of
+// the stream.
+func (s *reconnectingWorkflowStream) poll(dropErr error) error {
+	if s.policy.PollInterval <= 0 || s.executeID == "" {
+		return dropErr
+	}
+	req := &RetrieveWorkflowsRunsHistoriesReq{WorkflowID: s.workflowID, ExecuteID: s.executeID}
+	opts := &PollOptions{InitialInterval: s.policy.PollInterval, BackoffFactor: 1}
+	history, _, err := PollUntil(s.ctx, opts, func(ctx context.Context) (*WorkflowRunHistory, bool, error) {
+		resp, err := s.histories.Retrieve(ctx, req)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(resp.Histories) > 0 && resp.Histories[0].ExecuteStatus != WorkflowExecuteStatusRunning {
+			return resp.Histories[0], true, nil
+		}
+		return nil, false, nil
+	})
+	if err != nil {
+		return err
+	}
+	s.pending = s.historyEvents(history)
+	s.finished = true
+	return nil
+}
+
+// historyEvents returns the events ending the stream of a run which ended in history.
+func (s *reconnectingWorkflowStream) historyEvents(history *WorkflowRunHistory) []*WorkflowEvent {
+	id := s.lastID + 1
+	if !s.delivered {
+		id = 0
+	}
+	if history.ExecuteStatus == WorkflowExecuteStatusFail {
+		code, _ := strconv.Atoi(history.ErrorCode)
+		return []*WorkflowEvent{{
+			ID:    id,
+			Event: WorkflowEventTypeError,
+			Error: &WorkflowEventError{ErrorCode: code, ErrorMessage: history.ErrorMessage},
+		}}
+	}
+	return []*WorkflowEvent{
+		{ID: id, Event: WorkflowEventTypeMessage, Message: &WorkflowEventMessage{Content: history.Output, NodeIsFinish: true}},
+		{ID: id + 1, Event: WorkflowEventTypeDone, DebugURL: &WorkflowEventDebugURL{URL: history.DebugURL}},
+	}
+}
+
+func (s *reconnectingWorkflowStream) Close() error {
+	s.finished, s.pending = true, nil
+	return s.current.Close()
+}
+
+func (s *reconnectingWorkflowStream) Response() HTTPResponse {
+	return s.current.Response()
+}
+
+// isStreamDropped reports whether the stream failed because of its connection, rather than the
+// caller's context or an error of the server. A stream the server ended cleanly is not dropped.
+func isStreamDropped(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isTerminalWorkflowEvent reports whether the server closes the stream after the event.
+func isTerminalWorkflowEvent(event *WorkflowEvent) bool {
+	switch event.Event {
+	case WorkflowEventTypeDone, WorkflowEventTypeError, WorkflowEventTypeInterrupt:
+		return true
+	}
+	return false
+}
+
+// workflowEventExecuteID returns the execute id of the run reported by the event, if any.
+func workflowEventExecuteID(event *WorkflowEvent) string {
+	if event.Message != nil {
+		if executeID, ok := event.Message.Ext["execute_id"].(string); ok {
+			return executeID
+		}
+	}
+	if event.DebugURL != nil {
+		if u, err := url.Parse(event.DebugURL.URL); err == nil {
+			return u.Query().Get("execute_id")
+		}
+	}
+	return ""
+}
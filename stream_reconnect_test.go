@@ -0,0 +1,282 @@
+package coze
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// droppedBody returns the data, then fails as a connection dropped midway.
+type droppedBody struct {
+	io.Reader
+}
+
+func (b *droppedBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (b *droppedBody) Close() error {
+	return nil
+}
+
+func newTestReconnectPolicy() *StreamReconnectPolicy {
+	return &StreamReconnectPolicy{Resume: true, MaxReconnects: 2, PollInterval: time.Millisecond}
+}
+
+func recvAllWorkflowEvents(t *testing.T, stream Stream[WorkflowEvent]) ([]*WorkflowEvent, error) {
+	t.Helper()
+	var events []*WorkflowEvent
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
+
+func TestWorkflowStreamReconnect(t *testing.T) {
+	const firstEvents = "id: 0\nevent: Message\ndata: {\"content\":\"a\",\"ext\":{\"execute_id\":\"exec1\"}}\n\n" +
+		"id: 1\nevent: Message\ndata: {\"content\":\"b\"}\n\n"
+
+	t.Run("resume with last event id and skip delivered events", func(t *testing.T) {
+		var lastEventIDs []string
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				lastEventIDs = append(lastEventIDs, req.Header.Get(lastEventIDHeader))
+				if len(lastEventIDs) == 1 {
+					return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+						Body: &droppedBody{Reader: strings.NewReader(firstEvents)}}, nil
+				}
+				return mockStreamResponse("id: 1\nevent: Message\ndata: {\"content\":\"b\"}\n\n" +
+					"id: 2\nevent: Message\ndata: {\"content\":\"c\"}\n\n" +
+					"id: 3\nevent: Done\ndata: {}\n\n")
+			},
+		}}, ComBaseURL)
+
+		stream, err := newWorkflowRun(core).Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"},
+			WithCallStreamReconnect(newTestReconnectPolicy()))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		events, err := recvAllWorkflowEvents(t, stream)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "1"}, lastEventIDs)
+		require.Len(t, events, 4)
+		for i, event := range events {
+			assert.Equal(t, i, event.ID)
+		}
+		assert.Equal(t, "c", events[2].Message.Content)
+		assert.Equal(t, WorkflowEventTypeDone, events[3].Event)
+	})
+
+	t.Run("poll the run history when the stream cannot be resumed", func(t *testing.T) {
+		streams := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				switch req.URL.Path {
+				case "/v1/workflow/stream_run":
+					streams++
+					if streams == 1 {
+						return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+							Body: &droppedBody{Reader: strings.NewReader(firstEvents)}}, nil
+					}
+					resp, err := mockResponse(http.StatusOK, &baseResponse{Code: 4000, Msg: "cannot resume"})
+					resp.Header.Set("Content-Type", "application/json")
+					return resp, err
+				case "/v1/workflows/wf1/run_histories/exec1":
+					return mockResponse(http.StatusOK, &retrieveWorkflowRunsHistoriesResp{
+						RetrieveWorkflowRunsHistoriesResp: &RetrieveWorkflowRunsHistoriesResp{
+							Histories: []*WorkflowRunHistory{{ExecuteID: "exec1", ExecuteStatus: WorkflowExecuteStatusSuccess, Output: "done"}},
+						},
+					})
+				}
+				t.Fatalf("unexpected request %s", req.URL.Path)
+				return nil, nil
+			},
+		}}, ComBaseURL)
+
+		stream, err := newWorkflowRun(core).Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"},
+			WithCallStreamReconnect(newTestReconnectPolicy()))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		events, err := recvAllWorkflowEvents(t, stream)
+		require.NoError(t, err)
+		assert.Equal(t, 2, streams)
+		require.Len(t, events, 4)
+		assert.Equal(t, 2, events[2].ID)
+		assert.Equal(t, "done", events[2].Message.Content)
+		assert.Equal(t, WorkflowEventTypeDone, events[3].Event)
+	})
+
+	t.Run("poll the run history without sending the request again unless resume is set", func(t *testing.T) {
+		for name, body := range map[string]func() io.ReadCloser{
+			"dropped": func() io.ReadCloser { return &droppedBody{Reader: strings.NewReader(firstEvents)} },
+			"ended":   func() io.ReadCloser { return io.NopCloser(strings.NewReader(firstEvents)) },
+		} {
+			streams := 0
+			core := newCore(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					switch req.URL.Path {
+					case "/v1/workflow/stream_run":
+						streams++
+						return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body()}, nil
+					case "/v1/workflows/wf1/run_histories/exec1":
+						return mockResponse(http.StatusOK, &retrieveWorkflowRunsHistoriesResp{
+							RetrieveWorkflowRunsHistoriesResp: &RetrieveWorkflowRunsHistoriesResp{
+								Histories: []*WorkflowRunHistory{{ExecuteID: "exec1", ExecuteStatus: WorkflowExecuteStatusSuccess, Output: "done"}},
+							},
+						})
+					}
+					t.Fatalf("unexpected request %s", req.URL.Path)
+					return nil, nil
+				},
+			}}, ComBaseURL)
+
+			policy := DefaultStreamReconnectPolicy()
+			policy.PollInterval = time.Millisecond
+			stream, err := newWorkflowRun(core).Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"},
+				WithCallStreamReconnect(policy))
+			require.NoError(t, err, name)
+
+			events, err := recvAllWorkflowEvents(t, stream)
+			require.NoError(t, err, name)
+			assert.Equal(t, 1, streams, name)
+			require.Len(t, events, 4, name)
+			assert.Equal(t, "done", events[2].Message.Content, name)
+			assert.Equal(t, WorkflowEventTypeDone, events[3].Event, name)
+			require.NoError(t, stream.Close())
+		}
+	})
+
+	t.Run("drop before the execute id is seen returns the drop error", func(t *testing.T) {
+		requests := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				requests++
+				require.Equal(t, "/v1/workflow/stream_run", req.URL.Path, "the run history is not polled")
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+					Body: &droppedBody{Reader: strings.NewReader("id: 0\nevent: Message\ndata: {\"content\":\"a\",\"node_title\":\"Start\"}\n\n")}}, nil
+			},
+		}}, ComBaseURL)
+
+		stream, err := newWorkflowRun(core).Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"},
+			WithCallStreamReconnect(DefaultStreamReconnectPolicy()))
+		require.NoError(t, err)
+		events, err := recvAllWorkflowEvents(t, stream)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Len(t, events, 1)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("error event ends the stream with a stream error", func(t *testing.T) {
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				resp, err := mockStreamResponse("id: 0\nevent: Message\ndata: {\"content\":\"a\",\"node_title\":\"Start\"}\n\n" +
+					"id: 1\nevent: Error\ndata: {\"error_code\":5000,\"error_message\":\"node failed\"}\n\n")
+				resp.Header.Set(logIDHeader, "test_log_id")
+				return resp, err
+			},
+		}}, ComBaseURL)
+
+		stream, err := newWorkflowRun(core).Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"},
+			WithCallStreamReconnect(newTestReconnectPolicy()))
+		require.NoError(t, err)
+		defer stream.Close()
+
+		events, err := recvAllWorkflowEvents(t, stream)
+		require.Len(t, events, 2)
+		streamErr, ok := AsStreamError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, 5000, streamErr.Code)
+		assert.Equal(t, "test_log_id", streamErr.LogID)
+		assert.Equal(t, "Start", streamErr.NodeTitle)
+		assert.Equal(t, events[0], streamErr.LastWorkflowEvent)
+		assert.ErrorIs(t, err, ErrServer)
+	})
+
+	t.Run("failed run from history", func(t *testing.T) {
+		stream := &reconnectingWorkflowStream{delivered: true, lastID: 4}
+		events := stream.historyEvents(&WorkflowRunHistory{
+			ExecuteStatus: WorkflowExecuteStatusFail, ErrorCode: "720701013", ErrorMessage: "node failed",
+		})
+		require.Len(t, events, 1)
+		assert.Equal(t, 5, events[0].ID)
+		assert.Equal(t, &WorkflowEventError{ErrorCode: 720701013, ErrorMessage: "node failed"}, events[0].Error)
+	})
+
+	t.Run("drop error is returned without policy or execute id", func(t *testing.T) {
+		for _, opts := range [][]CallOption{nil, {WithCallStreamReconnect(&StreamReconnectPolicy{PollInterval: time.Millisecond})}} {
+			core := newCore(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+						Body: &droppedBody{Reader: strings.NewReader("id: 0\nevent: Message\ndata: {}\n\n")}}, nil
+				},
+			}}, ComBaseURL)
+
+			stream, err := newWorkflowRun(core).Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"}, opts...)
+			require.NoError(t, err)
+			events, err := recvAllWorkflowEvents(t, stream)
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			assert.Len(t, events, 1)
+			require.NoError(t, stream.Close())
+		}
+	})
+
+	t.Run("reconnections are bounded while no new event is delivered", func(t *testing.T) {
+		requests := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				requests++
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+					Body: &droppedBody{Reader: strings.NewReader("id: 0\nevent: Message\ndata: {}\n\n")}}, nil
+			},
+		}}, ComBaseURL)
+
+		stream, err := newWorkflowRun(core).Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"},
+			WithCallStreamReconnect(newTestReconnectPolicy()))
+		require.NoError(t, err)
+		events, err := recvAllWorkflowEvents(t, stream)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Len(t, events, 1)
+		assert.Equal(t, 3, requests)
+	})
+
+	t.Run("canceled context is not reconnected", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		requests := 0
+		core := newCore(&http.Client{Transport: &mockTransport{
+			roundTripFunc: func(req *http.Request) (*http.Response, error) {
+				requests++
+				return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+					Body: &droppedBody{Reader: strings.NewReader(firstEvents)}}, nil
+			},
+		}}, ComBaseURL)
+
+		stream, err := newWorkflowRun(core).Resume(ctx, &ResumeRunWorkflowsReq{WorkflowID: "wf1"},
+			WithCallStreamReconnect(newTestReconnectPolicy()))
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		cancel()
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Error(t, err)
+		assert.Equal(t, 1, requests)
+	})
+}
@@ -6,6 +6,13 @@ import (
 	"net/http"
 )
 
+// TemplatesService is the template api, see CozeAPI.Templates.
+type TemplatesService interface {
+	Duplicate(ctx context.Context, templateID string, req *DuplicateTemplateReq, opts ...CallOption) (*TemplateDuplicateResp, error)
+}
+
+var _ TemplatesService = (*templates)(nil)
+
 // templates provides access to template-related operations
 type templates struct {
 	core *core
@@ -17,7 +24,8 @@ func newTemplates(core *core) *templates {
 }
 
 // Duplicate creates a copy of an existing template
-func (c *templates) Duplicate(ctx context.Context, templateID string, req *DuplicateTemplateReq) (*TemplateDuplicateResp, error) {
+func (c *templates) Duplicate(ctx context.Context, templateID string, req *DuplicateTemplateReq, opts ...CallOption) (*TemplateDuplicateResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	url := fmt.Sprintf("/v1/templates/%s/duplicate", templateID)
 
 	var resp templateDuplicateResp
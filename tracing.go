@@ -0,0 +1,65 @@
+package coze
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer creates a span for every API call of the client. It is a small subset of the
+// OpenTelemetry API, so that the SDK has no tracing dependency, see the cozeotel module for an
+// adapter to an OpenTelemetry tracer provider.
+type Tracer interface {
+	// Start starts the span of a call, the returned context carries the span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+
+	// Inject writes the trace context carried by ctx into the headers of the request, e.g. the W3C
+	// traceparent and tracestate headers.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is the span of a single API call. The span of a stream or a binary response ends when its
+// body is fully read or closed.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key value pair attached to a span, the value is a string, bool, int, int64 or
+// float64.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Keys of the attributes set on the spans of the SDK.
+const (
+	AttrOperation          = "coze.operation"
+	AttrHTTPMethod         = "http.request.method"
+	AttrURLPath            = "url.path"
+	AttrHTTPStatusCode     = "http.response.status_code"
+	AttrCode               = "coze.code"
+	AttrLogID              = "coze.log_id"
+	AttrStreamFirstEventMs = "coze.stream.time_to_first_event_ms"
+	AttrStreamDurationMs   = "coze.stream.duration_ms"
+	AttrStreamEvents       = "coze.stream.events"
+	AttrStreamEventsPrefix = "coze.stream.events."
+)
+
+// WithTracer creates a span for every API call of the client, see Tracer
+func WithTracer(tracer Tracer) CozeAPIOption {
+	return func(opt *newCozeAPIOpt) {
+		opt.tracer = tracer
+	}
+}
+
+// startSpan starts the span of the call and injects its trace context into the request headers.
+func (c *core) startSpan(ctx context.Context, call *Call) (context.Context, Span) {
+	ctx, span := c.tracer.Start(ctx, call.Operation,
+		Attribute{Key: AttrOperation, Value: call.Operation},
+		Attribute{Key: AttrHTTPMethod, Value: call.HTTPRequest.Method},
+		Attribute{Key: AttrURLPath, Value: call.path},
+	)
+	c.tracer.Inject(ctx, call.HTTPRequest.Header)
+	return ctx, span
+}
@@ -0,0 +1,202 @@
+package coze
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traceIDKey struct{}
+
+// recordTracer records the spans it creates
+type recordTracer struct {
+	mu    sync.Mutex
+	spans []*recordSpan
+}
+
+type recordSpan struct {
+	name  string
+	attrs map[string]interface{}
+	errs  []error
+	ended int
+}
+
+func (t *recordTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordSpan{name: name, attrs: map[string]interface{}{}}
+	span.SetAttributes(attrs...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, traceIDKey{}, name), span
+}
+
+func (t *recordTracer) Inject(ctx context.Context, header http.Header) {
+	if name, ok := ctx.Value(traceIDKey{}).(string); ok {
+		header.Set("traceparent", "00-"+name+"-01")
+	}
+}
+
+func (s *recordSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) RecordError(err error) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordSpan) End() {
+	s.ended++
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("span per call", func(t *testing.T) {
+		tracer := &recordTracer{}
+		var traceparent string
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithTracer(tracer),
+			WithHttpClient(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					traceparent = req.Header.Get("traceparent")
+					return mockResponse(http.StatusOK, &retrieveChatsResp{Chat: &RetrieveChatsResp{Chat: Chat{ID: "chat1"}}})
+				},
+			}}),
+		)
+
+		_, err := api.Chat.Retrieve(context.Background(), &RetrieveChatsReq{ConversationID: "conv1", ChatID: "chat1"})
+		require.NoError(t, err)
+		assert.Equal(t, "00-chat.retrieve-01", traceparent)
+
+		require.Len(t, tracer.spans, 1)
+		span := tracer.spans[0]
+		assert.Equal(t, "chat.retrieve", span.name)
+		assert.Equal(t, 1, span.ended)
+		assert.Empty(t, span.errs)
+		assert.Equal(t, "chat.retrieve", span.attrs[AttrOperation])
+		assert.Equal(t, http.MethodGet, span.attrs[AttrHTTPMethod])
+		assert.Equal(t, "/v3/chat/retrieve", span.attrs[AttrURLPath])
+		assert.Equal(t, http.StatusOK, span.attrs[AttrHTTPStatusCode])
+		assert.Equal(t, 0, span.attrs[AttrCode])
+		assert.Equal(t, "test_log_id", span.attrs[AttrLogID])
+	})
+
+	t.Run("business error", func(t *testing.T) {
+		tracer := &recordTracer{}
+		api := businessErrorClient(t, WithTracer(tracer))
+		_, err := api.Users.Me(context.Background())
+		require.Error(t, err)
+
+		require.Len(t, tracer.spans, 1)
+		span := tracer.spans[0]
+		assert.Equal(t, 4000, span.attrs[AttrCode])
+		require.Len(t, span.errs, 1)
+		assert.Equal(t, err, span.errs[0])
+		assert.Equal(t, 1, span.ended)
+	})
+
+	t.Run("transport error", func(t *testing.T) {
+		tracer := &recordTracer{}
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithTracer(tracer),
+			WithHttpClient(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					return nil, errors.New("connection refused")
+				},
+			}}),
+		)
+		_, err := api.Users.Me(context.Background())
+		require.Error(t, err)
+
+		span := tracer.spans[0]
+		require.Len(t, span.errs, 1)
+		assert.NotContains(t, span.attrs, AttrHTTPStatusCode)
+		assert.Equal(t, 1, span.ended)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		tracer := &recordTracer{}
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithTracer(tracer),
+			WithHttpClient(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{http.CanonicalHeaderKey(logIDHeader): []string{"stream_log_id"}},
+						Body: io.NopCloser(strings.NewReader(`event: conversation.chat.created
+data: {"id":"chat1"}
+
+event: conversation.message.delta
+data: {"content":"a"}
+
+event: conversation.message.delta
+data: {"content":"b"}
+
+event: done
+data:
+`)),
+					}, nil
+				},
+			}}),
+		)
+
+		stream, err := api.Chat.Stream(context.Background(), &CreateChatsReq{})
+		require.NoError(t, err)
+		span := tracer.spans[0]
+		assert.Equal(t, "chat.stream", span.name)
+		assert.Equal(t, 0, span.ended)
+
+		for {
+			_, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+		}
+		require.NoError(t, stream.Close())
+
+		assert.Equal(t, 1, span.ended)
+		assert.Empty(t, span.errs)
+		assert.Equal(t, "stream_log_id", span.attrs[AttrLogID])
+		assert.Equal(t, 4, span.attrs[AttrStreamEvents])
+		assert.Equal(t, 1, span.attrs[AttrStreamEventsPrefix+string(ChatEventConversationChatCreated)])
+		assert.Equal(t, 2, span.attrs[AttrStreamEventsPrefix+string(ChatEventConversationMessageDelta)])
+		assert.Equal(t, 1, span.attrs[AttrStreamEventsPrefix+string(ChatEventDone)])
+		assert.Contains(t, span.attrs, AttrStreamFirstEventMs)
+		assert.Contains(t, span.attrs, AttrStreamDurationMs)
+	})
+
+	t.Run("stream closed early", func(t *testing.T) {
+		tracer := &recordTracer{}
+		api := NewCozeAPI(NewTokenAuth("token"),
+			WithTracer(tracer),
+			WithHttpClient(&http.Client{Transport: &mockTransport{
+				roundTripFunc: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{},
+						Body:       io.NopCloser(strings.NewReader("event: conversation.chat.created\ndata: {}\n")),
+					}, nil
+				},
+			}}),
+		)
+
+		stream, err := api.Workflows.Runs.Stream(context.Background(), &RunWorkflowsReq{WorkflowID: "wf1"})
+		require.NoError(t, err)
+		require.NoError(t, stream.Close())
+		require.NoError(t, stream.Close())
+
+		span := tracer.spans[0]
+		assert.Equal(t, "workflows.runs.stream", span.name)
+		assert.Equal(t, 1, span.ended)
+		assert.NotContains(t, span.attrs, AttrStreamEvents)
+		assert.Contains(t, span.attrs, AttrStreamDurationMs)
+	})
+}
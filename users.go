@@ -19,6 +19,13 @@ type meResp struct {
 	User *User `json:"data"`
 }
 
+// UsersService is the user api, see CozeAPI.Users.
+type UsersService interface {
+	Me(ctx context.Context, opts ...CallOption) (*User, error)
+}
+
+var _ UsersService = (*users)(nil)
+
 type users struct {
 	client *core
 }
@@ -30,7 +37,8 @@ func newUsers(core *core) *users {
 }
 
 // Me retrieves the current user's information
-func (r *users) Me(ctx context.Context) (*User, error) {
+func (r *users) Me(ctx context.Context, opts ...CallOption) (*User, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodGet
 	uri := "/v1/users/me"
 	resp := &meResp{}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"time"
 )
 
 func ptrValue[T any](s *T) T {
@@ -72,3 +73,32 @@ func isAuthContext(ctx context.Context) bool {
 	}
 	return strV == authContextValue
 }
+
+// detachedContext keeps the values of its parent, such as the span and the log fields, but not its
+// cancellation nor its call options, for the calls the SDK makes on its own once the parent is done.
+type detachedContext struct {
+	context.Context
+}
+
+func detachContext(ctx context.Context) context.Context {
+	return detachedContext{Context: ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	if key == callOptionContextKey {
+		return nil
+	}
+	return c.Context.Value(key)
+}
@@ -1,6 +1,7 @@
 package coze
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -65,3 +66,15 @@ func Test_MustToJson(t *testing.T) {
 	jsonStr := mustToJson(map[string]string{"test": "test"})
 	assert.Equal(t, jsonStr, `{"test":"test"}`)
 }
+
+func Test_DetachContext(t *testing.T) {
+	as := assert.New(t)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), contextKey("key"), "value"))
+	ctx = withCallOptions(ctx, []CallOption{WithCallHeader("X-Test", "1")})
+	cancel()
+
+	detached := detachContext(ctx)
+	as.Nil(detached.Err())
+	as.Equal("value", detached.Value(contextKey("key")))
+	as.Nil(getCallOption(detached))
+}
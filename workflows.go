@@ -1,13 +1,14 @@
 package coze
 
-type workflows struct {
-	Runs *workflowRuns
-	Chat *workflowsChat
+// WorkflowsAPI is the workflow api, see CozeAPI.Workflows.
+type WorkflowsAPI struct {
+	Runs *WorkflowRunsAPI
+	Chat WorkflowsChatService
 }
 
-func newWorkflows(core *core) *workflows {
-	return &workflows{
-		Runs: newWorkflowRun(core),
+func newWorkflows(core *core) *WorkflowsAPI {
+	return &WorkflowsAPI{
+		Runs: newWorkflowRunsAPI(core),
 		Chat: newWorkflowsChat(core),
 	}
 }
@@ -1,16 +1,23 @@
 package coze
 
 import (
-	"bufio"
 	"context"
 	"net/http"
 )
 
+// WorkflowsChatService is the api of the chat flows, see WorkflowsAPI.Chat.
+type WorkflowsChatService interface {
+	Stream(ctx context.Context, req *WorkflowsChatStreamReq, opts ...CallOption) (Stream[ChatEvent], error)
+}
+
+var _ WorkflowsChatService = (*workflowsChat)(nil)
+
 type workflowsChat struct {
 	client *core
 }
 
-func (r *workflowsChat) Stream(ctx context.Context, req *WorkflowsChatStreamReq) (Stream[ChatEvent], error) {
+func (r *workflowsChat) Stream(ctx context.Context, req *WorkflowsChatStreamReq, opts ...CallOption) (Stream[ChatEvent], error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/workflows/chat"
 	resp, err := r.client.StreamRequest(ctx, method, uri, req)
@@ -18,13 +25,7 @@ func (r *workflowsChat) Stream(ctx context.Context, req *WorkflowsChatStreamReq)
 		return nil, err
 	}
 
-	return &streamReader[ChatEvent]{
-		ctx:          ctx,
-		response:     resp,
-		reader:       bufio.NewReader(resp.Body),
-		processor:    parseChatEvent,
-		httpResponse: newHTTPResponse(resp),
-	}, nil
+	return newChatStreamReader(ctx, r.client, resp, ptrValue(req.ConversationID), ""), nil
 }
 
 func newWorkflowsChat(core *core) *workflowsChat {
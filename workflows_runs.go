@@ -1,15 +1,14 @@
 package coze
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 )
 
-func (r *workflowRuns) Create(ctx context.Context, req *RunWorkflowsReq) (*RunWorkflowsResp, error) {
+func (r *workflowRuns) Create(ctx context.Context, req *RunWorkflowsReq, opts ...CallOption) (*RunWorkflowsResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/workflow/run"
 	resp := &runWorkflowsResp{}
@@ -21,40 +20,65 @@ func (r *workflowRuns) Create(ctx context.Context, req *RunWorkflowsReq) (*RunWo
 	return resp.RunWorkflowsResp, nil
 }
 
-func (r *workflowRuns) Resume(ctx context.Context, req *ResumeRunWorkflowsReq) (Stream[WorkflowEvent], error) {
+func (r *workflowRuns) Resume(ctx context.Context, req *ResumeRunWorkflowsReq, opts ...CallOption) (Stream[WorkflowEvent], error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/workflow/stream_resume"
-	resp, err := r.client.StreamRequest(ctx, method, uri, req)
-	if err != nil {
-		return nil, err
-	}
-
-	return &streamReader[WorkflowEvent]{
-		ctx:          ctx,
-		response:     resp,
-		reader:       bufio.NewReader(resp.Body),
-		processor:    parseWorkflowEvent,
-		httpResponse: newHTTPResponse(resp),
-	}, nil
+	return r.stream(ctx, method, uri, req, req.WorkflowID)
 }
 
-func (r *workflowRuns) Stream(ctx context.Context, req *RunWorkflowsReq) (Stream[WorkflowEvent], error) {
+func (r *workflowRuns) Stream(ctx context.Context, req *RunWorkflowsReq, opts ...CallOption) (Stream[WorkflowEvent], error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodPost
 	uri := "/v1/workflow/stream_run"
-	resp, err := r.client.StreamRequest(ctx, method, uri, req)
+	return r.stream(ctx, method, uri, req, req.WorkflowID)
+}
+
+// stream opens a workflow stream, which reconnects after drops if the call options ask for it.
+func (r *workflowRuns) stream(ctx context.Context, method, uri string, req any, workflowID string) (Stream[WorkflowEvent], error) {
+	connect := func(ctx context.Context, lastEventID string) (*streamReader[WorkflowEvent], error) {
+		var opts []RequestOption
+		if lastEventID != "" {
+			opts = append(opts, withHTTPHeader(lastEventIDHeader, lastEventID))
+		}
+		resp, err := r.client.StreamRequest(ctx, method, uri, req, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return newStreamReader(ctx, r.client, resp, decodeWorkflowEvent), nil
+	}
+
+	reader, err := connect(ctx, "")
 	if err != nil {
 		return nil, err
 	}
+	policy := streamReconnectPolicyFor(ctx)
+	if policy == nil {
+		return reader, nil
+	}
+	return newReconnectingWorkflowStream(ctx, r.client, policy, workflowID, reader, connect), nil
+}
 
-	return &streamReader[WorkflowEvent]{
-		ctx:          ctx,
-		response:     resp,
-		reader:       bufio.NewReader(resp.Body),
-		processor:    parseWorkflowEvent,
-		httpResponse: newHTTPResponse(resp),
-	}, nil
+// WorkflowRunsService is the api of the workflow runs, see WorkflowsAPI.Runs.
+type WorkflowRunsService interface {
+	Create(ctx context.Context, req *RunWorkflowsReq, opts ...CallOption) (*RunWorkflowsResp, error)
+	Resume(ctx context.Context, req *ResumeRunWorkflowsReq, opts ...CallOption) (Stream[WorkflowEvent], error)
+	Stream(ctx context.Context, req *RunWorkflowsReq, opts ...CallOption) (Stream[WorkflowEvent], error)
+}
+
+// WorkflowRunsAPI is the api of the workflow runs and its sub-services.
+type WorkflowRunsAPI struct {
+	WorkflowRunsService
+	Histories WorkflowRunsHistoriesService
 }
 
+func newWorkflowRunsAPI(core *core) *WorkflowRunsAPI {
+	runs := newWorkflowRun(core)
+	return &WorkflowRunsAPI{WorkflowRunsService: runs, Histories: runs.Histories}
+}
+
+var _ WorkflowRunsService = (*workflowRuns)(nil)
+
 type workflowRuns struct {
 	client    *core
 	Histories *workflowRunsHistories
@@ -67,35 +91,26 @@ func newWorkflowRun(core *core) *workflowRuns {
 	}
 }
 
-func parseWorkflowEvent(lineBytes []byte, reader *bufio.Reader) (*WorkflowEvent, bool, error) {
-	line := string(lineBytes)
-	if strings.HasPrefix(line, "id:") {
-		id := strings.TrimSpace(line[3:])
-		data, err := reader.ReadString('\n')
-		if err != nil {
-			return nil, false, err
-		}
-		event := strings.TrimSpace(data[6:])
-		data, err = reader.ReadString('\n')
-		if err != nil {
-			return nil, false, err
-		}
-		data = strings.TrimSpace(data[5:])
-
-		eventLine := map[string]string{
-			"id":    id,
-			"event": event,
-			"data":  data,
-		}
-
-		eventData, err := doParseWorkflowEvent(eventLine)
-		if err != nil {
-			return nil, false, err
-		}
+func decodeWorkflowEvent(event *sseEvent) (*WorkflowEvent, bool, error) {
+	if event.Event == "" {
+		return nil, false, nil
+	}
+	eventData, err := doParseWorkflowEvent(event.ID, WorkflowEventType(event.Event), event.Data)
+	if err != nil {
+		return nil, false, err
+	}
+	if eventData.Event == WorkflowEventTypeError {
+		return eventData, false, newWorkflowStreamError(eventData)
+	}
+	return eventData, eventData.IsDone(), nil
+}
 
-		return eventData, eventData.IsDone(), nil
+// newWorkflowStreamError returns the error reported by an Error event.
+func newWorkflowStreamError(event *WorkflowEvent) *StreamError {
+	if event.Error == nil {
+		return &StreamError{}
 	}
-	return nil, false, nil
+	return &StreamError{Code: event.Error.ErrorCode, Message: event.Error.ErrorMessage}
 }
 
 // WorkflowRunResult represents the result of a workflow runs
@@ -181,10 +196,8 @@ func parseWorkflowEventDone(id int, data string) (*WorkflowEvent, error) {
 	}, nil
 }
 
-func doParseWorkflowEvent(eventLine map[string]string) (*WorkflowEvent, error) {
-	id, _ := strconv.Atoi(eventLine["id"])
-	event := WorkflowEventType(eventLine["event"])
-	data := eventLine["data"]
+func doParseWorkflowEvent(eventID string, event WorkflowEventType, data string) (*WorkflowEvent, error) {
+	id, _ := strconv.Atoi(eventID)
 
 	switch event {
 	case WorkflowEventTypeMessage:
@@ -6,7 +6,8 @@ import (
 	"net/http"
 )
 
-func (r *workflowRunsHistories) Retrieve(ctx context.Context, req *RetrieveWorkflowsRunsHistoriesReq) (*RetrieveWorkflowRunsHistoriesResp, error) {
+func (r *workflowRunsHistories) Retrieve(ctx context.Context, req *RetrieveWorkflowsRunsHistoriesReq, opts ...CallOption) (*RetrieveWorkflowRunsHistoriesResp, error) {
+	ctx = withCallOptions(ctx, opts)
 	method := http.MethodGet
 	uri := fmt.Sprintf("/v1/workflows/%s/run_histories/%s", req.WorkflowID, req.ExecuteID)
 	resp := &retrieveWorkflowRunsHistoriesResp{}
@@ -18,6 +19,14 @@ func (r *workflowRunsHistories) Retrieve(ctx context.Context, req *RetrieveWorkf
 	return resp.RetrieveWorkflowRunsHistoriesResp, nil
 }
 
+// WorkflowRunsHistoriesService is the api of the histories of the workflow runs, see
+// WorkflowRunsAPI.Histories.
+type WorkflowRunsHistoriesService interface {
+	Retrieve(ctx context.Context, req *RetrieveWorkflowsRunsHistoriesReq, opts ...CallOption) (*RetrieveWorkflowRunsHistoriesResp, error)
+}
+
+var _ WorkflowRunsHistoriesService = (*workflowRunsHistories)(nil)
+
 type workflowRunsHistories struct {
 	core *core
 }
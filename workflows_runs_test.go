@@ -175,10 +175,16 @@ data:{"debug_url":"https://www.coze.cn/work_flow?***"}
 	t.Run("Parse error event", func(t *testing.T) {
 		mockTransport := &mockTransport{
 			roundTripFunc: func(req *http.Request) (*http.Response, error) {
-				return mockStreamResponse(`id:0
+				resp, err := mockStreamResponse(`id:0
+event:Message
+data:{"content":"partial","node_title":"LLM"}
+
+id:1
 event:Error
 data:{"error_code":400,"error_message":"Bad Request"}
 `)
+				resp.Header.Set(logIDHeader, "test_log_id")
+				return resp, err
 			},
 		}
 
@@ -192,11 +198,27 @@ data:{"error_code":400,"error_message":"Bad Request"}
 		require.NoError(t, err)
 		defer reader.Close()
 
+		message, err := reader.Recv()
+		require.NoError(t, err)
 		event, err := reader.Recv()
 		require.NoError(t, err)
 		assert.Equal(t, WorkflowEventTypeError, event.Event)
 		assert.Equal(t, 400, event.Error.ErrorCode)
 		assert.Equal(t, "Bad Request", event.Error.ErrorMessage)
+
+		// the error event is returned as a StreamError once delivered
+		_, err = reader.Recv()
+		streamErr, ok := AsStreamError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, 400, streamErr.Code)
+		assert.Equal(t, "Bad Request", streamErr.Message)
+		assert.Equal(t, "test_log_id", streamErr.LogID)
+		assert.Equal(t, http.StatusOK, streamErr.HTTPStatusCode)
+		assert.Equal(t, "LLM", streamErr.NodeTitle)
+		assert.Equal(t, message, streamErr.LastWorkflowEvent)
+		assert.Nil(t, streamErr.LastChatEvent)
+		_, err = reader.Recv()
+		assert.Equal(t, err, streamErr)
 	})
 
 	// Test interrupt event parsing
@@ -6,7 +6,8 @@ import (
 	"strconv"
 )
 
-func (r *workspace) List(ctx context.Context, req *ListWorkspaceReq) (NumberPaged[Workspace], error) {
+func (r *workspace) List(ctx context.Context, req *ListWorkspaceReq, opts ...CallOption) (NumberPaged[Workspace], error) {
+	ctx = withCallOptions(ctx, opts)
 	if req.PageSize == 0 {
 		req.PageSize = 20
 	}
@@ -32,6 +33,13 @@ func (r *workspace) List(ctx context.Context, req *ListWorkspaceReq) (NumberPage
 		}, req.PageSize, req.PageNum)
 }
 
+// WorkspacesService is the workspace api, see CozeAPI.Workspaces.
+type WorkspacesService interface {
+	List(ctx context.Context, req *ListWorkspaceReq, opts ...CallOption) (NumberPaged[Workspace], error)
+}
+
+var _ WorkspacesService = (*workspace)(nil)
+
 type workspace struct {
 	core *core
 }